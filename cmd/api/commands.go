@@ -0,0 +1,526 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"cheapeats-api/internal/auth"
+	"cheapeats-api/internal/config"
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/exchange"
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+	"cheapeats-api/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// commandContext is cancelled on SIGINT or SIGTERM so long-running commands
+// stop cleanly.
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func runIngest(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("ingest", flag.ContinueOnError)
+	lat := flags.Float64("lat", 0, "latitude (required)")
+	lng := flags.Float64("lng", 0, "longitude (required)")
+	radius := flags.Int("radius", 1000, "search radius in meters, at most 50000")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	provided := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { provided[f.Name] = true })
+	if !provided["lat"] || !provided["lng"] {
+		fmt.Fprintln(os.Stderr, "ingest: -lat and -lng are required")
+		return 2
+	}
+	if *lat < -90 || *lat > 90 || *lng < -180 || *lng > 180 {
+		fmt.Fprintln(os.Stderr, "ingest: invalid coordinates")
+		return 2
+	}
+	if *radius <= 0 || *radius > 50000 {
+		fmt.Fprintln(os.Stderr, "ingest: radius must be between 1 and 50000")
+		return 2
+	}
+	if cfg.API.GooglePlacesAPIKey == "" {
+		fmt.Fprintln(os.Stderr, "ingest: GOOGLE_PLACES_API_KEY is not set")
+		return 1
+	}
+	classifier, err := services.LoadCuisineClassifier(cfg.Ingest.CuisineRulesFile)
+	if err != nil {
+		slog.Error("failed to load cuisine rules", "error", err)
+		return 1
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	fetcher := services.NewPriceFetcher(
+		services.NewRestaurantAPIClient(cfg.API.GooglePlacesAPIKey),
+		repository.NewGormRepositories(database.GetDB()),
+		classifier,
+	)
+
+	start := time.Now()
+	if err := fetcher.FetchAndSaveRestaurants(ctx, *lat, *lng, *radius); err != nil {
+		slog.Error("ingest failed", "error", err)
+		return 1
+	}
+	slog.Info("ingest finished", "lat", *lat, "lng", *lng, "radius", *radius, "duration", time.Since(start))
+	return 0
+}
+
+func runImportMenu(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("import-menu", flag.ContinueOnError)
+	restaurantID := flags.Uint("restaurant", 0, "restaurant ID (required)")
+	format := flags.String("format", "", "csv, json or jsonld (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "only print the changes that would be made")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: import-menu -restaurant <id> [-format csv|json|jsonld] [-dry-run] <file>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *restaurantID == 0 || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		slog.Error("failed to open menu file", "error", err)
+		return 1
+	}
+	defer file.Close()
+
+	importer := services.NewMenuImporter()
+
+	var rows []services.MenuImportRow
+	switch *format {
+	case "csv":
+		rows, err = importer.ParseCSV(file)
+	case "json":
+		rows, err = importer.ParseJSON(file)
+	case "jsonld":
+		rows, err = importer.ParseJSONLD(file)
+	default:
+		fmt.Fprintln(os.Stderr, "import-menu: unsupported format; use csv, json or jsonld")
+		return 2
+	}
+	if err != nil {
+		slog.Error("failed to parse menu file", "error", err)
+		return 1
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	repos := repository.NewGormRepositories(database.GetDB())
+	if _, err := repos.Restaurants.Get(ctx, uint(*restaurantID)); err != nil {
+		slog.Error("restaurant not found", "restaurant_id", *restaurantID, "error", err)
+		return 1
+	}
+
+	result, err := importer.Import(ctx, repos, uint(*restaurantID), rows, *dryRun)
+	if err != nil {
+		slog.Error("menu import failed", "error", err)
+		return 1
+	}
+
+	if err := printJSON(result); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runExport(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	kind := flags.String("type", "menu-items", "what to export: menu-items or price-history")
+	format := flags.String("format", services.ExportFormatCSV, "csv, ndjson or parquet")
+	output := flags.String("o", "", "output file (default: stdout)")
+	restaurantID := flags.Uint("restaurant", 0, "only this restaurant")
+	city := flags.String("city", "", "only restaurants in this city")
+	from := flags.String("from", "", "start date, YYYY-MM-DD or RFC3339")
+	to := flags.String("to", "", "end date, YYYY-MM-DD or RFC3339")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !services.ValidExportFormat(*format) {
+		fmt.Fprintln(os.Stderr, "export: invalid format; use csv, ndjson or parquet")
+		return 2
+	}
+
+	filter := services.ExportFilter{RestaurantID: uint(*restaurantID), City: *city}
+	for _, date := range []struct {
+		value  string
+		target **time.Time
+	}{{*from, &filter.From}, {*to, &filter.To}} {
+		if date.value == "" {
+			continue
+		}
+		t, err := parseDate(date.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "export: invalid date %q\n", date.value)
+			return 2
+		}
+		*date.target = &t
+	}
+
+	exporter := services.NewExporter()
+	var run func(context.Context, *gorm.DB, io.Writer, string, services.ExportFilter) error
+	switch *kind {
+	case "menu-items":
+		run = exporter.ExportMenuItems
+	case "price-history":
+		run = exporter.ExportPriceHistory
+	default:
+		fmt.Fprintln(os.Stderr, "export: invalid type; use menu-items or price-history")
+		return 2
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			slog.Error("failed to create output file", "error", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if err := run(ctx, database.GetDB().WithContext(ctx), w, *format, filter); err != nil {
+		slog.Error("export failed", "error", err)
+		return 1
+	}
+	return 0
+}
+
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func runSeed(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	created, err := services.Seed(ctx, repository.NewGormRepositories(database.GetDB()))
+	if err != nil {
+		slog.Error("seeding failed", "error", err)
+		return 1
+	}
+	slog.Info("seeded demo data", "restaurants_created", created)
+	return 0
+}
+
+func runRates(cfg *config.Config, logger *slog.Logger, args []string) int {
+	if len(args) == 0 || args[0] != "load" {
+		fmt.Fprintln(os.Stderr, "Usage: rates load [-source file|url]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("rates load", flag.ContinueOnError)
+	source := flags.String("source", cfg.ExchangeRates.Source, "ECB XML or JSON rates file or URL (default: EXCHANGE_RATES_SOURCE)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *source == "" {
+		fmt.Fprintf(os.Stderr, "rates load: no source; pass -source or set EXCHANGE_RATES_SOURCE (e.g. %s)\n", exchange.ECB90DayURL)
+		return 2
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	repos := repository.NewGormRepositories(database.GetDB())
+	converter := exchange.NewConverter()
+	count, err := exchange.NewUpdater(*source, repos.ExchangeRates, converter).Refresh(ctx)
+	if err != nil {
+		slog.Error("failed to load exchange rates", "error", err)
+		return 1
+	}
+	slog.Info("loaded exchange rates", "source", *source, "rates", count, "currencies", len(converter.Currencies()))
+	return 0
+}
+
+func runBackfillAddresses(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("backfill-addresses", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only print the changes that would be made")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	result, err := services.BackfillAddresses(ctx, database.GetDB(), *dryRun)
+	if err != nil {
+		slog.Error("address backfill failed", "error", err)
+		return 1
+	}
+
+	if err := printJSON(result); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runFindDuplicates(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("find-duplicates", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	result, err := services.FindDuplicates(ctx, repository.NewGormRepositories(database.GetDB()))
+	if err != nil {
+		slog.Error("duplicate scan failed", "error", err)
+		return 1
+	}
+
+	if err := printJSON(result); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runClassifyCuisines(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("classify-cuisines", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only print the changes that would be made")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	classifier, err := services.LoadCuisineClassifier(cfg.Ingest.CuisineRulesFile)
+	if err != nil {
+		slog.Error("failed to load cuisine rules", "error", err)
+		return 1
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	result, err := services.ReclassifyCuisines(ctx, database.GetDB(), classifier, *dryRun)
+	if err != nil {
+		slog.Error("cuisine classification failed", "error", err)
+		return 1
+	}
+
+	if err := printJSON(result); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// reindexTables are the application tables rebuilt by reindex.
+var reindexTables = []string{
+	"restaurants",
+	"menu_items",
+	"price_histories",
+	"scraped_data",
+	"api_keys",
+	"api_key_usages",
+	"exchange_rates",
+	"menu_item_variants",
+	"modifiers",
+	"opening_hours",
+	"promo_prices",
+	"cuisines",
+	"restaurant_cuisines",
+	"restaurant_source_links",
+	"duplicate_candidates",
+	"menu_match_candidates",
+}
+
+func runReindex(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	concurrently := flags.Bool("concurrently", false, "rebuild without blocking writes (slower; Postgres 12+)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	db := database.GetDB().WithContext(ctx)
+	for _, table := range reindexTables {
+		start := time.Now()
+
+		statement := "REINDEX TABLE " + table
+		if *concurrently {
+			statement = "REINDEX TABLE CONCURRENTLY " + table
+		}
+		if err := db.Exec(statement).Error; err != nil {
+			slog.Error("reindex failed", "table", table, "error", err)
+			return 1
+		}
+		if err := db.Exec("ANALYZE " + table).Error; err != nil {
+			slog.Error("analyze failed", "table", table, "error", err)
+			return 1
+		}
+		slog.Info("reindexed table", "table", table, "duration", time.Since(start))
+	}
+	return 0
+}
+
+func runAPIKey(cfg *config.Config, logger *slog.Logger, args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, "Usage: apikey create -name <name> -scopes read,ingest,admin [-rate-limit n] [-expires-in duration]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+	name := flags.String("name", "", "key name (required)")
+	scopes := flags.String("scopes", auth.ScopeRead, "comma-separated scopes: read, ingest, admin")
+	rateLimit := flags.Int("rate-limit", 0, "requests per minute (0 uses the server default)")
+	expiresIn := flags.Duration("expires-in", 0, "lifetime, e.g. 720h (0 never expires)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	apiKey := models.APIKey{
+		Name:      strings.TrimSpace(*name),
+		RateLimit: *rateLimit,
+	}
+
+	var scopeList []string
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopeList = append(scopeList, scope)
+		}
+	}
+	if err := validateAPIKeyFlags(apiKey, scopeList, *expiresIn); err != nil {
+		fmt.Fprintln(os.Stderr, "apikey create:", err)
+		return 2
+	}
+	apiKey.Scopes = strings.Join(scopeList, ",")
+	if *expiresIn > 0 {
+		expiresAt := time.Now().Add(*expiresIn)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := openCheckedDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	key, err := auth.CreateKey(context.Background(), &apiKey)
+	if err != nil {
+		slog.Error("failed to create API key", "error", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Created API key %d (%s). Store it now; it cannot be shown again.\n", apiKey.ID, apiKey.Name)
+	fmt.Println(key)
+	return 0
+}
+
+func validateAPIKeyFlags(apiKey models.APIKey, scopes []string, expiresIn time.Duration) error {
+	if apiKey.Name == "" {
+		return errors.New("-name is required")
+	}
+	if err := auth.ValidateScopes(scopes); err != nil {
+		return err
+	}
+	if apiKey.RateLimit < 0 {
+		return errors.New("-rate-limit must not be negative")
+	}
+	if expiresIn < 0 {
+		return errors.New("-expires-in must not be negative")
+	}
+	return nil
+}
@@ -1,21 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"cheapeats-api/internal/config"
 	"cheapeats-api/internal/database"
-	"cheapeats-api/internal/handlers"
-	"cheapeats-api/internal/services"
+	"cheapeats-api/internal/logging"
+	"cheapeats-api/internal/migrations"
 
 	_ "cheapeats-api/docs"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
-	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 // @title CheapEats API
@@ -34,69 +32,113 @@ import (
 
 // @schemes http https
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description API key as a bearer token, e.g. "Bearer ce_..."
+
 func main() {
 	cfg := config.LoadConfig()
 
-	dbConfig := database.DBConfig{
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		// Only the server logs to stdout; other commands keep it free for
+		// their output.
+		logOutput := os.Stderr
+		if name == "serve" {
+			logOutput = os.Stdout
+		}
+		logger := logging.Setup(logOutput, cfg.Logging.Level)
+
+		os.Exit(cmd.run(cfg, logger, args))
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	program := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", program)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for a command's flags.\n", program)
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, logger *slog.Logger, args []string) int
+}
+
+var commands = []command{
+	{"serve", "run the HTTP API (default)", runServe},
+	{"migrate", "apply, roll back, list or create schema migrations", runMigrate},
+	{"ingest", "fetch the restaurants around a location from the Places API", runIngest},
+	{"import-menu", "import a restaurant's menu from a CSV, JSON or JSON-LD file", runImportMenu},
+	{"export", "export menu items or price history", runExport},
+	{"seed", "insert demo restaurants and menus", runSeed},
+	{"rates", "load exchange rates from a file or feed", runRates},
+	{"backfill-addresses", "re-parse restaurant addresses from stored Places responses", runBackfillAddresses},
+	{"find-duplicates", "queue restaurants that may be the same place for review", runFindDuplicates},
+	{"classify-cuisines", "reassign restaurant cuisines using the cuisine rules", runClassifyCuisines},
+	{"reindex", "rebuild indexes and refresh planner statistics", runReindex},
+	{"apikey", "create API keys", runAPIKey},
+}
+
+func openDatabase(cfg *config.Config, logger *slog.Logger) error {
+	return database.InitDB(database.DBConfig{
 		Host:     cfg.Database.Host,
 		Port:     cfg.Database.Port,
 		User:     cfg.Database.User,
 		Password: cfg.Database.Password,
 		DBName:   cfg.Database.DBName,
 		SSLMode:  cfg.Database.SSLMode,
-	}
+		Logger:   logging.NewGormLogger(logger, cfg.Logging.SlowQueryThreshold),
+	})
+}
 
-	if err := database.InitDB(dbConfig); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+func newMigrator() (*migrations.Migrator, error) {
+	sqlDB, err := database.GetDB().DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB)
+}
+
+// openCheckedDatabase connects to the database and makes sure its schema
+// matches this build, for commands that read or write application data.
+func openCheckedDatabase(cfg *config.Config, logger *slog.Logger) error {
+	if err := openDatabase(cfg, logger); err != nil {
+		return err
 	}
 
-	apiClient := services.NewRestaurantAPIClient(cfg.API.GooglePlacesAPIKey)
-	priceFetcher := services.NewPriceFetcher(apiClient)
-	restaurantHandler := handlers.NewRestaurantHandler(priceFetcher)
-
-	r := chi.NewRouter()
-
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	r.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"),
-	))
-
-	r.Route("/api/v1", func(r chi.Router) {
-		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status":"healthy"}`))
-		})
-
-		r.Route("/restaurants", func(r chi.Router) {
-			r.Get("/", restaurantHandler.GetAllRestaurants)
-			r.Get("/search", restaurantHandler.SearchNearby)
-			r.Get("/{id}", restaurantHandler.GetRestaurant)
-			r.Get("/{id}/menu", restaurantHandler.GetMenuItems)
-		})
-
-		r.Route("/menu-items", func(r chi.Router) {
-			r.Get("/{itemId}", restaurantHandler.GetMenuItem)
-			r.Get("/{itemId}/price-history", restaurantHandler.GetPriceHistory)
-		})
-	})
-
-	serverAddr := fmt.Sprintf(":%s", cfg.Server.Port)
-	log.Printf("Starting server on %s", serverAddr)
-	
-	if err := http.ListenAndServe(serverAddr, r); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	migrator, err := newMigrator()
+	if err != nil {
+		return err
 	}
-}
\ No newline at end of file
+	if _, err := migrator.Check(context.Background()); err != nil {
+		return fmt.Errorf("database schema does not match this build; run `migrate up`: %w", err)
+	}
+	return nil
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
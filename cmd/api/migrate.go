@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"cheapeats-api/internal/config"
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/migrations"
+)
+
+const migrateUsage = `Usage: migrate <command>
+
+Commands:
+  up                 apply all pending migrations
+  down [-steps n]    roll back the last n migrations (default 1)
+  status             list migrations and whether they are applied
+  create <name>      write empty up/down scripts for a new migration
+`
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(cfg *config.Config, logger *slog.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	command, args := args[0], args[1:]
+	if command == "create" {
+		flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+		dir := flags.String("dir", migrations.SourceDir, "directory holding the migration scripts")
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+		if flags.NArg() != 1 {
+			fmt.Fprint(os.Stderr, migrateUsage)
+			return 2
+		}
+
+		up, down, err := migrations.Create(*dir, flags.Arg(0))
+		if err != nil {
+			slog.Error("failed to create migration", "error", err)
+			return 1
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return 0
+	}
+
+	if err := openDatabase(cfg, logger); err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+
+	migrator, err := newMigrator()
+	if err != nil {
+		slog.Error("failed to load migrations", "error", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			slog.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			slog.Error("migration failed", "error", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			slog.Info("no pending migrations")
+		}
+
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			slog.Info("rolled back migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			slog.Error("rollback failed", "error", err)
+			return 1
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			slog.Error("failed to read migration status", "error", err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			if s.Unknown {
+				applied += " (unknown to this build)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
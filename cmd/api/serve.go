@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cheapeats-api/internal/auth"
+	"cheapeats-api/internal/config"
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/exchange"
+	"cheapeats-api/internal/handlers"
+	"cheapeats-api/internal/health"
+	"cheapeats-api/internal/logging"
+	"cheapeats-api/internal/metrics"
+	"cheapeats-api/internal/migrations"
+	"cheapeats-api/internal/repository"
+	"cheapeats-api/internal/services"
+	"cheapeats-api/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// runServe starts the HTTP server and background workers and blocks until
+// SIGINT or SIGTERM.
+func runServe(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("failed to configure tracing", err)
+	}
+
+	if err := openDatabase(cfg, logger); err != nil {
+		fatal("failed to initialize database", err)
+	}
+
+	migrator, err := newMigrator()
+	if err != nil {
+		fatal("failed to load migrations", err)
+	}
+	if err := checkSchema(migrator, cfg.Database.MigrateOnStart); err != nil {
+		fatal("database schema does not match this build; run `migrate up`", err)
+	}
+
+	apiClient := services.NewRestaurantAPIClient(cfg.API.GooglePlacesAPIKey)
+	repos := repository.NewGormRepositories(database.GetDB())
+	classifier, err := services.LoadCuisineClassifier(cfg.Ingest.CuisineRulesFile)
+	if err != nil {
+		fatal("failed to load cuisine rules", err)
+	}
+	priceFetcher := services.NewPriceFetcher(apiClient, repos, classifier)
+	converter := exchange.NewConverter()
+	rateUpdater := exchange.NewUpdater(cfg.ExchangeRates.Source, repos.ExchangeRates, converter)
+	if err := rateUpdater.Reload(context.Background()); err != nil {
+		fatal("failed to load exchange rates", err)
+	}
+
+	restaurantHandler := handlers.NewRestaurantHandler(priceFetcher, repos, converter)
+	dealHandler := handlers.NewDealHandler(repos.Menus, converter)
+	reportHandler := handlers.NewReportHandler()
+	menuImporter := services.NewMenuImporter()
+	adminHandler := handlers.NewAdminHandler(menuImporter, repos)
+	exportHandler := handlers.NewExportHandler(services.NewExporter())
+
+	ingestQueue := services.NewIngestQueue(priceFetcher, cfg.Ingest.Workers, cfg.Ingest.QueueCapacity)
+	ingestQueue.Start()
+	ingestHandler := handlers.NewIngestHandler(ingestQueue)
+	metrics.RegisterQueueDepth(ingestQueue.Depth)
+
+	db := database.GetDB()
+	checker := health.NewChecker(cfg.Health.CheckTimeout)
+	checker.Register("database", true, health.Database(db))
+	checker.Register("migrations", true, health.SchemaVersion(migrator.Check, migrator.Latest()))
+	checker.Register("ingest_queue", false, health.QueueDepth(ingestQueue.Depth, cfg.Ingest.QueueCapacity))
+	checker.Register("last_ingest", false, health.LastIngest(repos.Scrapes.LastScrapedAt, cfg.Health.MaxIngestAge))
+	checker.Register("places_api", false, health.Cached(health.Reachable(apiClient.Ping), time.Minute))
+	healthHandler := handlers.NewHealthHandler(checker)
+
+	trustedProxies, err := auth.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		fatal("failed to parse TRUSTED_PROXIES", err)
+	}
+	keyStore := auth.NewKeyStore(30 * time.Second)
+	usageRecorder := auth.NewUsageRecorder()
+
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.Auth.JWT.Enabled() {
+		verifier, err := newJWTVerifier(cfg.Auth.JWT)
+		if err != nil {
+			fatal("failed to configure JWT authentication", err)
+		}
+		jwtVerifier = verifier
+	}
+
+	authenticator := auth.NewAuthenticator(keyStore, jwtVerifier, auth.NewRateLimiter(), usageRecorder, cfg.Auth.AdminToken, cfg.Auth.DefaultRateLimit, cfg.Auth.FailureLimit)
+	apiKeyHandler := handlers.NewAPIKeyHandler(keyStore)
+
+	requireRead := auth.RequireScope(auth.ScopeRead)
+	requireIngest := auth.RequireScope(auth.ScopeIngest)
+	requireAdmin := auth.RequireScope(auth.ScopeAdmin)
+
+	usageCtx, stopUsage := context.WithCancel(context.Background())
+	usageDone := make(chan struct{})
+	go func() {
+		usageRecorder.Run(usageCtx, 30*time.Second)
+		close(usageDone)
+	}()
+
+	ratesCtx, stopRates := context.WithCancel(context.Background())
+	if cfg.ExchangeRates.Source != "" {
+		go rateUpdater.Run(ratesCtx, cfg.ExchangeRates.RefreshInterval)
+	}
+
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(auth.RealIP(trustedProxies))
+	r.Use(tracing.Middleware)
+	r.Use(metrics.Middleware)
+	r.Use(logging.Middleware)
+	r.Use(middleware.Recoverer)
+
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           300,
+	}))
+
+	r.Get("/swagger/*", httpSwagger.Handler(
+		httpSwagger.URL("/swagger/doc.json"),
+	))
+
+	r.Get("/healthz", healthHandler.Liveness)
+	r.Get("/readyz", healthHandler.Readiness)
+	r.Handle("/metrics", metrics.Handler())
+
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/health", healthHandler.Liveness)
+		r.Get("/ready", healthHandler.Readiness)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticator.Authenticate)
+
+			r.Route("/restaurants", func(r chi.Router) {
+				r.With(requireRead).Get("/", restaurantHandler.GetAllRestaurants)
+				r.With(requireIngest).Get("/search", restaurantHandler.SearchNearby)
+				r.With(requireRead).Get("/{id}", restaurantHandler.GetRestaurant)
+				r.With(requireRead).Get("/{id}/menu", restaurantHandler.GetMenuItems)
+				r.With(requireRead).Get("/{id}/promos", restaurantHandler.GetPromos)
+
+				r.Group(func(r chi.Router) {
+					r.Use(requireAdmin)
+					r.Post("/", adminHandler.CreateRestaurant)
+					r.Put("/{id}", adminHandler.UpdateRestaurant)
+					r.Patch("/{id}", adminHandler.PatchRestaurant)
+					r.Delete("/{id}", adminHandler.DeleteRestaurant)
+					r.Post("/{id}/restore", adminHandler.RestoreRestaurant)
+					r.Put("/{id}/opening-hours", adminHandler.SetOpeningHours)
+					r.Delete("/{id}/opening-hours", adminHandler.DeleteOpeningHours)
+					r.Put("/{id}/cuisines", adminHandler.SetRestaurantCuisines)
+					r.Post("/{id}/merge", adminHandler.MergeRestaurant)
+					r.Post("/{id}/menu", adminHandler.CreateMenuItem)
+					r.Post("/{id}/menu/import", adminHandler.ImportMenu)
+					r.Post("/{id}/promos", adminHandler.CreatePromo)
+				})
+			})
+
+			r.Route("/promos", func(r chi.Router) {
+				r.Use(requireAdmin)
+				r.Put("/{promoId}", adminHandler.UpdatePromo)
+				r.Patch("/{promoId}", adminHandler.PatchPromo)
+				r.Delete("/{promoId}", adminHandler.DeletePromo)
+			})
+
+			r.Route("/menu-items", func(r chi.Router) {
+				r.With(requireRead).Get("/{itemId}", restaurantHandler.GetMenuItem)
+				r.With(requireRead).Get("/{itemId}/price-history", restaurantHandler.GetPriceHistory)
+
+				r.Group(func(r chi.Router) {
+					r.Use(requireAdmin)
+					r.Put("/{itemId}", adminHandler.UpdateMenuItem)
+					r.Patch("/{itemId}", adminHandler.PatchMenuItem)
+					r.Delete("/{itemId}", adminHandler.DeleteMenuItem)
+					r.Post("/{itemId}/restore", adminHandler.RestoreMenuItem)
+					r.Post("/{itemId}/rename", adminHandler.RenameMenuItem)
+					r.Post("/{itemId}/merge", adminHandler.MergeMenuItem)
+					r.Post("/{itemId}/variants", adminHandler.CreateVariant)
+					r.Put("/{itemId}/variants/{variantId}", adminHandler.UpdateVariant)
+					r.Patch("/{itemId}/variants/{variantId}", adminHandler.PatchVariant)
+					r.Delete("/{itemId}/variants/{variantId}", adminHandler.DeleteVariant)
+					r.Post("/{itemId}/modifiers", adminHandler.CreateModifier)
+					r.Put("/{itemId}/modifiers/{modifierId}", adminHandler.UpdateModifier)
+					r.Patch("/{itemId}/modifiers/{modifierId}", adminHandler.PatchModifier)
+					r.Delete("/{itemId}/modifiers/{modifierId}", adminHandler.DeleteModifier)
+				})
+			})
+
+			r.With(requireRead).Get("/cuisines", restaurantHandler.GetCuisines)
+			r.With(requireRead).Get("/deals/cheapest", dealHandler.GetCheapest)
+
+			r.With(requireIngest).Post("/ingest", ingestHandler.CreateIngestJob)
+
+			r.Route("/reports", func(r chi.Router) {
+				r.Use(requireRead)
+				r.Get("/area-prices", reportHandler.GetAreaPrices)
+			})
+
+			r.Route("/export", func(r chi.Router) {
+				r.Use(requireRead)
+				r.Get("/menu-items", exportHandler.ExportMenuItems)
+				r.Get("/price-history", exportHandler.ExportPriceHistory)
+			})
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(requireAdmin)
+				r.Get("/api-keys", apiKeyHandler.ListAPIKeys)
+				r.Post("/api-keys", apiKeyHandler.CreateAPIKey)
+				r.Get("/api-keys/{id}/usage", apiKeyHandler.GetAPIKeyUsage)
+				r.Delete("/api-keys/{id}", apiKeyHandler.RevokeAPIKey)
+				r.Get("/duplicates", adminHandler.ListDuplicates)
+				r.Post("/duplicates/{id}/merge", adminHandler.MergeDuplicate)
+				r.Post("/duplicates/{id}/dismiss", adminHandler.DismissDuplicate)
+				r.Get("/menu-matches", adminHandler.ListMenuMatches)
+				r.Post("/menu-matches/{id}/merge", adminHandler.MergeMenuMatch)
+				r.Post("/menu-matches/{id}/dismiss", adminHandler.DismissMenuMatch)
+			})
+		})
+	})
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.Server.Port),
+		Handler:           r,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("starting server", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		fatal("failed to start server", err)
+	case sig := <-stop:
+		slog.Info("shutting down", "signal", sig.String())
+	}
+
+	// One deadline covers the whole shutdown: in-flight requests first, then
+	// the ingest jobs they may have queued, then the final usage flush.
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	healthHandler.Drain()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("HTTP server did not drain in time", "error", err)
+	}
+
+	if err := ingestQueue.Shutdown(ctx); err != nil {
+		slog.Warn("ingest workers did not drain in time", "error", err)
+	}
+
+	stopRates()
+
+	stopUsage()
+	select {
+	case <-usageDone:
+	case <-ctx.Done():
+		slog.Warn("API key usage was not flushed before the shutdown deadline")
+	}
+
+	if err := database.Close(); err != nil {
+		slog.Error("failed to close database", "error", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+
+	slog.Info("server stopped")
+
+	return 0
+}
+
+// checkSchema refuses to serve against a database whose migrations don't
+// match the ones built into the binary, optionally applying pending ones
+// first.
+func checkSchema(migrator *migrations.Migrator, migrate bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if migrate {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			slog.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+	}
+
+	version, err := migrator.Check(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("database schema is up to date", "version", version)
+	return nil
+}
+
+func newJWTVerifier(cfg config.JWTConfig) (*auth.JWTVerifier, error) {
+	roleScopes, err := auth.ParseRoleScopes(cfg.RoleScopes)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	keys, err := auth.NewJWKS(ctx, cfg.JWKSFile, cfg.JWKSURL, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewJWTVerifier(keys, auth.JWTOptions{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		RolesClaim: cfg.RolesClaim,
+		RoleScopes: roleScopes,
+		RateLimit:  cfg.RateLimit,
+	}), nil
+}
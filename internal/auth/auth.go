@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scopes that can be granted to a client. Admin implies every other scope.
+const (
+	ScopeRead   = "read"
+	ScopeIngest = "ingest"
+	ScopeAdmin  = "admin"
+)
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopeIngest, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// Principal is the authenticated client behind a request.
+type Principal struct {
+	// ID identifies the client for rate limiting, e.g. "key:42".
+	ID        string
+	Name      string
+	KeyID     uint
+	Scopes    []string
+	RateLimit int
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored by the authentication
+// middleware, or nil for anonymous requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
+
+type Authenticator struct {
+	keys             *KeyStore
+	jwt              *JWTVerifier
+	limiter          *RateLimiter
+	usage            *UsageRecorder
+	bootstrapToken   string
+	defaultRateLimit int
+	failures         *RateLimiter
+	failureLimit     int
+}
+
+// NewAuthenticator creates the authentication middleware. jwtVerifier may be
+// nil, in which case only API keys are accepted. failureLimit is how many
+// failed attempts per minute a client IP may make before it is turned away
+// without its credentials being checked; 0 disables the limit.
+func NewAuthenticator(keys *KeyStore, jwtVerifier *JWTVerifier, limiter *RateLimiter, usage *UsageRecorder, bootstrapToken string, defaultRateLimit, failureLimit int) *Authenticator {
+	return &Authenticator{
+		keys:             keys,
+		jwt:              jwtVerifier,
+		limiter:          limiter,
+		usage:            usage,
+		bootstrapToken:   bootstrapToken,
+		defaultRateLimit: defaultRateLimit,
+		failures:         NewRateLimiter(),
+		failureLimit:     failureLimit,
+	}
+}
+
+// Authenticate resolves the API key sent as a bearer token or in the
+// X-API-Key header, rejects unknown, revoked or expired keys, and enforces the
+// key's rate limit. When JWT verification is configured, bearer tokens in JWT
+// form are verified as OIDC access tokens instead. Requests without
+// credentials are rejected, and clients that keep sending invalid ones are
+// throttled by IP.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := credentials(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "Missing API key")
+			return
+		}
+
+		ip := clientIP(r)
+		if a.failureLimit > 0 {
+			if exhausted, retryAfter := a.failures.Exhausted(ip, a.failureLimit); exhausted {
+				writeRetryAfter(w, retryAfter)
+				writeError(w, http.StatusTooManyRequests, "Too many failed authentication attempts")
+				return
+			}
+		}
+
+		principal, err := a.resolve(r.Context(), token)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "API key lookup failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "Failed to verify API key")
+			return
+		}
+		if principal == nil {
+			if a.failureLimit > 0 {
+				a.failures.Allow(ip, a.failureLimit)
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "Invalid credentials")
+			return
+		}
+
+		if principal.RateLimit > 0 {
+			allowed, remaining, retryAfter := a.limiter.Allow(principal.ID, principal.RateLimit)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(principal.RateLimit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			if !allowed {
+				a.usage.Record(principal.KeyID, true)
+				writeRetryAfter(w, retryAfter)
+				writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+		}
+		a.usage.Record(principal.KeyID, false)
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func (a *Authenticator) resolve(ctx context.Context, token string) (*Principal, error) {
+	if a.bootstrapToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.bootstrapToken)) == 1 {
+		return &Principal{
+			ID:     "bootstrap",
+			Name:   "bootstrap admin token",
+			Scopes: []string{ScopeAdmin},
+		}, nil
+	}
+
+	if a.jwt != nil && LooksLikeJWT(token) {
+		principal, err := a.jwt.Verify(ctx, token)
+		if err != nil {
+			slog.InfoContext(ctx, "rejected JWT", "error", err)
+			return nil, nil
+		}
+		if principal.RateLimit == 0 {
+			principal.RateLimit = a.defaultRateLimit
+		}
+		return principal, nil
+	}
+
+	key, err := a.keys.Lookup(ctx, token)
+	if err != nil || key == nil {
+		return nil, err
+	}
+
+	rateLimit := key.RateLimit
+	if rateLimit == 0 {
+		rateLimit = a.defaultRateLimit
+	}
+
+	return &Principal{
+		ID:        "key:" + strconv.FormatUint(uint64(key.ID), 10),
+		Name:      key.Name,
+		KeyID:     key.ID,
+		Scopes:    key.ScopeList(),
+		RateLimit: rateLimit,
+	}, nil
+}
+
+// RequireScope rejects requests whose principal lacks scope. It must run
+// after Authenticate.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := PrincipalFromContext(r.Context())
+			if principal == nil {
+				writeError(w, http.StatusUnauthorized, "Missing API key")
+				return
+			}
+			if !principal.HasScope(scope) {
+				writeError(w, http.StatusForbidden, "Credentials lack the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func credentials(r *http.Request) (string, bool) {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key, true
+	}
+	return bearerToken(r)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func writeRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	response, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+const testBootstrapToken = "bootstrap-secret"
+
+// testServer returns a handler that authenticates requests, optionally
+// requires scope, and answers with the principal's ID.
+func testServer(authenticator *Authenticator, scope string, trusted []netip.Prefix) http.Handler {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(PrincipalFromContext(r.Context()).ID))
+	})
+	if scope != "" {
+		handler = RequireScope(scope)(handler)
+	}
+	return RealIP(trusted)(authenticator.Authenticate(handler))
+}
+
+func testRequest(remoteAddr string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := newTestKeyStore(time.Minute)
+	readKey, _ := keys.add(t, 1, "read", 0)
+	adminKey, _ := keys.add(t, 2, "admin", 0)
+	limitedKey, _ := keys.add(t, 3, "read", 1)
+	authenticator := NewAuthenticator(keys.KeyStore, nil, NewRateLimiter(), NewUsageRecorder(), testBootstrapToken, 60, 0)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		scope   string
+		code    int
+		body    string
+	}{
+		{name: "no credentials", code: http.StatusUnauthorized},
+		{name: "api key header", headers: map[string]string{"X-API-Key": readKey}, code: http.StatusOK, body: "key:1"},
+		{name: "bearer key", headers: map[string]string{"Authorization": "Bearer " + readKey}, code: http.StatusOK, body: "key:1"},
+		{name: "lowercase bearer", headers: map[string]string{"Authorization": "bearer " + readKey}, code: http.StatusOK, body: "key:1"},
+		{name: "basic auth", headers: map[string]string{"Authorization": "Basic " + readKey}, code: http.StatusUnauthorized},
+		{name: "unknown key", headers: map[string]string{"X-API-Key": "ce_unknown"}, code: http.StatusUnauthorized},
+		{name: "bootstrap token", headers: map[string]string{"Authorization": "Bearer " + testBootstrapToken}, scope: ScopeAdmin, code: http.StatusOK, body: "bootstrap"},
+		{name: "scope granted", headers: map[string]string{"X-API-Key": readKey}, scope: ScopeRead, code: http.StatusOK, body: "key:1"},
+		{name: "scope missing", headers: map[string]string{"X-API-Key": readKey}, scope: ScopeAdmin, code: http.StatusForbidden},
+		{name: "admin implies read", headers: map[string]string{"X-API-Key": adminKey}, scope: ScopeRead, code: http.StatusOK, body: "key:2"},
+		{name: "within rate limit", headers: map[string]string{"X-API-Key": limitedKey}, code: http.StatusOK, body: "key:3"},
+		{name: "over rate limit", headers: map[string]string{"X-API-Key": limitedKey}, code: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		testServer(authenticator, tt.scope, nil).ServeHTTP(rec, testRequest("192.0.2.1:1234", tt.headers))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: principal = %q, want %q", tt.name, rec.Body, tt.body)
+		}
+		if tt.code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: no Retry-After header", tt.name)
+		}
+	}
+}
+
+func TestAuthenticateThrottlesFailures(t *testing.T) {
+	keys := newTestKeyStore(time.Minute)
+	key, _ := keys.add(t, 1, "read", 0)
+	trusted, err := ParseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		code       int
+	}{
+		{"first failure", "203.0.113.7:1000", map[string]string{"X-API-Key": "ce_wrong"}, http.StatusUnauthorized},
+		{"second failure", "203.0.113.7:1001", map[string]string{"X-API-Key": "ce_wrong"}, http.StatusUnauthorized},
+		{"throttled", "203.0.113.7:1002", map[string]string{"X-API-Key": "ce_wrong"}, http.StatusTooManyRequests},
+		{"throttled with a valid key", "203.0.113.7:1003", map[string]string{"X-API-Key": key}, http.StatusTooManyRequests},
+		{"spoofed X-Forwarded-For", "203.0.113.7:1004", map[string]string{"X-API-Key": "ce_wrong", "X-Forwarded-For": "198.51.100.1"}, http.StatusTooManyRequests},
+		{"spoofed X-Real-IP", "203.0.113.7:1005", map[string]string{"X-API-Key": "ce_wrong", "X-Real-IP": "198.51.100.2"}, http.StatusTooManyRequests},
+		{"other client", "203.0.113.8:1000", map[string]string{"X-API-Key": key}, http.StatusOK},
+		{"behind the proxy", "10.0.0.1:1000", map[string]string{"X-API-Key": "ce_wrong", "X-Forwarded-For": "203.0.113.9"}, http.StatusUnauthorized},
+		{"throttled client behind the proxy", "10.0.0.1:1001", map[string]string{"X-API-Key": key, "X-Forwarded-For": "203.0.113.7"}, http.StatusTooManyRequests},
+		{"prepended address behind the proxy", "10.0.0.1:1002", map[string]string{"X-API-Key": key, "X-Forwarded-For": "198.51.100.3, 203.0.113.7"}, http.StatusTooManyRequests},
+	}
+	authenticator := NewAuthenticator(keys.KeyStore, nil, NewRateLimiter(), NewUsageRecorder(), "", 60, 2)
+	server := testServer(authenticator, "", trusted)
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, testRequest(tt.remoteAddr, tt.headers))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.code)
+		}
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.5, ::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"direct", "203.0.113.7:1234", nil, "203.0.113.7:1234"},
+		{"untrusted X-Forwarded-For", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7:1234"},
+		{"untrusted X-Real-IP", "203.0.113.7:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "203.0.113.7:1234"},
+		{"trusted X-Forwarded-For", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"trusted X-Real-IP", "192.168.1.5:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"trusted IPv6 proxy", "[::1]:1234", map[string]string{"X-Forwarded-For": "2001:db8::1"}, "2001:db8::1"},
+		{"X-Forwarded-For before X-Real-IP", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}, "198.51.100.1"},
+		{"chain of proxies", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 192.168.1.5"}, "198.51.100.1"},
+		{"client prepends an address", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "10.9.9.9, 198.51.100.1"}, "198.51.100.1"},
+		{"only proxies", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "10.9.9.9"}, "10.1.2.3:1234"},
+		{"garbage", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "not-an-ip"}, "10.1.2.3:1234"},
+		{"no header", "10.1.2.3:1234", nil, "10.1.2.3:1234"},
+	}
+	for _, tt := range tests {
+		var got string
+		handler := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.RemoteAddr
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), testRequest(tt.remoteAddr, tt.headers))
+		if got != tt.want {
+			t.Errorf("%s: RemoteAddr = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"10.0.0.0/8", 1, false},
+		{" 10.0.0.1 , 172.16.0.0/12,fd00::/8 ", 3, false},
+		{"10.0.0.0/33", 0, true},
+		{"proxy.internal", 0, true},
+	}
+	for _, tt := range tests {
+		prefixes, err := ParseTrustedProxies(tt.value)
+		if (err != nil) != tt.wantErr || len(prefixes) != tt.want {
+			t.Errorf("ParseTrustedProxies(%q) = %v, %v, want %d prefixes and error %v", tt.value, prefixes, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwk is a single JSON Web Key as published in a JWKS document.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKS holds the public keys used to verify tokens, loaded from a file or a
+// URL. Keys fetched from a URL are refreshed periodically and whenever a
+// token names a key ID that isn't known yet.
+type JWKS struct {
+	file       string
+	url        string
+	refresh    time.Duration
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// attemptedAt is when a fetch was last started, whether or not it
+	// succeeded.
+	attemptedAt time.Time
+}
+
+// minRefetchInterval stops tokens with unknown key IDs, or a JWKS URL that is
+// down, from triggering a fetch of the URL on every request.
+const minRefetchInterval = 30 * time.Second
+
+// NewJWKS loads the key set from file, or from url when no file is given.
+func NewJWKS(ctx context.Context, file, url string, refresh time.Duration) (*JWKS, error) {
+	if file == "" && url == "" {
+		return nil, errors.New("either a JWKS file or a JWKS URL is required")
+	}
+
+	set := &JWKS{
+		file:       file,
+		url:        url,
+		refresh:    refresh,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := set.load(ctx); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Key returns the public key with the given ID. An empty kid matches the
+// only key of a single-key set.
+func (s *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, stale, ok := s.lookup(kid)
+	if ok && !stale {
+		return key, nil
+	}
+
+	if s.url != "" && s.canRefetch() {
+		if err := s.load(ctx); err != nil && !ok {
+			return nil, err
+		}
+		key, _, ok = s.lookup(kid)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (s *JWKS) lookup(kid string) (crypto.PublicKey, bool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stale := s.url != "" && s.refresh > 0 && time.Since(s.fetchedAt) > s.refresh
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, stale, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, stale, ok
+}
+
+// canRefetch reports whether the last fetch attempt was long enough ago to
+// try again, and if so claims the attempt, so that concurrent requests don't
+// all fetch at once.
+func (s *JWKS) canRefetch() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.attemptedAt) <= minRefetchInterval {
+		return false
+	}
+	s.attemptedAt = time.Now()
+	return true
+}
+
+func (s *JWKS) load(ctx context.Context) error {
+	var data []byte
+	var err error
+
+	if s.file != "" {
+		data, err = os.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+	} else {
+		s.mu.Lock()
+		s.attemptedAt = time.Now()
+		s.mu.Unlock()
+
+		data, err = s.fetch(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *JWKS) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	return data, nil
+}
+
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in JWKS: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
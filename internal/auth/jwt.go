@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTOptions configures token verification.
+type JWTOptions struct {
+	Issuer   string
+	Audience string
+	// RolesClaim is the claim holding the caller's roles. Dots address nested
+	// objects, e.g. "realm_access.roles".
+	RolesClaim string
+	// RoleScopes maps role names to the scopes they grant. Roles named after
+	// a scope grant that scope even without an explicit mapping.
+	RoleScopes map[string][]string
+	RateLimit  int
+}
+
+// JWTVerifier authenticates requests carrying an OIDC access token signed by
+// one of the keys in a JWKS.
+type JWTVerifier struct {
+	keys    *JWKS
+	options JWTOptions
+	parser  *jwt.Parser
+}
+
+var jwtSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+func NewJWTVerifier(keys *JWKS, options JWTOptions) *JWTVerifier {
+	if options.RolesClaim == "" {
+		options.RolesClaim = "roles"
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtSigningMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(options.Issuer),
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+
+	return &JWTVerifier{
+		keys:    keys,
+		options: options,
+		parser:  jwt.NewParser(parserOptions...),
+	}
+}
+
+// ParseRoleScopes parses a mapping such as "editor=read,ingest;ops=admin".
+func ParseRoleScopes(value string) (map[string][]string, error) {
+	mapping := make(map[string][]string)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		role, scopes, found := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !found || role == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", entry)
+		}
+
+		for _, scope := range strings.Split(scopes, ",") {
+			scope = strings.TrimSpace(scope)
+			if !ValidScope(scope) {
+				return nil, fmt.Errorf("unknown scope %q for role %q", scope, role)
+			}
+			mapping[role] = append(mapping[role], scope)
+		}
+	}
+	return mapping, nil
+}
+
+// LooksLikeJWT reports whether token has the three-part JWS compact form, as
+// opposed to an opaque API key.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks the token's signature, issuer, audience and expiry and maps
+// its roles to scopes.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	name, _ := claims["preferred_username"].(string)
+	if name == "" {
+		name = subject
+	}
+
+	return &Principal{
+		ID:        "jwt:" + subject,
+		Name:      name,
+		Scopes:    v.scopes(claims),
+		RateLimit: v.options.RateLimit,
+	}, nil
+}
+
+func (v *JWTVerifier) scopes(claims jwt.MapClaims) []string {
+	seen := make(map[string]bool)
+	var scopes []string
+
+	grant := func(scope string) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	for _, role := range claimStrings(claims, v.options.RolesClaim) {
+		if mapped, ok := v.options.RoleScopes[role]; ok {
+			for _, scope := range mapped {
+				grant(scope)
+			}
+		} else if ValidScope(role) {
+			grant(role)
+		}
+	}
+	return scopes
+}
+
+// claimStrings reads a claim at a dotted path as a list of strings. Both JSON
+// arrays and space-separated strings (as used by the "scope" claim) are
+// accepted.
+func claimStrings(claims jwt.MapClaims, path string) []string {
+	var value interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "cheapeats"
+)
+
+// testJWKS serves a key set from an httptest server and signs tokens with
+// its private keys.
+type testJWKS struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]crypto.Signer
+	down    bool
+	fetches atomic.Int32
+}
+
+func newTestJWKS(t *testing.T) *testJWKS {
+	t.Helper()
+	set := &testJWKS{t: t, keys: make(map[string]crypto.Signer)}
+	set.server = httptest.NewServer(http.HandlerFunc(set.serve))
+	t.Cleanup(set.server.Close)
+	return set
+}
+
+func (s *testJWKS) addRSA(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.keys[kid] = key
+	s.mu.Unlock()
+}
+
+func (s *testJWKS) addEC(kid string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.keys[kid] = key
+	s.mu.Unlock()
+}
+
+func (s *testJWKS) setDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+func (s *testJWKS) serve(w http.ResponseWriter, r *http.Request) {
+	s.fetches.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	var keys []jwk
+	for kid, key := range s.keys {
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			keys = append(keys, jwk{Kty: "RSA", Kid: kid, Use: "sig", N: encode(key.N), E: encode(big.NewInt(int64(key.E)))})
+		case *ecdsa.PrivateKey:
+			keys = append(keys, jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: encode(key.X), Y: encode(key.Y)})
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+func (s *testJWKS) sign(kid string, claims jwt.MapClaims) string {
+	s.t.Helper()
+	s.mu.Lock()
+	key := s.keys[kid]
+	s.mu.Unlock()
+
+	method := jwt.SigningMethod(jwt.SigningMethodRS256)
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		method = jwt.SigningMethodES256
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return signed
+}
+
+func (s *testJWKS) verifier(t *testing.T, options JWTOptions) *JWTVerifier {
+	t.Helper()
+	keys, err := NewJWKS(context.Background(), "", s.server.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewJWTVerifier(keys, options)
+}
+
+// allowRefetch backdates the verifier's last JWKS fetch as if
+// minRefetchInterval had passed.
+func allowRefetch(verifier *JWTVerifier) {
+	verifier.keys.mu.Lock()
+	verifier.keys.attemptedAt = time.Now().Add(-2 * minRefetchInterval)
+	verifier.keys.mu.Unlock()
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                testIssuer,
+		"aud":                testAudience,
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"roles":              []string{"read"},
+	}
+}
+
+func TestJWTVerify(t *testing.T) {
+	set := newTestJWKS(t)
+	set.addRSA("rsa")
+	set.addEC("ec")
+
+	verifier := set.verifier(t, JWTOptions{
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		RoleScopes: map[string][]string{"editor": {ScopeRead, ScopeIngest}},
+		RateLimit:  30,
+	})
+
+	tests := []struct {
+		name    string
+		kid     string
+		claims  func(jwt.MapClaims)
+		wantErr bool
+		scopes  []string
+	}{
+		{name: "rsa", kid: "rsa", scopes: []string{ScopeRead}},
+		{name: "ec", kid: "ec", scopes: []string{ScopeRead}},
+		{name: "mapped role", kid: "rsa", claims: func(c jwt.MapClaims) { c["roles"] = []string{"editor", "read"} }, scopes: []string{ScopeRead, ScopeIngest}},
+		{name: "space separated roles", kid: "rsa", claims: func(c jwt.MapClaims) { c["roles"] = "admin other" }, scopes: []string{ScopeAdmin}},
+		{name: "unknown role", kid: "rsa", claims: func(c jwt.MapClaims) { c["roles"] = []string{"viewer"} }},
+		{name: "wrong issuer", kid: "rsa", claims: func(c jwt.MapClaims) { c["iss"] = "https://other.example.com" }, wantErr: true},
+		{name: "wrong audience", kid: "rsa", claims: func(c jwt.MapClaims) { c["aud"] = "other" }, wantErr: true},
+		{name: "expired", kid: "rsa", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, wantErr: true},
+		{name: "no expiry", kid: "rsa", claims: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: true},
+		{name: "no subject", kid: "rsa", claims: func(c jwt.MapClaims) { delete(c, "sub") }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+
+			principal, err := verifier.Verify(context.Background(), set.sign(tt.kid, claims))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Verify() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if principal.ID != "jwt:user-1" || principal.Name != "alice" || principal.RateLimit != 30 {
+				t.Errorf("Verify() = %+v", principal)
+			}
+			if !reflect.DeepEqual(principal.Scopes, tt.scopes) {
+				t.Errorf("scopes = %v, want %v", principal.Scopes, tt.scopes)
+			}
+		})
+	}
+}
+
+func TestJWTVerifyNestedRolesClaim(t *testing.T) {
+	set := newTestJWKS(t)
+	set.addRSA("rsa")
+	verifier := set.verifier(t, JWTOptions{Issuer: testIssuer, RolesClaim: "realm_access.roles"})
+
+	claims := validClaims()
+	delete(claims, "roles")
+	claims["realm_access"] = map[string]interface{}{"roles": []string{"ingest"}}
+
+	principal, err := verifier.Verify(context.Background(), set.sign("rsa", claims))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !reflect.DeepEqual(principal.Scopes, []string{ScopeIngest}) {
+		t.Errorf("scopes = %v, want [ingest]", principal.Scopes)
+	}
+}
+
+func TestJWTVerifyRejectsKeyOutsideSet(t *testing.T) {
+	set := newTestJWKS(t)
+	set.addRSA("rsa")
+	verifier := set.verifier(t, JWTOptions{Issuer: testIssuer})
+
+	other := newTestJWKS(t)
+	other.addRSA("rsa")
+
+	if _, err := verifier.Verify(context.Background(), other.sign("rsa", validClaims())); err == nil {
+		t.Fatal("Verify() accepted a token signed by a key outside the set")
+	}
+}
+
+func TestJWKSFetchesRotatedKey(t *testing.T) {
+	set := newTestJWKS(t)
+	set.addRSA("old")
+	verifier := set.verifier(t, JWTOptions{Issuer: testIssuer})
+
+	set.addEC("new")
+	allowRefetch(verifier)
+	if _, err := verifier.Verify(context.Background(), set.sign("new", validClaims())); err != nil {
+		t.Fatalf("Verify() with a rotated key error = %v", err)
+	}
+	if got := set.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+}
+
+func TestJWKSDoesNotRefetchWhileDown(t *testing.T) {
+	set := newTestJWKS(t)
+	set.addRSA("rsa")
+	verifier := set.verifier(t, JWTOptions{Issuer: testIssuer})
+
+	set.setDown(true)
+	set.addRSA("unknown")
+	allowRefetch(verifier)
+	token := set.sign("unknown", validClaims())
+	for i := 0; i < 5; i++ {
+		if _, err := verifier.Verify(context.Background(), token); err == nil {
+			t.Fatal("Verify() succeeded with a key the set doesn't have")
+		}
+	}
+	if got := set.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2: one at start and one failed refetch", got)
+	}
+
+	// Known keys keep working while the URL is down.
+	if _, err := verifier.Verify(context.Background(), set.sign("rsa", validClaims())); err != nil {
+		t.Errorf("Verify() with a known key error = %v", err)
+	}
+}
+
+func TestParseRoleScopes(t *testing.T) {
+	got, err := ParseRoleScopes("editor=read,ingest; ops=admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"editor": {ScopeRead, ScopeIngest}, "ops": {ScopeAdmin}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRoleScopes() = %v, want %v", got, want)
+	}
+
+	for _, value := range []string{"editor", "=read", "editor=write"} {
+		if _, err := ParseRoleScopes(value); err == nil {
+			t.Errorf("ParseRoleScopes(%q) succeeded, want error", value)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	keyPrefix    = "ce_"
+	keyPrefixLen = len(keyPrefix) + 8
+)
+
+// GenerateKey returns a new random API key together with the prefix shown in
+// listings and the hash that is stored in the database. The plaintext key is
+// never stored.
+func GenerateKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = keyPrefix + hex.EncodeToString(buf)
+	return key, key[:keyPrefixLen], HashKey(key), nil
+}
+
+// HashKey returns the hex-encoded SHA-256 digest of key. Keys carry enough
+// entropy that a fast hash is sufficient.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKey generates a key for apiKey, which must have its name, scopes and
+// limits set, stores it and returns the plaintext key. The plaintext cannot
+// be recovered later.
+func CreateKey(ctx context.Context, apiKey *models.APIKey) (string, error) {
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = hash
+	if err := database.GetDB().WithContext(ctx).Create(apiKey).Error; err != nil {
+		return "", fmt.Errorf("failed to store API key: %w", err)
+	}
+	return key, nil
+}
+
+// ValidateScopes checks that scopes is non-empty and only holds known scopes.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return errors.New("unknown scope " + scope + "; use read, ingest or admin")
+		}
+	}
+	return nil
+}
+
+// KeyStore looks up API keys by their hash, caching results briefly so that
+// authenticated requests don't each cost a database round trip. Only keys
+// that exist are cached: caching misses would let clients sending random
+// tokens grow the cache without bound.
+type KeyStore struct {
+	ttl time.Duration
+	// find loads the key with a hash, returning nil when there is none.
+	find    func(ctx context.Context, hash string) (*models.APIKey, error)
+	mu      sync.Mutex
+	cache   map[string]cachedKey
+	lookups int
+}
+
+type cachedKey struct {
+	key     *models.APIKey
+	expires time.Time
+}
+
+func NewKeyStore(ttl time.Duration) *KeyStore {
+	return &KeyStore{
+		ttl:   ttl,
+		find:  findKey,
+		cache: make(map[string]cachedKey),
+	}
+}
+
+func findKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	err := database.GetDB().WithContext(ctx).Where("key_hash = ?", hash).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// Lookup returns the active key matching the plaintext key, or nil if there
+// is none.
+func (s *KeyStore) Lookup(ctx context.Context, key string) (*models.APIKey, error) {
+	hash := HashKey(key)
+	now := time.Now()
+
+	s.mu.Lock()
+	s.lookups++
+	if s.lookups%1000 == 0 {
+		s.sweep(now)
+	}
+	entry, ok := s.cache[hash]
+	s.mu.Unlock()
+
+	if !ok || now.After(entry.expires) {
+		apiKey, err := s.find(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == nil {
+			s.Invalidate(hash)
+			return nil, nil
+		}
+		entry = cachedKey{key: apiKey, expires: now.Add(s.ttl)}
+
+		s.mu.Lock()
+		s.cache[hash] = entry
+		s.mu.Unlock()
+	}
+
+	if !entry.key.Active(now) {
+		return nil, nil
+	}
+	return entry.key, nil
+}
+
+// Invalidate drops a key from the cache, e.g. after it was revoked.
+func (s *KeyStore) Invalidate(hash string) {
+	s.mu.Lock()
+	delete(s.cache, hash)
+	s.mu.Unlock()
+}
+
+// sweep drops expired entries, e.g. of keys that were deleted. The caller
+// must hold s.mu.
+func (s *KeyStore) sweep(now time.Time) {
+	for hash, entry := range s.cache {
+		if now.After(entry.expires) {
+			delete(s.cache, hash)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cheapeats-api/internal/models"
+)
+
+// testKeyStore is a KeyStore over keys by hash that counts its lookups.
+type testKeyStore struct {
+	*KeyStore
+	keys  map[string]*models.APIKey
+	finds int
+}
+
+func newTestKeyStore(ttl time.Duration) *testKeyStore {
+	store := &testKeyStore{KeyStore: NewKeyStore(ttl), keys: make(map[string]*models.APIKey)}
+	store.find = func(ctx context.Context, hash string) (*models.APIKey, error) {
+		store.finds++
+		return store.keys[hash], nil
+	}
+	return store
+}
+
+// add stores a new key with scopes and returns its plaintext.
+func (s *testKeyStore) add(t *testing.T, id uint, scopes string, rateLimit int) (string, *models.APIKey) {
+	t.Helper()
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey := &models.APIKey{ID: id, Name: "test", Prefix: prefix, KeyHash: hash, Scopes: scopes, RateLimit: rateLimit}
+	s.keys[hash] = apiKey
+	return key, apiKey
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, keyPrefix) || len(key) != len(keyPrefix)+48 {
+		t.Errorf("key = %q, want %s and 48 hex digits", key, keyPrefix)
+	}
+	if prefix != key[:keyPrefixLen] {
+		t.Errorf("prefix = %q, want the key's first %d characters", prefix, keyPrefixLen)
+	}
+	if hash != HashKey(key) || len(hash) != 64 || strings.Contains(hash, key) {
+		t.Errorf("hash = %q, want the SHA-256 of the key", hash)
+	}
+	if other, _, _, _ := GenerateKey(); other == key {
+		t.Error("two keys are the same")
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"ce_test", "1c84801790c5d463b8dfccc1b5856dc7f4b66731280dd466663294be006a7796"},
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}
+	for _, tt := range tests {
+		if got := HashKey(tt.key); got != tt.want {
+			t.Errorf("HashKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	tests := []struct {
+		scopes  []string
+		wantErr bool
+	}{
+		{[]string{ScopeRead}, false},
+		{[]string{ScopeRead, ScopeIngest, ScopeAdmin}, false},
+		{nil, true},
+		{[]string{ScopeRead, "write"}, true},
+		{[]string{"Admin"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateScopes(tt.scopes); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateScopes(%v) = %v, want error %v", tt.scopes, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{[]string{ScopeRead}, ScopeRead, true},
+		{[]string{ScopeRead}, ScopeIngest, false},
+		{[]string{ScopeRead, ScopeIngest}, ScopeIngest, true},
+		{[]string{ScopeAdmin}, ScopeRead, true},
+		{[]string{ScopeAdmin}, ScopeIngest, true},
+		{nil, ScopeRead, false},
+	}
+	for _, tt := range tests {
+		principal := &Principal{Scopes: tt.scopes}
+		if got := principal.HasScope(tt.scope); got != tt.want {
+			t.Errorf("%v HasScope(%q) = %v, want %v", tt.scopes, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestKeyStoreLookup(t *testing.T) {
+	store := newTestKeyStore(time.Minute)
+	key, _ := store.add(t, 1, "read", 0)
+	revoked, revokedKey := store.add(t, 2, "read", 0)
+	revokedAt := time.Now().Add(-time.Hour)
+	revokedKey.RevokedAt = &revokedAt
+	expired, expiredKey := store.add(t, 3, "read", 0)
+	expiredAt := time.Now().Add(-time.Second)
+	expiredKey.ExpiresAt = &expiredAt
+	future, futureKey := store.add(t, 4, "read", 0)
+	expiresAt := time.Now().Add(time.Hour)
+	futureKey.ExpiresAt = &expiresAt
+
+	tests := []struct {
+		name string
+		key  string
+		want uint
+	}{
+		{"active", key, 1},
+		{"expiring later", future, 4},
+		{"revoked", revoked, 0},
+		{"expired", expired, 0},
+		{"unknown", "ce_unknown", 0},
+		{"hash instead of key", HashKey(key), 0},
+	}
+	for _, tt := range tests {
+		found, err := store.Lookup(context.Background(), tt.key)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got uint
+		if found != nil {
+			got = found.ID
+		}
+		if got != tt.want {
+			t.Errorf("%s: Lookup() = key %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKeyStoreCache(t *testing.T) {
+	ctx := context.Background()
+	store := newTestKeyStore(time.Minute)
+	key, apiKey := store.add(t, 1, "read", 0)
+
+	for i := 0; i < 3; i++ {
+		if found, err := store.Lookup(ctx, key); err != nil || found == nil {
+			t.Fatalf("Lookup() = %v, %v", found, err)
+		}
+	}
+	if store.finds != 1 {
+		t.Errorf("%d database lookups for a cached key, want 1", store.finds)
+	}
+
+	// Revoking a key reaches the cache once its entry expires.
+	revokedAt := time.Now()
+	revoked := *apiKey
+	revoked.RevokedAt = &revokedAt
+	store.keys[apiKey.KeyHash] = &revoked
+	if found, _ := store.Lookup(ctx, key); found == nil {
+		t.Error("cached key was looked up again before expiring")
+	}
+	entry := store.cache[apiKey.KeyHash]
+	entry.expires = time.Now().Add(-time.Second)
+	store.cache[apiKey.KeyHash] = entry
+	if found, _ := store.Lookup(ctx, key); found != nil || store.finds != 2 {
+		t.Errorf("after expiry Lookup() = %v with %d database lookups, want the revoked key rejected after 2", found, store.finds)
+	}
+
+	// Deleted keys and unknown ones aren't cached.
+	delete(store.keys, apiKey.KeyHash)
+	store.Invalidate(apiKey.KeyHash)
+	for i := 0; i < 2; i++ {
+		if found, _ := store.Lookup(ctx, key); found != nil {
+			t.Errorf("deleted key was found")
+		}
+	}
+	if store.finds != 4 || len(store.cache) != 0 {
+		t.Errorf("%d database lookups and %d cached keys, want misses looked up each time and not cached", store.finds, len(store.cache))
+	}
+}
+
+func TestKeyStoreLookupError(t *testing.T) {
+	store := newTestKeyStore(time.Minute)
+	failure := errors.New("connection refused")
+	store.find = func(ctx context.Context, hash string) (*models.APIKey, error) {
+		return nil, failure
+	}
+	if _, err := store.Lookup(context.Background(), "ce_key"); !errors.Is(err, failure) {
+		t.Errorf("Lookup() error = %v, want %v", err, failure)
+	}
+}
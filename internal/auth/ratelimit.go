@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is an in-memory token bucket per client. Each bucket holds up
+// to one minute's worth of requests and refills continuously.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// idleBucketTTL is how long an untouched bucket is kept. After a minute it is
+// full again anyway, so dropping it changes nothing.
+const idleBucketTTL = 2 * time.Minute
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the client's bucket. It returns whether the
+// request may proceed, how many requests remain, and when rejected, how long
+// until the next token is available.
+func (l *RateLimiter) Allow(client string, perMinute int) (bool, int, time.Duration) {
+	now := time.Now()
+	rate := float64(perMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+	if l.calls%1000 == 0 {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(perMinute), last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(perMinute), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, 0, wait
+	}
+
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// Exhausted reports whether the client's bucket is empty, without taking a
+// token, and if so how long until the next token is available.
+func (l *RateLimiter) Exhausted(client string, perMinute int) (bool, time.Duration) {
+	now := time.Now()
+	rate := float64(perMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		return false, 0
+	}
+	tokens := math.Min(float64(perMinute), b.tokens+now.Sub(b.last).Seconds()*rate)
+	if tokens >= 1 {
+		return false, 0
+	}
+	return true, time.Duration((1 - tokens) / rate * float64(time.Second))
+}
+
+func (l *RateLimiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if now.Sub(b.last) > idleBucketTTL {
+			delete(l.buckets, client)
+		}
+	}
+}
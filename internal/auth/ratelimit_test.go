@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := NewRateLimiter()
+
+	tests := []struct {
+		client    string
+		allowed   bool
+		remaining int
+	}{
+		{"a", true, 2},
+		{"a", true, 1},
+		{"b", true, 2},
+		{"a", true, 0},
+		{"a", false, 0},
+		{"b", true, 1},
+	}
+	for i, tt := range tests {
+		allowed, remaining, wait := limiter.Allow(tt.client, 3)
+		if allowed != tt.allowed || remaining != tt.remaining {
+			t.Errorf("request %d from %s = %v with %d remaining, want %v with %d", i, tt.client, allowed, remaining, tt.allowed, tt.remaining)
+		}
+		// At 3 a minute a token comes every 20 seconds.
+		if !allowed && (wait <= 19*time.Second || wait > 20*time.Second) {
+			t.Errorf("request %d from %s: wait = %v, want about 20s", i, tt.client, wait)
+		}
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := NewRateLimiter()
+	for i := 0; i < 3; i++ {
+		limiter.Allow("a", 3)
+	}
+	if exhausted, _ := limiter.Exhausted("a", 3); !exhausted {
+		t.Fatal("bucket is not exhausted after its limit")
+	}
+
+	// Twenty seconds on, one token is back, and only one.
+	limiter.buckets["a"].last = limiter.buckets["a"].last.Add(-20 * time.Second)
+	if exhausted, _ := limiter.Exhausted("a", 3); exhausted {
+		t.Error("bucket is still exhausted after refilling a token")
+	}
+	if allowed, _, _ := limiter.Allow("a", 3); !allowed {
+		t.Error("refilled token was not allowed")
+	}
+	if allowed, _, _ := limiter.Allow("a", 3); allowed {
+		t.Error("more than the refilled token was allowed")
+	}
+}
+
+func TestRateLimiterExhaustedTakesNoToken(t *testing.T) {
+	limiter := NewRateLimiter()
+	if exhausted, _ := limiter.Exhausted("a", 1); exhausted {
+		t.Error("unknown client is exhausted")
+	}
+	limiter.Allow("a", 1)
+	for i := 0; i < 3; i++ {
+		exhausted, wait := limiter.Exhausted("a", 1)
+		if !exhausted || wait <= 59*time.Second {
+			t.Errorf("check %d = %v, %v, want exhausted for about a minute", i, exhausted, wait)
+		}
+	}
+}
+
+func TestRateLimiterSweepsIdleBuckets(t *testing.T) {
+	limiter := NewRateLimiter()
+	limiter.Allow("idle", 3)
+	limiter.Allow("busy", 3)
+	limiter.buckets["idle"].last = time.Now().Add(-idleBucketTTL - time.Second)
+
+	limiter.sweep(time.Now())
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("idle bucket was kept")
+	}
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Error("busy bucket was dropped")
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and CIDR
+// ranges, such as "10.0.0.0/8,192.168.1.5".
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// RealIP sets a request's RemoteAddr to the client address a proxy passed on
+// in the X-Forwarded-For or X-Real-IP header, as chi's middleware.RealIP
+// does, but only for requests from one of the trusted proxies. Anyone can
+// send those headers, so from other peers they are ignored; otherwise a
+// client could pick a new address for each request and dodge the per-IP
+// throttle on failed authentication. X-Forwarded-For is read from the right,
+// skipping trusted proxies, since a client can put anything on its left.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedIP(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedIP returns the client address forwarded by a trusted proxy, or ""
+// when the request didn't come through one or it forwarded no valid address.
+func forwardedIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, ok := parseIP(clientIP(r))
+	if !ok || !isTrusted(peer, trusted) {
+		return ""
+	}
+
+	if header := r.Header.Values("X-Forwarded-For"); len(header) > 0 {
+		hops := strings.Split(strings.Join(header, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseIP(hops[i])
+			if !ok {
+				return ""
+			}
+			if !isTrusted(addr, trusted) {
+				return addr.String()
+			}
+		}
+		return ""
+	}
+	if addr, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+		return addr.String()
+	}
+	return ""
+}
+
+func parseIP(value string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client, as set by RealIP when behind
+// a trusted proxy.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
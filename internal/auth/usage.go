@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageRecorder counts requests per API key in memory and periodically
+// writes the totals to the database, so usage tracking doesn't add a write to
+// every request.
+type UsageRecorder struct {
+	mu       sync.Mutex
+	counts   map[usageKey]*usageCount
+	lastUsed map[uint]time.Time
+}
+
+type usageKey struct {
+	keyID uint
+	day   time.Time
+}
+
+type usageCount struct {
+	requests    int64
+	rateLimited int64
+}
+
+func NewUsageRecorder() *UsageRecorder {
+	return &UsageRecorder{
+		counts:   make(map[usageKey]*usageCount),
+		lastUsed: make(map[uint]time.Time),
+	}
+}
+
+// Record counts one request for the key. Requests without a stored key, such
+// as those using the bootstrap token, are ignored.
+func (u *UsageRecorder) Record(keyID uint, rateLimited bool) {
+	if keyID == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	count, ok := u.counts[usageKey{keyID, day}]
+	if !ok {
+		count = &usageCount{}
+		u.counts[usageKey{keyID, day}] = count
+	}
+	count.requests++
+	if rateLimited {
+		count.rateLimited++
+	}
+	u.lastUsed[keyID] = now
+}
+
+// Run flushes the counters every interval until ctx is cancelled, then
+// flushes one last time.
+func (u *UsageRecorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := u.Flush(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to flush API key usage", "error", err)
+			}
+		case <-ctx.Done():
+			if err := u.Flush(context.Background()); err != nil {
+				slog.Error("failed to flush API key usage", "error", err)
+			}
+			return
+		}
+	}
+}
+
+// Flush adds the counters collected since the last flush to the database.
+// On failure the counters are put back so they are retried next time.
+func (u *UsageRecorder) Flush(ctx context.Context) error {
+	u.mu.Lock()
+	counts, lastUsed := u.counts, u.lastUsed
+	u.counts = make(map[usageKey]*usageCount)
+	u.lastUsed = make(map[uint]time.Time)
+	u.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	err := database.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		totals := make(map[uint]int64)
+
+		for key, count := range counts {
+			usage := models.APIKeyUsage{
+				APIKeyID:    key.keyID,
+				Day:         key.day,
+				Requests:    count.requests,
+				RateLimited: count.rateLimited,
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "api_key_id"}, {Name: "day"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"requests":     gorm.Expr("api_key_usages.requests + EXCLUDED.requests"),
+					"rate_limited": gorm.Expr("api_key_usages.rate_limited + EXCLUDED.rate_limited"),
+				}),
+			}).Create(&usage).Error; err != nil {
+				return err
+			}
+			totals[key.keyID] += count.requests
+		}
+
+		for keyID, requests := range totals {
+			if err := tx.Model(&models.APIKey{}).Where("id = ?", keyID).Updates(map[string]interface{}{
+				"request_count": gorm.Expr("request_count + ?", requests),
+				"last_used_at":  lastUsed[keyID],
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		u.restore(counts, lastUsed)
+		return err
+	}
+
+	return nil
+}
+
+func (u *UsageRecorder) restore(counts map[usageKey]*usageCount, lastUsed map[uint]time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for key, count := range counts {
+		if current, ok := u.counts[key]; ok {
+			current.requests += count.requests
+			current.rateLimited += count.rateLimited
+		} else {
+			u.counts[key] = count
+		}
+	}
+	for keyID, at := range lastUsed {
+		if at.After(u.lastUsed[keyID]) {
+			u.lastUsed[keyID] = at
+		}
+	}
+}
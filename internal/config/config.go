@@ -2,16 +2,70 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	API      APIConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	API           APIConfig
+	Auth          AuthConfig
+	Ingest        IngestConfig
+	Health        HealthConfig
+	Logging       LoggingConfig
+	Tracing       TracingConfig
+	ExchangeRates ExchangeRatesConfig
 }
 
 type ServerConfig struct {
-	Port string
+	Port              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	// TrustedProxies lists the addresses, as IPs and CIDR ranges, whose
+	// X-Forwarded-For and X-Real-IP headers are believed.
+	TrustedProxies string
+}
+
+type IngestConfig struct {
+	Workers       int
+	QueueCapacity int
+	// CuisineRulesFile is a JSON file of cuisine classification rules; the
+	// built-in rules are used when it is empty.
+	CuisineRulesFile string
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration
+	// MaxIngestAge is how old the newest scraped data may be before readiness
+	// reports the ingest as degraded.
+	MaxIngestAge time.Duration
+}
+
+type LoggingConfig struct {
+	Level string
+	// SlowQueryThreshold is the duration above which SQL statements are
+	// logged as warnings. Other statements are only logged at debug level.
+	SlowQueryThreshold time.Duration
+}
+
+// TracingConfig selects where OpenTelemetry spans are sent. Exporter is
+// "otlp", "stdout" or "none".
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string
+	ServiceName  string
+	SampleRatio  float64
+}
+
+// ExchangeRatesConfig points at a rate file or feed, in ECB XML or JSON.
+// Without a source, only rates already in the database are used.
+type ExchangeRatesConfig struct {
+	Source          string
+	RefreshInterval time.Duration
 }
 
 type DatabaseConfig struct {
@@ -21,16 +75,48 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// MigrateOnStart applies pending migrations before serving instead of
+	// refusing to start.
+	MigrateOnStart bool
 }
 
 type APIConfig struct {
 	GooglePlacesAPIKey string
 }
 
+type AuthConfig struct {
+	AdminToken       string
+	DefaultRateLimit int
+	FailureLimit     int
+	JWT              JWTConfig
+}
+
+// JWTConfig enables OIDC access tokens as an alternative to API keys. It is
+// active when an issuer and either a JWKS file or URL are set.
+type JWTConfig struct {
+	Issuer     string
+	Audience   string
+	JWKSFile   string
+	JWKSURL    string
+	RolesClaim string
+	RoleScopes string
+	RateLimit  int
+}
+
+func (c JWTConfig) Enabled() bool {
+	return c.Issuer != "" && (c.JWKSFile != "" || c.JWKSURL != "")
+}
+
 func LoadConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
+			Port:              getEnv("PORT", "8080"),
+			ReadTimeout:       getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+			ReadHeaderTimeout: getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:      getEnvDuration("SERVER_WRITE_TIMEOUT", 2*time.Minute),
+			IdleTimeout:       getEnvDuration("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			ShutdownTimeout:   getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+			TrustedProxies:    getEnv("TRUSTED_PROXIES", ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -39,10 +125,49 @@ func LoadConfig() *Config {
 			Password: getEnv("DB_PASSWORD", "cheapeats_pass"),
 			DBName:   getEnv("DB_NAME", "cheapeats_db"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			MigrateOnStart: getEnvBool("DB_MIGRATE_ON_START", false),
 		},
 		API: APIConfig{
 			GooglePlacesAPIKey: getEnv("GOOGLE_PLACES_API_KEY", ""),
 		},
+		Auth: AuthConfig{
+			AdminToken:       getEnv("ADMIN_API_TOKEN", ""),
+			DefaultRateLimit: getEnvInt("API_KEY_RATE_LIMIT", 60),
+			FailureLimit:     getEnvInt("AUTH_FAILURE_LIMIT", 10),
+			JWT: JWTConfig{
+				Issuer:     getEnv("JWT_ISSUER", ""),
+				Audience:   getEnv("JWT_AUDIENCE", ""),
+				JWKSFile:   getEnv("JWT_JWKS_FILE", ""),
+				JWKSURL:    getEnv("JWT_JWKS_URL", ""),
+				RolesClaim: getEnv("JWT_ROLES_CLAIM", "roles"),
+				RoleScopes: getEnv("JWT_ROLE_SCOPES", ""),
+				RateLimit:  getEnvInt("JWT_RATE_LIMIT", 0),
+			},
+		},
+		Ingest: IngestConfig{
+			Workers:          getEnvInt("INGEST_WORKERS", 2),
+			QueueCapacity:    getEnvInt("INGEST_QUEUE_CAPACITY", 100),
+			CuisineRulesFile: getEnv("CUISINE_RULES_FILE", ""),
+		},
+		Health: HealthConfig{
+			CheckTimeout: getEnvDuration("HEALTH_CHECK_TIMEOUT", 5*time.Second),
+			MaxIngestAge: getEnvDuration("HEALTH_MAX_INGEST_AGE", 24*time.Hour),
+		},
+		Logging: LoggingConfig{
+			Level:              getEnv("LOG_LEVEL", "info"),
+			SlowQueryThreshold: getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+		},
+		Tracing: TracingConfig{
+			Exporter:     getEnv("TRACING_EXPORTER", "none"),
+			OTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", ""),
+			ServiceName:  getEnv("OTEL_SERVICE_NAME", "cheapeats-api"),
+			SampleRatio:  getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+		ExchangeRates: ExchangeRatesConfig{
+			Source:          getEnv("EXCHANGE_RATES_SOURCE", ""),
+			RefreshInterval: getEnvDuration("EXCHANGE_RATES_REFRESH_INTERVAL", 24*time.Hour),
+		},
 	}
 }
 
@@ -51,4 +176,32 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -2,9 +2,10 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
-	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/metrics"
+	"cheapeats-api/internal/tracing"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -18,6 +19,8 @@ type DBConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// Logger receives GORM's logs; nil uses GORM's default logger.
+	Logger logger.Interface
 }
 
 var DB *gorm.DB
@@ -27,31 +30,42 @@ func InitDB(config DBConfig) error {
 		config.Host, config.User, config.Password, config.DBName, config.Port, config.SSLMode)
 
 	var err error
+	gormLogger := config.Logger
+	if gormLogger == nil {
+		gormLogger = logger.Default
+	}
+
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: gormLogger,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Connected to PostgreSQL database")
+	slog.Info("connected to PostgreSQL database", "host", config.Host, "database", config.DBName)
 
-	if err := autoMigrate(); err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+	if err := DB.Use(metrics.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register metrics plugin: %w", err)
+	}
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
 	}
 
 	return nil
 }
 
-func autoMigrate() error {
-	return DB.AutoMigrate(
-		&models.Restaurant{},
-		&models.MenuItem{},
-		&models.PriceHistory{},
-		&models.ScrapedData{},
-	)
-}
-
 func GetDB() *gorm.DB {
 	return DB
-}
\ No newline at end of file
+}
+
+// Close closes the underlying connection pool.
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
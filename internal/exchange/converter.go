@@ -0,0 +1,117 @@
+// Package exchange loads currency exchange rates and converts prices
+// between currencies.
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+)
+
+// Base is the currency all rates are quoted against, as in the ECB feeds.
+const Base = "EUR"
+
+// ErrNoRate is returned when there is no rate for a currency.
+var ErrNoRate = errors.New("no exchange rate")
+
+type datedRate struct {
+	date time.Time
+	rate float64
+}
+
+// Converter converts money using the rate history it was loaded with. It is
+// safe for concurrent use and can be reloaded while serving.
+type Converter struct {
+	mu      sync.RWMutex
+	history map[string][]datedRate // oldest first
+}
+
+func NewConverter() *Converter {
+	return &Converter{history: map[string][]datedRate{Base: {{rate: 1}}}}
+}
+
+// Load replaces the converter's rates.
+func (c *Converter) Load(rates []models.ExchangeRate) {
+	history := map[string][]datedRate{Base: {{rate: 1}}}
+	for _, rate := range rates {
+		if rate.Currency == Base || rate.Rate <= 0 {
+			continue
+		}
+		history[rate.Currency] = append(history[rate.Currency], datedRate{rate.Date, rate.Rate})
+	}
+	for _, rates := range history {
+		sort.Slice(rates, func(i, j int) bool { return rates[i].date.Before(rates[j].date) })
+	}
+
+	c.mu.Lock()
+	c.history = history
+	c.mu.Unlock()
+}
+
+// Supports reports whether the converter has a rate for currency.
+func (c *Converter) Supports(currency string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.history[currency]) > 0
+}
+
+// Currencies lists the currencies with a known rate.
+func (c *Converter) Currencies() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	currencies := make([]string, 0, len(c.history))
+	for currency := range c.history {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// Convert converts m into currency at the latest rates.
+func (c *Converter) Convert(m money.Money, currency string) (money.Money, error) {
+	return c.ConvertAt(m, currency, time.Time{})
+}
+
+// ConvertAt converts m into currency at the rates published on or before at,
+// falling back to the oldest known rate for earlier dates. A zero at uses the
+// latest rates. The result is rounded to the target currency's minor unit.
+func (c *Converter) ConvertAt(m money.Money, currency string, at time.Time) (money.Money, error) {
+	if m.Currency == currency {
+		return m, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	from, err := c.rateAt(m.Currency, at)
+	if err != nil {
+		return money.Money{}, err
+	}
+	to, err := c.rateAt(currency, at)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return money.FromFloat(m.Float64()/from*to, currency), nil
+}
+
+func (c *Converter) rateAt(currency string, at time.Time) (float64, error) {
+	rates := c.history[currency]
+	if len(rates) == 0 {
+		return 0, fmt.Errorf("%w for %s", ErrNoRate, currency)
+	}
+	if at.IsZero() {
+		return rates[len(rates)-1].rate, nil
+	}
+
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].date.After(at) })
+	if i == 0 {
+		return rates[0].rate, nil
+	}
+	return rates[i-1].rate, nil
+}
@@ -0,0 +1,171 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+)
+
+// ECB reference rate feeds. The daily feed has the latest rates, the 90-day
+// and historical ones everything since then or since 1999.
+const (
+	ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	ECB90DayURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml"
+)
+
+// maxSourceSize bounds how much of a rate file or feed is read.
+const maxSourceSize = 32 << 20
+
+// Fetch reads exchange rates from source, which is either an http(s) URL or
+// a file path. The data may be in the ECB XML format or JSON of the form
+//
+//	{"base": "USD", "date": "2024-05-02", "rates": {"EUR": 0.93, "GBP": 0.80}}
+//
+// optionally wrapped in an array for several days. Rates against another
+// base are converted to euro rates, which requires the euro to be listed.
+func Fetch(ctx context.Context, client *http.Client, source string) ([]models.ExchangeRate, error) {
+	body, err := read(ctx, client, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []models.ExchangeRate
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '<' {
+		rates, err = parseECB(trimmed)
+	} else {
+		rates, err = parseJSON(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates from %s: %w", source, err)
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no exchange rates found in %s", source)
+	}
+
+	for i := range rates {
+		rates[i].Source = source
+	}
+	return rates, nil
+}
+
+func read(ctx context.Context, client *http.Client, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open exchange rate file: %w", err)
+		}
+		defer file.Close()
+		return io.ReadAll(io.LimitReader(file, maxSourceSize))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch exchange rates: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSourceSize))
+}
+
+// ecbEnvelope matches eurofxref XML: Cube elements per day, each holding a
+// Cube per currency.
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func parseECB(body []byte) ([]models.ExchangeRate, error) {
+	var envelope ecbEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range envelope.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", day.Time)
+		}
+		for _, r := range day.Rates {
+			rate, err := strconv.ParseFloat(r.Rate, 64)
+			if err != nil || rate <= 0 || !money.ValidCurrency(r.Currency) {
+				return nil, fmt.Errorf("invalid rate %s=%q on %s", r.Currency, r.Rate, day.Time)
+			}
+			rates = append(rates, models.ExchangeRate{Currency: r.Currency, Date: date, Rate: rate})
+		}
+		rates = append(rates, models.ExchangeRate{Currency: Base, Date: date, Rate: 1})
+	}
+	return rates, nil
+}
+
+type jsonRates struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func parseJSON(body []byte) ([]models.ExchangeRate, error) {
+	var days []jsonRates
+	if err := json.Unmarshal(body, &days); err != nil {
+		var day jsonRates
+		if err := json.Unmarshal(body, &day); err != nil {
+			return nil, err
+		}
+		days = []jsonRates{day}
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range days {
+		date, err := time.Parse("2006-01-02", day.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", day.Date)
+		}
+
+		if len(day.Rates) == 0 {
+			return nil, fmt.Errorf("no rates on %s", day.Date)
+		}
+
+		base := Base
+		if day.Base != "" {
+			base = strings.ToUpper(day.Base)
+		}
+		day.Rates[base] = 1
+
+		perEuro := day.Rates[Base]
+		if perEuro <= 0 {
+			return nil, errors.New("rates against " + base + " must include " + Base)
+		}
+
+		for currency, rate := range day.Rates {
+			if rate <= 0 || !money.ValidCurrency(currency) {
+				return nil, fmt.Errorf("invalid rate %s=%v on %s", currency, rate, day.Date)
+			}
+			rates = append(rates, models.ExchangeRate{Currency: currency, Date: date, Rate: rate / perEuro})
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,77 @@
+package exchange
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cheapeats-api/internal/repository"
+)
+
+// Updater keeps stored rates and a Converter up to date from a rate source.
+type Updater struct {
+	source    string
+	client    *http.Client
+	rates     repository.ExchangeRateRepository
+	converter *Converter
+}
+
+// NewUpdater returns an updater for source, a file path or URL as accepted
+// by Fetch. The source may be empty when rates are only loaded from the
+// database.
+func NewUpdater(source string, rates repository.ExchangeRateRepository, converter *Converter) *Updater {
+	return &Updater{
+		source:    source,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		rates:     rates,
+		converter: converter,
+	}
+}
+
+// Reload loads the stored rates into the converter.
+func (u *Updater) Reload(ctx context.Context) error {
+	rates, err := u.rates.List(ctx)
+	if err != nil {
+		return err
+	}
+	u.converter.Load(rates)
+	return nil
+}
+
+// Refresh fetches rates from the source, stores them and reloads the
+// converter. It returns the number of rates fetched.
+func (u *Updater) Refresh(ctx context.Context) (int, error) {
+	rates, err := Fetch(ctx, u.client, u.source)
+	if err != nil {
+		return 0, err
+	}
+	if err := u.rates.Save(ctx, rates); err != nil {
+		return 0, err
+	}
+	return len(rates), u.Reload(ctx)
+}
+
+// Run refreshes the rates now and then every interval until ctx is
+// cancelled. Failures are logged and retried at the next interval.
+func (u *Updater) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := u.Refresh(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.ErrorContext(ctx, "failed to refresh exchange rates", "source", u.source, "error", err)
+		} else {
+			slog.InfoContext(ctx, "refreshed exchange rates", "source", u.source, "rates", count)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,802 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cheapeats-api/internal/metrics"
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
+	"cheapeats-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	menuImporter *services.MenuImporter
+	repos        repository.Repositories
+}
+
+func NewAdminHandler(menuImporter *services.MenuImporter, repos repository.Repositories) *AdminHandler {
+	return &AdminHandler{
+		menuImporter: menuImporter,
+		repos:        repos,
+	}
+}
+
+// maxImportSize caps the size of uploaded menu files.
+const maxImportSize = 10 << 20
+
+// RestaurantInput is the request body for creating and updating restaurants.
+// Fields left out of a PATCH request keep their current value.
+type RestaurantInput struct {
+	ExternalID  *string  `json:"external_id"`
+	Name        *string  `json:"name"`
+	Address     *string  `json:"address"`
+	City        *string  `json:"city"`
+	State       *string  `json:"state"`
+	ZipCode     *string  `json:"zip_code"`
+	Country     *string  `json:"country"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	CuisineType *string  `json:"cuisine_type"`
+	Phone       *string  `json:"phone"`
+	Website     *string  `json:"website"`
+	Rating      *float32 `json:"rating"`
+	PriceRange  *string  `json:"price_range"`
+	TimeZone    *string  `json:"time_zone" example:"America/New_York"`
+}
+
+// MenuItemInput is the request body for creating and updating menu items.
+// Fields left out of a PATCH request keep their current value.
+type MenuItemInput struct {
+	Name        *string      `json:"name"`
+	Description *string      `json:"description"`
+	Category    *string      `json:"category"`
+	Price       *json.Number `json:"price" swaggertype:"string" example:"12.50"`
+	Currency    *string      `json:"currency"`
+	IsAvailable *bool        `json:"is_available"`
+	Diets       *[]string    `json:"diets" example:"vegan,gluten_free"`
+	Allergens   *[]string    `json:"allergens" example:"soy"`
+	SpiceLevel  *int         `json:"spice_level" example:"1"`
+}
+
+var validPriceRanges = map[string]bool{"": true, "Free": true, "$": true, "$$": true, "$$$": true, "$$$$": true, "N/A": true}
+
+// manualProvenance is recorded with prices set through the admin endpoints.
+var manualProvenance = models.PriceProvenance{Source: models.PriceSourceManual}
+
+func (in *RestaurantInput) validate(full bool) error {
+	if full && in.Name == nil {
+		return errors.New("name is required")
+	}
+	if in.Name != nil && strings.TrimSpace(*in.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+	if in.ExternalID != nil && strings.TrimSpace(*in.ExternalID) == "" {
+		return errors.New("external_id must not be empty")
+	}
+	if in.Latitude != nil && (*in.Latitude < -90 || *in.Latitude > 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if in.Longitude != nil && (*in.Longitude < -180 || *in.Longitude > 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if in.Rating != nil && (*in.Rating < 0 || *in.Rating > 5) {
+		return errors.New("rating must be between 0 and 5")
+	}
+	if in.PriceRange != nil && !validPriceRanges[*in.PriceRange] {
+		return errors.New("price_range must be one of Free, $, $$, $$$, $$$$ or N/A")
+	}
+	if in.TimeZone != nil && *in.TimeZone != "" {
+		if _, err := time.LoadLocation(*in.TimeZone); err != nil {
+			return errors.New("time_zone must be an IANA time zone such as America/New_York")
+		}
+	}
+	return nil
+}
+
+// apply copies the input onto the restaurant. With full set, fields missing
+// from the input are reset to their zero value, giving PUT semantics.
+func (in *RestaurantInput) apply(restaurant *models.Restaurant, full bool) {
+	setString(&restaurant.ExternalID, in.ExternalID, false)
+	setString(&restaurant.Name, in.Name, full)
+	setString(&restaurant.Address, in.Address, full)
+	setString(&restaurant.City, in.City, full)
+	setString(&restaurant.State, in.State, full)
+	setString(&restaurant.ZipCode, in.ZipCode, full)
+	setString(&restaurant.Country, in.Country, full)
+	setString(&restaurant.CuisineType, in.CuisineType, full)
+	setString(&restaurant.Phone, in.Phone, full)
+	setString(&restaurant.Website, in.Website, full)
+	setString(&restaurant.PriceRange, in.PriceRange, full)
+	setString(&restaurant.TimeZone, in.TimeZone, full)
+
+	if in.Latitude != nil || full {
+		restaurant.Latitude = valueOr(in.Latitude, 0)
+	}
+	if in.Longitude != nil || full {
+		restaurant.Longitude = valueOr(in.Longitude, 0)
+	}
+	if in.Rating != nil || full {
+		restaurant.Rating = valueOr(in.Rating, 0)
+	}
+}
+
+func (in *MenuItemInput) validate(full bool) error {
+	if full && in.Name == nil {
+		return errors.New("name is required")
+	}
+	if full && in.Price == nil {
+		return errors.New("price is required")
+	}
+	if in.Name != nil && strings.TrimSpace(*in.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+	if in.Currency != nil && !money.ValidCurrency(*in.Currency) {
+		return errors.New("currency must be a three-letter ISO 4217 code")
+	}
+	if in.Price != nil {
+		price, err := money.Parse(in.Price.String(), valueOr(in.Currency, money.DefaultCurrency))
+		if err != nil {
+			return errors.New("price must be a decimal number")
+		}
+		if price.IsNegative() {
+			return errors.New("price must not be negative")
+		}
+	}
+	if in.Diets != nil {
+		if _, err := models.ParseDiets(*in.Diets); err != nil {
+			return err
+		}
+	}
+	if in.Allergens != nil {
+		if _, err := models.ParseAllergens(*in.Allergens); err != nil {
+			return err
+		}
+	}
+	if in.SpiceLevel != nil && !models.ValidSpiceLevel(*in.SpiceLevel) {
+		return fmt.Errorf("spice_level must be between 0 and %d", models.MaxSpiceLevel)
+	}
+	return nil
+}
+
+// price returns the item's price after applying the input, rounded to the
+// currency's minor unit. A new currency without a new price keeps the amount.
+func (in *MenuItemInput) price(current money.Money, full bool) (money.Money, error) {
+	amount := current.String()
+	if in.Price != nil {
+		amount = in.Price.String()
+	}
+
+	currency := current.Currency
+	if in.Currency != nil {
+		currency = *in.Currency
+	} else if full {
+		currency = money.DefaultCurrency
+	}
+	return money.Parse(amount, currency)
+}
+
+// apply copies everything except the price onto the menu item; price changes
+// go through MenuRepository.Update so they end up in the history.
+func (in *MenuItemInput) apply(item *models.MenuItem, full bool) {
+	setString(&item.Name, in.Name, full)
+	setString(&item.Description, in.Description, full)
+	setString(&item.Category, in.Category, full)
+
+	if in.IsAvailable != nil {
+		item.IsAvailable = *in.IsAvailable
+	} else if full {
+		item.IsAvailable = true
+	}
+
+	// The tags were checked by validate.
+	if in.Diets != nil || full {
+		item.Diets, _ = models.ParseDiets(valueOr(in.Diets, nil))
+	}
+	if in.Allergens != nil || full {
+		item.Allergens, _ = models.ParseAllergens(valueOr(in.Allergens, nil))
+	}
+	if in.SpiceLevel != nil || full {
+		item.SpiceLevel = in.SpiceLevel
+	}
+}
+
+// CreateRestaurant godoc
+// @Summary Create a restaurant
+// @Description Create a restaurant manually. An external ID is generated when none is given.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant body RestaurantInput true "Restaurant"
+// @Success 201 {object} models.Restaurant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants [post]
+func (h *AdminHandler) CreateRestaurant(w http.ResponseWriter, r *http.Request) {
+	var input RestaurantInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := input.validate(true); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var restaurant models.Restaurant
+	input.apply(&restaurant, true)
+	if restaurant.ExternalID == "" {
+		restaurant.ExternalID = newManualExternalID()
+	}
+
+	if !h.checkExternalID(w, r, restaurant.ExternalID, 0) {
+		return
+	}
+
+	if err := h.repos.Restaurants.Create(r.Context(), &restaurant); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			respondWithError(w, http.StatusConflict, "A restaurant with this external_id already exists")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create restaurant")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, restaurant)
+}
+
+// UpdateRestaurant godoc
+// @Summary Replace a restaurant
+// @Description Replace all editable fields of a restaurant
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Param restaurant body RestaurantInput true "Restaurant"
+// @Success 200 {object} models.Restaurant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id} [put]
+func (h *AdminHandler) UpdateRestaurant(w http.ResponseWriter, r *http.Request) {
+	h.updateRestaurant(w, r, true)
+}
+
+// PatchRestaurant godoc
+// @Summary Update a restaurant
+// @Description Update only the fields present in the request body
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Param restaurant body RestaurantInput true "Fields to update"
+// @Success 200 {object} models.Restaurant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id} [patch]
+func (h *AdminHandler) PatchRestaurant(w http.ResponseWriter, r *http.Request) {
+	h.updateRestaurant(w, r, false)
+}
+
+func (h *AdminHandler) updateRestaurant(w http.ResponseWriter, r *http.Request, full bool) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	var input RestaurantInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := input.validate(full); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	restaurant, ok := h.findRestaurant(w, r, id)
+	if !ok {
+		return
+	}
+
+	if input.ExternalID != nil && *input.ExternalID != restaurant.ExternalID {
+		if !h.checkExternalID(w, r, *input.ExternalID, restaurant.ID) {
+			return
+		}
+	}
+
+	input.apply(restaurant, full)
+
+	if err := h.repos.Restaurants.Update(r.Context(), restaurant); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			respondWithError(w, http.StatusConflict, "A restaurant with this external_id already exists")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update restaurant")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, restaurant)
+}
+
+// DeleteRestaurant godoc
+// @Summary Delete a restaurant
+// @Description Soft-delete a restaurant together with its menu items
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id} [delete]
+func (h *AdminHandler) DeleteRestaurant(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	if err := h.repos.Restaurants.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Restaurant not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete restaurant")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreRestaurant godoc
+// @Summary Restore a deleted restaurant
+// @Description Undo a soft delete, including the menu items that were deleted with the restaurant
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Success 200 {object} models.Restaurant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/restore [post]
+func (h *AdminHandler) RestoreRestaurant(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	restaurant, err := h.repos.Restaurants.Restore(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Deleted restaurant not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to restore restaurant")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, restaurant)
+}
+
+// CreateMenuItem godoc
+// @Summary Create a menu item
+// @Description Add a menu item to a restaurant and record its initial price
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Param item body MenuItemInput true "Menu item"
+// @Success 201 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/menu [post]
+func (h *AdminHandler) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	var input MenuItemInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := input.validate(true); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := h.findRestaurant(w, r, restaurantID); !ok {
+		return
+	}
+
+	price, err := input.price(money.Money{}, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item := models.MenuItem{
+		RestaurantID: restaurantID,
+		Price:        price,
+	}
+	input.apply(&item, true)
+
+	if err := h.repos.Menus.Create(r.Context(), &item, manualProvenance); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create menu item")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, item)
+}
+
+// UpdateMenuItem godoc
+// @Summary Replace a menu item
+// @Description Replace all editable fields of a menu item. A price change is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param item body MenuItemInput true "Menu item"
+// @Success 200 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId} [put]
+func (h *AdminHandler) UpdateMenuItem(w http.ResponseWriter, r *http.Request) {
+	h.updateMenuItem(w, r, true)
+}
+
+// PatchMenuItem godoc
+// @Summary Update a menu item
+// @Description Update only the fields present in the request body. A price change is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param item body MenuItemInput true "Fields to update"
+// @Success 200 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId} [patch]
+func (h *AdminHandler) PatchMenuItem(w http.ResponseWriter, r *http.Request) {
+	h.updateMenuItem(w, r, false)
+}
+
+func (h *AdminHandler) updateMenuItem(w http.ResponseWriter, r *http.Request, full bool) {
+	id, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	var input MenuItemInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := input.validate(full); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, ok := h.findMenuItem(w, r, id)
+	if !ok {
+		return
+	}
+
+	price, err := input.price(item.Price, full)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input.apply(item, full)
+	changed, err := h.repos.Menus.Update(r.Context(), item, price, manualProvenance)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update menu item")
+		return
+	}
+	if changed {
+		metrics.PriceChanges.Inc()
+	}
+
+	respondWithJSON(w, http.StatusOK, item)
+}
+
+// DeleteMenuItem godoc
+// @Summary Delete a menu item
+// @Description Soft-delete a menu item. Its price history is kept.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId} [delete]
+func (h *AdminHandler) DeleteMenuItem(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	item, ok := h.findMenuItem(w, r, id)
+	if !ok {
+		return
+	}
+
+	if err := h.repos.Menus.Delete(r.Context(), item); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete menu item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreMenuItem godoc
+// @Summary Restore a deleted menu item
+// @Description Undo a soft delete of a menu item. The restaurant must not be deleted.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Success 200 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/restore [post]
+func (h *AdminHandler) RestoreMenuItem(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	item, err := h.repos.Menus.Restore(r.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Deleted menu item not found")
+		return
+	case errors.Is(err, repository.ErrRestaurantDeleted):
+		respondWithError(w, http.StatusConflict, "Restore the restaurant first")
+		return
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Failed to restore menu item")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, item)
+}
+
+// ImportMenu godoc
+// @Summary Import a restaurant menu
+// @Description Import menu items from CSV (columns: name, description, category, price, currency, available, diets, allergens, spice_level; tags separated by ";"), JSON, or schema.org JSON-LD (a Menu with hasMenuSection and hasMenuItem, offers and suitableForDiet). Items are matched by name; new items are created, changed items updated and items missing from the file deactivated, all in one transaction with price history. Use dry_run=true to get the diff without applying it.
+// @Tags admin
+// @Accept json
+// @Accept text/csv
+// @Accept application/ld+json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Param format query string false "Input format: csv, json or jsonld (default: from Content-Type)"
+// @Param dry_run query bool false "Only report the changes that would be made"
+// @Param menu body []services.MenuImportRow true "Menu items"
+// @Success 200 {object} services.MenuImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/menu/import [post]
+func (h *AdminHandler) ImportMenu(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid dry_run value")
+			return
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		contentType := r.Header.Get("Content-Type")
+		switch {
+		case strings.Contains(contentType, "csv"):
+			format = "csv"
+		case strings.Contains(contentType, "ld+json"):
+			format = "jsonld"
+		case strings.Contains(contentType, "json"):
+			format = "json"
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var rows []services.MenuImportRow
+	switch format {
+	case "csv":
+		rows, err = h.menuImporter.ParseCSV(body)
+	case "json":
+		rows, err = h.menuImporter.ParseJSON(body)
+	case "jsonld":
+		rows, err = h.menuImporter.ParseJSONLD(body)
+	default:
+		respondWithError(w, http.StatusBadRequest, "Unsupported import format; use csv, json or jsonld")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := h.findRestaurant(w, r, restaurantID); !ok {
+		return
+	}
+
+	result, err := h.menuImporter.Import(r.Context(), h.repos, restaurantID, rows, dryRun)
+	if err != nil {
+		var importErr *services.MenuImportError
+		if errors.As(err, &importErr) {
+			respondWithError(w, http.StatusBadRequest, importErr.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to import menu")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// findRestaurant loads the live restaurant with ID id, responding with an
+// error when there is none.
+func (h *AdminHandler) findRestaurant(w http.ResponseWriter, r *http.Request, id uint) (*models.Restaurant, bool) {
+	restaurant, err := h.repos.Restaurants.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Restaurant not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch restaurant")
+		}
+		return nil, false
+	}
+	return restaurant, true
+}
+
+// findMenuItem loads the live menu item with ID id, responding with an
+// error when there is none.
+func (h *AdminHandler) findMenuItem(w http.ResponseWriter, r *http.Request, id uint) (*models.MenuItem, bool) {
+	item, err := h.repos.Menus.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Menu item not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch menu item")
+		}
+		return nil, false
+	}
+	return item, true
+}
+
+// checkExternalID responds with a conflict when externalID belongs to, or
+// is linked to, a restaurant other than the one with ID id, deleted or not.
+// Restaurants.Create and Update still return ErrDuplicate if another request
+// takes the external ID in between.
+func (h *AdminHandler) checkExternalID(w http.ResponseWriter, r *http.Request, externalID string, id uint) bool {
+	restaurant, err := h.repos.Restaurants.GetByExternalID(r.Context(), externalID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return true
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Failed to check external_id")
+		return false
+	case restaurant.ID != id:
+		respondWithError(w, http.StatusConflict, "A restaurant with this external_id already exists")
+		return false
+	}
+	return true
+}
+
+func decodeJSONBody(r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return errors.New("Invalid request body: " + err.Error())
+	}
+	return nil
+}
+
+func parseIDParam(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid id")
+	}
+	return uint(id), nil
+}
+
+// optionalIDQuery parses an optional ID query parameter; it returns nil when
+// the parameter is absent.
+func optionalIDQuery(r *http.Request, name string) (*uint, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return nil, errors.New("invalid id")
+	}
+	parsed := uint(id)
+	return &parsed, nil
+}
+
+func newManualExternalID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return "manual:" + hex.EncodeToString(buf)
+}
+
+func setString(dst *string, value *string, reset bool) {
+	if value != nil {
+		*dst = strings.TrimSpace(*value)
+	} else if reset {
+		*dst = ""
+	}
+}
+
+func valueOr[T any](value *T, fallback T) T {
+	if value != nil {
+		return *value
+	}
+	return fallback
+}
@@ -0,0 +1,636 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
+	"cheapeats-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// serveRoute runs handler for a request to path, routed through pattern so
+// that its URL parameters are set, and returns the response.
+func serveRoute(t *testing.T, handler http.HandlerFunc, method, pattern, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := chi.NewRouter()
+	router.Method(method, pattern, handler)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body == "" {
+		req.ContentLength = 0
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// responseItem is the part of a priced record's JSON that tests check;
+// money.Money is encoded but not decoded.
+type responseItem struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Price    string `json:"price"`
+	Currency string `json:"currency"`
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder, dst interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), dst); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+func createTestRestaurant(t *testing.T, repos repository.Repositories, name string) *models.Restaurant {
+	t.Helper()
+	restaurant := &models.Restaurant{Name: name, City: "Springfield", ExternalID: "manual:" + name}
+	if err := repos.Restaurants.Create(context.Background(), restaurant); err != nil {
+		t.Fatal(err)
+	}
+	return restaurant
+}
+
+func createTestItem(t *testing.T, repos repository.Repositories, restaurantID uint, name, price string) *models.MenuItem {
+	t.Helper()
+	amount, err := money.Parse(price, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &models.MenuItem{RestaurantID: restaurantID, Name: name, Price: amount, IsAvailable: true}
+	if err := repos.Menus.Create(context.Background(), item, manualProvenance); err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func newTestAdminHandler() (*AdminHandler, repository.Repositories) {
+	repos := repository.NewMemoryRepositories()
+	return NewAdminHandler(services.NewMenuImporter(), repos), repos
+}
+
+func TestPromoEndpoints(t *testing.T) {
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	other := createTestRestaurant(t, repos, "Mario's")
+	item := createTestItem(t, repos, restaurant.ID, "Margherita Pizza", "12.00")
+	otherItem := createTestItem(t, repos, other.ID, "Calzone", "10.00")
+
+	rec := serveRoute(t, h.CreatePromo, http.MethodPost, "/restaurants/{id}/promos", "/restaurants/999/promos",
+		`{"name": "Happy hour", "discount_percent": 25}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown restaurant: status = %d, want 404", rec.Code)
+	}
+
+	body := `{"name": "Lunch", "menu_item_id": ` + jsonID(otherItem.ID) + `, "price": "5.00"}`
+	rec = serveRoute(t, h.CreatePromo, http.MethodPost, "/restaurants/{id}/promos", "/restaurants/"+jsonID(restaurant.ID)+"/promos", body)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("another restaurant's item: status = %d, want 400", rec.Code)
+	}
+
+	body = `{"name": "Lunch", "menu_item_id": ` + jsonID(item.ID) + `, "price": "9.50"}`
+	rec = serveRoute(t, h.CreatePromo, http.MethodPost, "/restaurants/{id}/promos", "/restaurants/"+jsonID(restaurant.ID)+"/promos", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var promo responseItem
+	decodeResponse(t, rec, &promo)
+	if promo.Price != "9.50" || promo.Currency != "USD" {
+		t.Errorf("created promo price = %s %s, want 9.50 USD", promo.Price, promo.Currency)
+	}
+
+	rec = serveRoute(t, h.PatchPromo, http.MethodPatch, "/promos/{promoId}", "/promos/"+jsonID(promo.ID), `{"discount_percent": 20}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: status = %d, body %s", rec.Code, rec.Body)
+	}
+	stored, err := repos.Promos.Get(context.Background(), promo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Price != nil || stored.DiscountPercent == nil || *stored.DiscountPercent != 20 || stored.Name != "Lunch" {
+		t.Errorf("patched promo = %+v, want 20%% off named Lunch", stored)
+	}
+
+	rec = serveRoute(t, h.DeletePromo, http.MethodDelete, "/promos/{promoId}", "/promos/"+jsonID(promo.ID), "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	rec = serveRoute(t, h.DeletePromo, http.MethodDelete, "/promos/{promoId}", "/promos/"+jsonID(promo.ID), "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("delete again: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestOpeningHoursEndpoints(t *testing.T) {
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	path := "/restaurants/" + jsonID(restaurant.ID) + "/opening-hours"
+	hours := `{"periods": [{"open": {"day": 1, "time": "09:00"}, "close": {"day": 1, "time": "17:00"}}]}`
+
+	rec := serveRoute(t, h.SetOpeningHours, http.MethodPut, "/restaurants/{id}/opening-hours", "/restaurants/999/opening-hours", hours)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown restaurant: status = %d, want 404", rec.Code)
+	}
+
+	rec = serveRoute(t, h.SetOpeningHours, http.MethodPut, "/restaurants/{id}/opening-hours", path, hours)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set: status = %d, body %s", rec.Code, rec.Body)
+	}
+	stored, err := repos.Restaurants.Get(context.Background(), restaurant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.OpeningHours == nil || len(stored.OpeningHours.Periods) != 1 {
+		t.Fatalf("stored opening hours = %+v, want one period", stored.OpeningHours)
+	}
+
+	rec = serveRoute(t, h.DeleteOpeningHours, http.MethodDelete, "/restaurants/{id}/opening-hours", path, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	rec = serveRoute(t, h.DeleteOpeningHours, http.MethodDelete, "/restaurants/{id}/opening-hours", path, "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("delete again: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestVariantEndpoints(t *testing.T) {
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	item := createTestItem(t, repos, restaurant.ID, "Margherita Pizza", "12.00")
+	itemPath := "/menu-items/" + jsonID(item.ID) + "/variants"
+
+	rec := serveRoute(t, h.CreateVariant, http.MethodPost, "/menu-items/{itemId}/variants", itemPath, `{"name": "Large", "price": "15.00"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var variant responseItem
+	decodeResponse(t, rec, &variant)
+
+	rec = serveRoute(t, h.CreateVariant, http.MethodPost, "/menu-items/{itemId}/variants", itemPath, `{"name": "large", "price": "16.00"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("duplicate name: status = %d, want 409", rec.Code)
+	}
+
+	variantPath := itemPath + "/" + jsonID(variant.ID)
+	rec = serveRoute(t, h.PatchVariant, http.MethodPatch, "/menu-items/{itemId}/variants/{variantId}", variantPath, `{"price": "15.50"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	stored, err := repos.Menus.Get(context.Background(), item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Variants) != 1 || stored.Variants[0].Price.String() != "15.50" {
+		t.Fatalf("stored variants = %+v, want Large at 15.50", stored.Variants)
+	}
+	if got := len(stored.Variants[0].PriceHistory); got != 2 {
+		t.Errorf("variant price history has %d entries, want 2", got)
+	}
+
+	rec = serveRoute(t, h.DeleteVariant, http.MethodDelete, "/menu-items/{itemId}/variants/{variantId}", "/menu-items/999/variants/"+jsonID(variant.ID), "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("delete under another item: status = %d, want 404", rec.Code)
+	}
+	rec = serveRoute(t, h.DeleteVariant, http.MethodDelete, "/menu-items/{itemId}/variants/{variantId}", variantPath, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+
+	// The name is free again once the variant is deleted.
+	rec = serveRoute(t, h.CreateVariant, http.MethodPost, "/menu-items/{itemId}/variants", itemPath, `{"name": "Large", "price": "16.00"}`)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("recreate: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRenameMenuItemMergesSameName(t *testing.T) {
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	item := createTestItem(t, repos, restaurant.ID, "Burger", "9.00")
+	renamed := createTestItem(t, repos, restaurant.ID, "Classic Burger", "9.50")
+
+	rec := serveRoute(t, h.RenameMenuItem, http.MethodPost, "/menu-items/{itemId}/rename",
+		"/menu-items/"+jsonID(item.ID)+"/rename", `{"name": "classic burger"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rename: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got responseItem
+	decodeResponse(t, rec, &got)
+	if got.Name != "classic burger" || got.Price != "9.50" {
+		t.Errorf("renamed item = %s at %s, want classic burger at 9.50", got.Name, got.Price)
+	}
+	if _, err := repos.Menus.Get(context.Background(), renamed.ID); err != repository.ErrNotFound {
+		t.Errorf("merged item still exists: %v", err)
+	}
+}
+
+func TestMergeMenuItemRejectsOtherRestaurant(t *testing.T) {
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	other := createTestRestaurant(t, repos, "Mario's")
+	item := createTestItem(t, repos, restaurant.ID, "Burger", "9.00")
+	otherItem := createTestItem(t, repos, other.ID, "Burger", "9.50")
+
+	rec := serveRoute(t, h.MergeMenuItem, http.MethodPost, "/menu-items/{itemId}/merge",
+		"/menu-items/"+jsonID(item.ID)+"/merge", `{"source_id": `+jsonID(otherItem.ID)+`}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestDuplicateEndpoints(t *testing.T) {
+	h, repos := newTestAdminHandler()
+	ctx := context.Background()
+	keep := createTestRestaurant(t, repos, "Luigi's")
+	duplicate := createTestRestaurant(t, repos, "Luigis")
+	createTestItem(t, repos, keep.ID, "Margherita Pizza", "12.00")
+	createTestItem(t, repos, duplicate.ID, "Margherita Pizza", "12.50")
+	createTestItem(t, repos, duplicate.ID, "Tiramisu", "6.00")
+	third := createTestRestaurant(t, repos, "Luigi's Pizza")
+
+	for _, candidate := range []*models.DuplicateCandidate{
+		{RestaurantID: keep.ID, DuplicateID: duplicate.ID, Score: 0.9, Status: models.DuplicatePending},
+		{RestaurantID: keep.ID, DuplicateID: third.ID, Score: 0.7, Status: models.DuplicatePending},
+	} {
+		if _, err := repos.Duplicates.Queue(ctx, candidate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := serveRoute(t, h.ListDuplicates, http.MethodGet, "/admin/duplicates", "/admin/duplicates", "")
+	var candidates []models.DuplicateCandidate
+	decodeResponse(t, rec, &candidates)
+	if len(candidates) != 2 || candidates[0].DuplicateID != duplicate.ID {
+		t.Fatalf("pending candidates = %+v, want the closer pair first", candidates)
+	}
+
+	rec = serveRoute(t, h.DismissDuplicate, http.MethodPost, "/admin/duplicates/{id}/dismiss",
+		"/admin/duplicates/"+jsonID(candidates[1].ID)+"/dismiss", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dismiss: status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec = serveRoute(t, h.DismissDuplicate, http.MethodPost, "/admin/duplicates/{id}/dismiss",
+		"/admin/duplicates/"+jsonID(candidates[1].ID)+"/dismiss", "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("dismiss again: status = %d, want 409", rec.Code)
+	}
+
+	rec = serveRoute(t, h.MergeDuplicate, http.MethodPost, "/admin/duplicates/{id}/merge",
+		"/admin/duplicates/"+jsonID(candidates[0].ID)+"/merge", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("merge: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var result services.RestaurantMergeResult
+	decodeResponse(t, rec, &result)
+	if result.RestaurantID != keep.ID || result.MovedItems != 1 || result.MergedItems != 1 {
+		t.Errorf("merge result = %+v, want one item moved and one merged into %d", result, keep.ID)
+	}
+
+	merged, err := repos.Restaurants.Get(ctx, keep.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.MenuItems) != 2 {
+		t.Errorf("kept restaurant has %d menu items, want 2", len(merged.MenuItems))
+	}
+	if _, err := repos.Restaurants.Get(ctx, duplicate.ID); err != repository.ErrNotFound {
+		t.Errorf("duplicate restaurant still exists: %v", err)
+	}
+
+	rec = serveRoute(t, h.ListDuplicates, http.MethodGet, "/admin/duplicates", "/admin/duplicates?status=merged", "")
+	decodeResponse(t, rec, &candidates)
+	if len(candidates) != 1 || candidates[0].Duplicate == nil || candidates[0].Duplicate.Name != "Luigis" {
+		t.Errorf("merged candidates = %+v, want the pair with the deleted restaurant", candidates)
+	}
+}
+
+func TestMenuMatchEndpoints(t *testing.T) {
+	ctx := context.Background()
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	importMenu := func(body string) {
+		t.Helper()
+		rec := serveRoute(t, func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("Content-Type", "application/json")
+			h.ImportMenu(w, r)
+		}, http.MethodPost, "/restaurants/{id}/menu/import", "/restaurants/"+jsonID(restaurant.ID)+"/menu/import", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("import: status = %d, body %s", rec.Code, rec.Body)
+		}
+	}
+	importMenu(`[
+		{"name": "Classic Burger", "category": "Burgers", "price": "12.99", "currency": "USD"},
+		{"name": "Margherita Pizza", "category": "Pizza", "price": "12.00", "currency": "USD"}
+	]`)
+	items, err := repos.Menus.ListByRestaurant(ctx, restaurant.ID, repository.MenuItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	burger, pizza := items[0], items[1]
+
+	// Alike enough to match, but not to be renamed automatically.
+	importMenu(`[
+		{"name": "Classic Cheeseburger", "category": "Burgers", "price": "13.99", "currency": "USD"},
+		{"name": "Marinara Pizza", "category": "Pizza", "price": "12.00", "currency": "USD"}
+	]`)
+
+	rec := serveRoute(t, h.ListMenuMatches, http.MethodGet, "/admin/menu-matches", "/admin/menu-matches", "")
+	var candidates []struct {
+		ID         uint         `json:"id"`
+		MenuItemID uint         `json:"menu_item_id"`
+		MenuItem   responseItem `json:"menu_item"`
+		NewItemID  uint         `json:"new_item_id"`
+		NewItem    responseItem `json:"new_item"`
+	}
+	decodeResponse(t, rec, &candidates)
+	if len(candidates) != 2 || candidates[0].MenuItemID != burger.ID || candidates[1].MenuItemID != pizza.ID {
+		t.Fatalf("pending candidates = %+v, want the burger, the more confident match, first", candidates)
+	}
+	merge, dismiss := candidates[0], candidates[1]
+	if merge.NewItem.Name != "Classic Cheeseburger" {
+		t.Errorf("candidate new item = %+v, want Classic Cheeseburger", merge.NewItem)
+	}
+
+	rec = serveRoute(t, h.DismissMenuMatch, http.MethodPost, "/admin/menu-matches/{id}/dismiss",
+		"/admin/menu-matches/"+jsonID(dismiss.ID)+"/dismiss", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dismiss: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec = serveRoute(t, h.MergeMenuMatch, http.MethodPost, "/admin/menu-matches/{id}/merge",
+		"/admin/menu-matches/"+jsonID(merge.ID)+"/merge", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("merge: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got responseItem
+	decodeResponse(t, rec, &got)
+	if got.ID != burger.ID || got.Name != "Classic Cheeseburger" || got.Price != "13.99" {
+		t.Errorf("merged item = %+v, want item %d renamed at 13.99", got, burger.ID)
+	}
+	stored, err := repos.Menus.Get(ctx, burger.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.PriceHistory) != 2 {
+		t.Errorf("merged item has %d history entries, want 2", len(stored.PriceHistory))
+	}
+	if _, err := repos.Menus.Get(ctx, merge.NewItemID); err != repository.ErrNotFound {
+		t.Errorf("new item still exists: %v", err)
+	}
+
+	rec = serveRoute(t, h.MergeMenuMatch, http.MethodPost, "/admin/menu-matches/{id}/merge",
+		"/admin/menu-matches/"+jsonID(merge.ID)+"/merge", "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("merge again: status = %d, want 409", rec.Code)
+	}
+	rec = serveRoute(t, h.ListMenuMatches, http.MethodGet, "/admin/menu-matches", "/admin/menu-matches?status=merged", "")
+	decodeResponse(t, rec, &candidates)
+	if len(candidates) != 1 || candidates[0].ID != merge.ID {
+		t.Errorf("merged candidates = %+v, want candidate %d", candidates, merge.ID)
+	}
+}
+
+func jsonID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// racingRestaurants misses every external ID in lookups, as if another
+// request took it just after the handler checked.
+type racingRestaurants struct {
+	repository.RestaurantRepository
+}
+
+func (racingRestaurants) GetByExternalID(ctx context.Context, externalID string) (*models.Restaurant, error) {
+	return nil, repository.ErrNotFound
+}
+
+func TestRestaurantExternalIDRace(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	repos.Restaurants = racingRestaurants{repos.Restaurants}
+	h := NewAdminHandler(services.NewMenuImporter(), repos)
+	existing := createTestRestaurant(t, repos, "Luigi's")
+
+	rec := serveRoute(t, h.CreateRestaurant, http.MethodPost, "/restaurants", "/restaurants",
+		`{"name": "Mario's", "city": "Springfield", "external_id": "`+existing.ExternalID+`"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("create: status = %d, want 409", rec.Code)
+	}
+
+	other := createTestRestaurant(t, repos, "Mario's")
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		handler := h.UpdateRestaurant
+		if method == http.MethodPatch {
+			handler = h.PatchRestaurant
+		}
+		rec = serveRoute(t, handler, method, "/restaurants/{id}", "/restaurants/"+jsonID(other.ID),
+			`{"name": "Mario's", "city": "Springfield", "external_id": "`+existing.ExternalID+`"}`)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("%s: status = %d, want 409", method, rec.Code)
+		}
+	}
+}
+
+func TestRestaurantLifecycle(t *testing.T) {
+	ctx := context.Background()
+	h, repos := newTestAdminHandler()
+	existing := createTestRestaurant(t, repos, "Luigi's")
+
+	rec := serveRoute(t, h.CreateRestaurant, http.MethodPost, "/restaurants", "/restaurants",
+		`{"name": "Mario's", "city": "Springfield", "external_id": "manual:Luigi's"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("taken external_id: status = %d, want 409", rec.Code)
+	}
+
+	rec = serveRoute(t, h.CreateRestaurant, http.MethodPost, "/restaurants", "/restaurants", `{"name": "Mario's", "city": "Springfield"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var created models.Restaurant
+	decodeResponse(t, rec, &created)
+	path := "/restaurants/" + jsonID(created.ID)
+
+	rec = serveRoute(t, h.PatchRestaurant, http.MethodPatch, "/restaurants/{id}", path, `{"external_id": "manual:Luigi's"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("patch to a taken external_id: status = %d, want 409", rec.Code)
+	}
+	rec = serveRoute(t, h.PatchRestaurant, http.MethodPatch, "/restaurants/{id}", path, `{"phone": "555-0100"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: status = %d, body %s", rec.Code, rec.Body)
+	}
+	stored, err := repos.Restaurants.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Phone != "555-0100" || stored.Name != "Mario's" {
+		t.Errorf("patched restaurant = %q, %q", stored.Name, stored.Phone)
+	}
+
+	// Items deleted before the restaurant stay deleted when it is restored.
+	kept := createTestItem(t, repos, created.ID, "Calzone", "10.00")
+	gone := createTestItem(t, repos, created.ID, "Stromboli", "11.00")
+	if err := repos.Menus.Delete(ctx, gone); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = serveRoute(t, h.DeleteRestaurant, http.MethodDelete, "/restaurants/{id}", path, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	if _, err := repos.Menus.Get(ctx, kept.ID); err != repository.ErrNotFound {
+		t.Errorf("menu item of deleted restaurant: err = %v, want ErrNotFound", err)
+	}
+	rec = serveRoute(t, h.RestoreMenuItem, http.MethodPost, "/menu-items/{itemId}/restore", "/menu-items/"+jsonID(kept.ID)+"/restore", "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("restore item of deleted restaurant: status = %d, want 409", rec.Code)
+	}
+
+	rec = serveRoute(t, h.RestoreRestaurant, http.MethodPost, "/restaurants/{id}/restore", path+"/restore", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if _, err := repos.Menus.Get(ctx, kept.ID); err != nil {
+		t.Errorf("menu item deleted with the restaurant: %v", err)
+	}
+	if _, err := repos.Menus.Get(ctx, gone.ID); err != repository.ErrNotFound {
+		t.Errorf("menu item deleted before the restaurant: err = %v, want ErrNotFound", err)
+	}
+	rec = serveRoute(t, h.RestoreRestaurant, http.MethodPost, "/restaurants/{id}/restore", "/restaurants/"+jsonID(existing.ID)+"/restore", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("restore a live restaurant: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestMenuItemLifecycle(t *testing.T) {
+	ctx := context.Background()
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+
+	rec := serveRoute(t, h.CreateMenuItem, http.MethodPost, "/restaurants/{id}/menu", "/restaurants/"+jsonID(restaurant.ID)+"/menu",
+		`{"name": "Margherita Pizza", "category": "Pizza", "price": "12.00", "currency": "USD"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var item responseItem
+	decodeResponse(t, rec, &item)
+	path := "/menu-items/" + jsonID(item.ID)
+
+	rec = serveRoute(t, h.PatchMenuItem, http.MethodPatch, "/menu-items/{itemId}", path, `{"description": "Tomato and mozzarella"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch description: status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec = serveRoute(t, h.PatchMenuItem, http.MethodPatch, "/menu-items/{itemId}", path, `{"price": "13.50"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch price: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	stored, err := repos.Menus.Get(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Description != "Tomato and mozzarella" || stored.Price.String() != "13.50" || stored.Category != "Pizza" {
+		t.Errorf("patched item = %q, %q, %s", stored.Description, stored.Category, stored.Price)
+	}
+	if len(stored.PriceHistory) != 2 {
+		t.Errorf("price history has %d entries, want the initial price and one change", len(stored.PriceHistory))
+	}
+
+	rec = serveRoute(t, h.DeleteMenuItem, http.MethodDelete, "/menu-items/{itemId}", path, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	rec = serveRoute(t, h.PatchMenuItem, http.MethodPatch, "/menu-items/{itemId}", path, `{"price": "14.00"}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("patch deleted item: status = %d, want 404", rec.Code)
+	}
+	rec = serveRoute(t, h.RestoreMenuItem, http.MethodPost, "/menu-items/{itemId}/restore", path+"/restore", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore: status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec = serveRoute(t, h.RestoreMenuItem, http.MethodPost, "/menu-items/{itemId}/restore", path+"/restore", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("restore live item: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestImportMenu(t *testing.T) {
+	ctx := context.Background()
+	h, repos := newTestAdminHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	pizza := createTestItem(t, repos, restaurant.ID, "Margherita Pizza", "12.00")
+	wings := createTestItem(t, repos, restaurant.ID, "Chicken Wing", "9.00")
+	salad := createTestItem(t, repos, restaurant.ID, "Caesar Salad", "8.00")
+
+	pattern := "/restaurants/{id}/menu/import"
+	path := "/restaurants/" + jsonID(restaurant.ID) + "/menu/import"
+	body := `[
+		{"name": "margherita pizza", "price": "12.50", "currency": "USD"},
+		{"name": "Chicken Wings", "price": "9.00", "currency": "USD"},
+		{"name": "Tiramisu", "price": "6.00", "currency": "USD"}
+	]`
+	importMenu := func(query string) services.MenuImportResult {
+		t.Helper()
+		rec := serveRoute(t, func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("Content-Type", "application/json")
+			h.ImportMenu(w, r)
+		}, http.MethodPost, pattern, path+query, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("import%s: status = %d, body %s", query, rec.Code, rec.Body)
+		}
+		var result services.MenuImportResult
+		decodeResponse(t, rec, &result)
+		return result
+	}
+
+	want := services.MenuImportResult{Created: 1, Updated: 2, Deactivated: 1, PriceChanges: 1}
+	result := importMenu("?dry_run=true")
+	if result.Created != want.Created || result.Updated != want.Updated || result.Deactivated != want.Deactivated || result.PriceChanges != want.PriceChanges {
+		t.Errorf("dry run = %+v", result)
+	}
+	items, err := repos.Menus.ListByRestaurant(ctx, restaurant.ID, repository.MenuItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].Price.String() != "12.00" {
+		t.Fatalf("dry run changed the menu")
+	}
+
+	result = importMenu("")
+	if result.Created != want.Created || result.Updated != want.Updated || result.Deactivated != want.Deactivated || result.PriceChanges != want.PriceChanges {
+		t.Errorf("import = %+v", result)
+	}
+
+	stored, err := repos.Menus.Get(ctx, pizza.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Price.String() != "12.50" || len(stored.PriceHistory) != 2 {
+		t.Errorf("pizza = %s with %d history entries, want 12.50 with 2", stored.Price, len(stored.PriceHistory))
+	}
+	if stored, err := repos.Menus.Get(ctx, wings.ID); err != nil || stored.Name != "Chicken Wings" {
+		t.Errorf("wings were not renamed: %+v, %v", stored, err)
+	}
+	if stored, err := repos.Menus.Get(ctx, salad.ID); err != nil || stored.IsAvailable {
+		t.Errorf("salad missing from the file was not deactivated: %+v, %v", stored, err)
+	}
+	items, err = repos.Menus.ListByRestaurant(ctx, restaurant.ID, repository.MenuItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 || items[3].Name != "Tiramisu" {
+		t.Errorf("menu after import = %d items", len(items))
+	}
+
+	rec := serveRoute(t, h.ImportMenu, http.MethodPost, pattern, "/restaurants/999/menu/import?format=json", body)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown restaurant: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"cheapeats-api/internal/auth"
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/models"
+)
+
+type APIKeyHandler struct {
+	keys *auth.KeyStore
+}
+
+func NewAPIKeyHandler(keys *auth.KeyStore) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys: keys,
+	}
+}
+
+// APIKeyInput is the request body for creating an API key.
+type APIKeyInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	RateLimit int        `json:"rate_limit"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned once when a key is created. The plaintext key
+// cannot be retrieved again.
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+func (in *APIKeyInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := auth.ValidateScopes(in.Scopes); err != nil {
+		return err
+	}
+	if in.RateLimit < 0 {
+		return errors.New("rate_limit must not be negative")
+	}
+	if in.ExpiresAt != nil && in.ExpiresAt.Before(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key with the given scopes. The plaintext key is only returned in this response. A rate_limit of 0 uses the server default.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key body APIKeyInput true "API key"
+// @Success 201 {object} CreatedAPIKey
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var input APIKeyInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := input.validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiKey := models.APIKey{
+		Name:      input.Name,
+		Scopes:    strings.Join(input.Scopes, ","),
+		RateLimit: input.RateLimit,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	key, err := auth.CreateKey(r.Context(), &apiKey)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, CreatedAPIKey{APIKey: apiKey, Key: key})
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List all API keys with their total request counts. Key hashes are never returned.
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var keys []models.APIKey
+
+	if err := database.GetDB().WithContext(r.Context()).Order("id").Find(&keys).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch API keys")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// GetAPIKeyUsage godoc
+// @Summary Get API key usage
+// @Description Get daily request and rate-limit counters for an API key, newest first
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date, exclusive (YYYY-MM-DD)"
+// @Success 200 {array} models.APIKeyUsage
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/api-keys/{id}/usage [get]
+func (h *APIKeyHandler) GetAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	query := database.GetDB().WithContext(r.Context()).Where("api_key_id = ?", id)
+
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		from, err := parseDateParam(fromStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid from date")
+			return
+		}
+		query = query.Where("day >= ?", from)
+	}
+
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err := parseDateParam(toStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid to date")
+			return
+		}
+		query = query.Where("day < ?", to)
+	}
+
+	var usage []models.APIKeyUsage
+	if err := query.Order("day DESC").Find(&usage).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch API key usage")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, usage)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key. Revoked keys are kept for their usage history.
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIKey
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	db := database.GetDB().WithContext(r.Context())
+	var apiKey models.APIKey
+
+	if err := db.First(&apiKey, id).Error; err != nil {
+		respondWithError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		if err := db.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke API key")
+			return
+		}
+		apiKey.RevokedAt = &now
+	}
+
+	h.keys.Invalidate(apiKey.KeyHash)
+
+	respondWithJSON(w, http.StatusOK, apiKey)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+)
+
+// CuisinesInput is the request body for setting a restaurant's cuisines, by
+// slug, main cuisine first.
+type CuisinesInput struct {
+	Cuisines []string `json:"cuisines"`
+}
+
+// GetCuisines godoc
+// @Summary List cuisines
+// @Description List the cuisine taxonomy. Slugs are the values accepted by the cuisine filter.
+// @Tags restaurants
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Cuisine
+// @Failure 500 {object} map[string]string
+// @Router /cuisines [get]
+func (h *RestaurantHandler) GetCuisines(w http.ResponseWriter, r *http.Request) {
+	cuisines, err := h.repos.Restaurants.ListCuisines(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch cuisines")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, cuisines)
+}
+
+// SetRestaurantCuisines godoc
+// @Summary Set a restaurant's cuisines
+// @Description Replace a restaurant's cuisines with existing cuisines from the taxonomy, main cuisine first. The restaurant's cuisine_type becomes the main cuisine's name. An empty list clears them.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Param cuisines body CuisinesInput true "Cuisine slugs"
+// @Success 200 {object} models.Restaurant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/cuisines [put]
+func (h *AdminHandler) SetRestaurantCuisines(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	var input CuisinesInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	restaurant, err := h.repos.Restaurants.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Restaurant not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch restaurant")
+		}
+		return
+	}
+
+	slugs := make([]string, 0, len(input.Cuisines))
+	seen := make(map[string]bool, len(input.Cuisines))
+	for _, value := range input.Cuisines {
+		slug := models.CuisineKey(value)
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+
+	found, err := h.repos.Restaurants.ListCuisines(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch cuisines")
+		return
+	}
+	bySlug := make(map[string]models.Cuisine, len(found))
+	for _, cuisine := range found {
+		bySlug[cuisine.Slug] = cuisine
+	}
+
+	// Keep the order given, so that the first is the main cuisine.
+	cuisines := make([]models.Cuisine, 0, len(slugs))
+	for _, slug := range slugs {
+		cuisine, ok := bySlug[slug]
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown cuisine %q", slug))
+			return
+		}
+		cuisines = append(cuisines, cuisine)
+	}
+
+	if err := h.repos.Restaurants.SetCuisines(r.Context(), restaurant, cuisines); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to set cuisines")
+		return
+	}
+	restaurant.Cuisines = cuisines
+
+	respondWithJSON(w, http.StatusOK, restaurant)
+}
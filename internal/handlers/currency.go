@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"cheapeats-api/internal/exchange"
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+)
+
+// requestCurrency returns the currency named by the currency query
+// parameter, or "" when prices should be returned as stored. A currency
+// without a rate is accepted: prices already in it are returned as they
+// are, and others are left in their own currency.
+func requestCurrency(r *http.Request) (string, error) {
+	currency := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("currency")))
+	if currency == "" {
+		return "", nil
+	}
+	if !money.ValidCurrency(currency) {
+		return "", errors.New("currency must be a three-letter ISO 4217 code")
+	}
+	return currency, nil
+}
+
+// requestMaxPrice parses the max_price query parameter as an amount of
+// currency, or of the default currency when none was requested.
+func requestMaxPrice(r *http.Request, currency string) (*money.Money, error) {
+	value := r.URL.Query().Get("max_price")
+	if value == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	maxPrice, err := money.Parse(value, currency)
+	if err != nil {
+		return nil, errors.New("Invalid max_price")
+	}
+	return &maxPrice, nil
+}
+
+// comparablePrice is a price in euros at the latest rates, or its own amount
+// without a rate for its currency, as prices are compared for deals.
+func comparablePrice(converter *exchange.Converter, price money.Money) float64 {
+	if euros, err := converter.Convert(price, exchange.Base); err == nil {
+		return euros.Float64()
+	}
+	return price.Float64()
+}
+
+// withinMaxPrice reports whether price is at most max once converted to max's
+// currency at the latest rates. A price in a currency without a rate only
+// matches a max in the same currency.
+func withinMaxPrice(converter *exchange.Converter, price, max money.Money) bool {
+	converted, err := converter.Convert(price, max.Currency)
+	return err == nil && converted.Amount <= max.Amount
+}
+
+// convertMenuItems converts prices to currency at the latest rates, and
+// price history entries at the rates of their day. Prices in a currency
+// without a rate are left as they are.
+func convertMenuItems(converter *exchange.Converter, items []models.MenuItem, currency string) {
+	if currency == "" {
+		return
+	}
+	for i := range items {
+		item := &items[i]
+		convertPrice(converter, &item.Price, &item.OriginalPrice, currency)
+		convertPriceHistory(converter, item.PriceHistory, currency)
+
+		for j := range item.Variants {
+			variant := &item.Variants[j]
+			convertPrice(converter, &variant.Price, &variant.OriginalPrice, currency)
+			convertPriceHistory(converter, variant.PriceHistory, currency)
+		}
+		for j := range item.Modifiers {
+			modifier := &item.Modifiers[j]
+			convertPrice(converter, &modifier.Price, &modifier.OriginalPrice, currency)
+			convertPriceHistory(converter, modifier.PriceHistory, currency)
+		}
+		if item.EffectivePrice != nil {
+			if converted, err := converter.Convert(*item.EffectivePrice, currency); err == nil {
+				item.EffectivePrice = &converted
+			}
+		}
+		if item.DealVariant != nil {
+			convertPrice(converter, &item.DealVariant.Price, &item.DealVariant.OriginalPrice, currency)
+		}
+		if item.Promo != nil {
+			convertPromo(converter, item.Promo, currency)
+		}
+	}
+}
+
+// convertPromos converts the fixed prices of promos to currency at the
+// latest rates. Prices in a currency without a rate are left as they are.
+func convertPromos(converter *exchange.Converter, promos []models.PromoPrice, currency string) {
+	for i := range promos {
+		convertPromo(converter, &promos[i], currency)
+	}
+}
+
+// convertPromo replaces rather than updates the promo's price, which may be
+// shared with the promo it was copied from.
+func convertPromo(converter *exchange.Converter, promo *models.PromoPrice, currency string) {
+	if currency == "" || promo.Price == nil {
+		return
+	}
+	price := *promo.Price
+	convertPrice(converter, &price, &promo.OriginalPrice, currency)
+	promo.Price = &price
+}
+
+// convertPrice converts price at the latest rate, keeping the stored price
+// in original when it changes.
+func convertPrice(converter *exchange.Converter, price *money.Money, original **money.Money, currency string) {
+	if converted, err := converter.Convert(*price, currency); err == nil && !converted.Equal(*price) {
+		stored := *price
+		*price, *original = converted, &stored
+	}
+}
+
+func convertPriceHistory(converter *exchange.Converter, history []models.PriceHistory, currency string) {
+	if currency == "" {
+		return
+	}
+	for i := range history {
+		entry := &history[i]
+		if converted, err := converter.ConvertAt(entry.Price, currency, entry.RecordedAt); err == nil && !converted.Equal(entry.Price) {
+			original := entry.Price
+			entry.Price, entry.OriginalPrice = converted, &original
+		}
+		// The previous price is converted at the same rate, so the two stay
+		// comparable.
+		if entry.PreviousPrice != nil {
+			if converted, err := converter.ConvertAt(*entry.PreviousPrice, currency, entry.RecordedAt); err == nil {
+				entry.PreviousPrice = &converted
+			}
+		}
+	}
+}
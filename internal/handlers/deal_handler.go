@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"cheapeats-api/internal/exchange"
+	"cheapeats-api/internal/repository"
+)
+
+const (
+	defaultDealLimit = 20
+	maxDealLimit     = 100
+)
+
+type DealHandler struct {
+	menus     repository.MenuRepository
+	converter *exchange.Converter
+}
+
+func NewDealHandler(menus repository.MenuRepository, converter *exchange.Converter) *DealHandler {
+	return &DealHandler{menus: menus, converter: converter}
+}
+
+// GetCheapest godoc
+// @Summary Cheapest menu items
+// @Description List available menu items across restaurants, cheapest first. Prices in different currencies are compared at the latest exchange rates; prices in a currency without a rate compare by their own amount. Promos aren't applied: items are ranked and filtered by their regular price.
+// @Tags deals
+// @Produce json
+// @Param city query string false "Filter by restaurant city"
+// @Param category query string false "Filter by menu category"
+// @Param max_price query number false "Maximum price, in the requested currency (default USD)"
+// @Param currency query string false "Convert prices to this currency (ISO 4217)"
+// @Param diet query string false "Only items suitable for these diets, comma-separated, e.g. vegan,gluten_free" Enums(vegetarian, vegan, pescatarian, gluten_free, dairy_free, halal, kosher)
+// @Param exclude_allergen query string false "Leave out items containing these allergens, comma-separated, e.g. peanut,milk"
+// @Param limit query int false "Number of items (default 20, at most 100)"
+// @Security BearerAuth
+// @Success 200 {array} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /deals/cheapest [get]
+func (h *DealHandler) GetCheapest(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	currency, err := requestCurrency(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := repository.DealFilter{
+		City:     q.Get("city"),
+		Category: q.Get("category"),
+		Limit:    defaultDealLimit,
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxDealLimit {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+	}
+
+	filter.MaxPrice, err = requestMaxPrice(r, currency)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.Diet, err = requestDietFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := h.menus.Cheapest(r.Context(), filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch deals")
+		return
+	}
+
+	convertMenuItems(h.converter, items, currency)
+
+	respondWithJSON(w, http.StatusOK, items)
+}
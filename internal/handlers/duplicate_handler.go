@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+	"cheapeats-api/internal/services"
+)
+
+const (
+	defaultDuplicateLimit = 50
+	maxDuplicateLimit     = 200
+)
+
+// MergeInput is the request body for merging one restaurant into another.
+type MergeInput struct {
+	DuplicateID uint `json:"duplicate_id" example:"42"`
+}
+
+// MergeCandidateInput is the optional request body for merging a queued
+// pair. KeepID picks which of the two restaurants to keep; by default the
+// older one is.
+type MergeCandidateInput struct {
+	KeepID *uint `json:"keep_id,omitempty" example:"7"`
+}
+
+// ListDuplicates godoc
+// @Summary List duplicate candidates
+// @Description List pairs of restaurants that may be the same place, with both restaurants, highest score first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending (default), merged or dismissed"
+// @Param limit query int false "Number of candidates (default 50, at most 200)"
+// @Success 200 {array} models.DuplicateCandidate
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/duplicates [get]
+func (h *AdminHandler) ListDuplicates(w http.ResponseWriter, r *http.Request) {
+	status, limit, ok := parseReviewQuery(w, r)
+	if !ok {
+		return
+	}
+
+	candidates, err := h.repos.Duplicates.List(r.Context(), status, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch duplicate candidates")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, candidates)
+}
+
+// parseReviewQuery reads the status and limit parameters of a review queue
+// listing, responding with an error when either is invalid.
+func parseReviewQuery(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	q := r.URL.Query()
+
+	status := q.Get("status")
+	if status == "" {
+		status = models.DuplicatePending
+	}
+	if !models.ValidDuplicateStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "status must be pending, merged or dismissed")
+		return "", 0, false
+	}
+
+	limit := defaultDuplicateLimit
+	if value := q.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxDuplicateLimit {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return "", 0, false
+		}
+	}
+	return status, limit, true
+}
+
+// MergeDuplicate godoc
+// @Summary Merge a duplicate candidate
+// @Description Merge a queued pair of restaurants: the menu, promos, price history, external IDs and cuisines of one are moved to the other, which keeps its own details and fills in those it lacks, and the first is deleted
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Candidate ID"
+// @Param merge body MergeCandidateInput false "Which restaurant to keep"
+// @Success 200 {object} services.RestaurantMergeResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/duplicates/{id}/merge [post]
+func (h *AdminHandler) MergeDuplicate(w http.ResponseWriter, r *http.Request) {
+	candidate, ok := h.pendingCandidate(w, r)
+	if !ok {
+		return
+	}
+
+	var input MergeCandidateInput
+	if r.ContentLength != 0 {
+		if err := decodeJSONBody(r, &input); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	keepID, duplicateID := candidate.RestaurantID, candidate.DuplicateID
+	if input.KeepID != nil {
+		switch *input.KeepID {
+		case candidate.RestaurantID:
+		case candidate.DuplicateID:
+			keepID, duplicateID = duplicateID, keepID
+		default:
+			respondWithError(w, http.StatusBadRequest, "keep_id must be one of the pair")
+			return
+		}
+	}
+
+	h.mergeRestaurants(w, r, keepID, duplicateID)
+}
+
+// DismissDuplicate godoc
+// @Summary Dismiss a duplicate candidate
+// @Description Mark a queued pair of restaurants as different places, so that it isn't queued again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Candidate ID"
+// @Success 200 {object} models.DuplicateCandidate
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/duplicates/{id}/dismiss [post]
+func (h *AdminHandler) DismissDuplicate(w http.ResponseWriter, r *http.Request) {
+	candidate, ok := h.pendingCandidate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repos.Duplicates.Dismiss(r.Context(), candidate); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to dismiss duplicate candidate")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, candidate)
+}
+
+// MergeRestaurant godoc
+// @Summary Merge a restaurant into another
+// @Description Merge the restaurant duplicate_id into this one, whether or not the pair was queued as a duplicate candidate. Its menu, promos, price history, external IDs and cuisines are moved to this restaurant, which keeps its own details and fills in those it lacks, and it is deleted.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID of the restaurant to keep"
+// @Param merge body MergeInput true "Restaurant to merge"
+// @Success 200 {object} services.RestaurantMergeResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/merge [post]
+func (h *AdminHandler) MergeRestaurant(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	var input MergeInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.DuplicateID == 0 {
+		respondWithError(w, http.StatusBadRequest, "duplicate_id is required")
+		return
+	}
+
+	h.mergeRestaurants(w, r, id, input.DuplicateID)
+}
+
+// pendingCandidate loads the candidate named by the id URL parameter,
+// responding with an error unless it exists and is pending.
+func (h *AdminHandler) pendingCandidate(w http.ResponseWriter, r *http.Request) (*models.DuplicateCandidate, bool) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid candidate ID")
+		return nil, false
+	}
+
+	candidate, err := h.repos.Duplicates.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Duplicate candidate not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch duplicate candidate")
+		}
+		return nil, false
+	}
+	if candidate.Status != models.DuplicatePending {
+		respondWithError(w, http.StatusConflict, "Duplicate candidate was already "+candidate.Status)
+		return nil, false
+	}
+	return candidate, true
+}
+
+func (h *AdminHandler) mergeRestaurants(w http.ResponseWriter, r *http.Request, keepID, duplicateID uint) {
+	result, err := services.MergeRestaurants(r.Context(), h.repos.Restaurants, keepID, duplicateID)
+	switch {
+	case errors.Is(err, services.ErrMergeSelf):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Restaurant not found")
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Failed to merge restaurants")
+	default:
+		respondWithJSON(w, http.StatusOK, result)
+	}
+}
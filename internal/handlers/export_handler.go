@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/services"
+
+	"gorm.io/gorm"
+)
+
+type ExportHandler struct {
+	exporter *services.Exporter
+}
+
+func NewExportHandler(exporter *services.Exporter) *ExportHandler {
+	return &ExportHandler{
+		exporter: exporter,
+	}
+}
+
+var exportContentTypes = map[string]string{
+	services.ExportFormatCSV:     "text/csv",
+	services.ExportFormatNDJSON:  "application/x-ndjson",
+	services.ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+type exportFunc func(ctx context.Context, db *gorm.DB, w io.Writer, format string, filter services.ExportFilter) error
+
+// ExportMenuItems godoc
+// @Summary Export menu items
+// @Description Stream menu items for a restaurant, a city or the whole database as CSV, NDJSON or Parquet. The date range applies to the item's last update.
+// @Tags export
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.apache.parquet
+// @Param format query string false "Output format: csv, ndjson or parquet (default: csv)"
+// @Param restaurant_id query int false "Only export this restaurant"
+// @Param city query string false "Only export restaurants in this city"
+// @Param from query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "End date, exclusive (YYYY-MM-DD or RFC3339)"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /export/menu-items [get]
+func (h *ExportHandler) ExportMenuItems(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, "menu-items", h.exporter.ExportMenuItems)
+}
+
+// ExportPriceHistory godoc
+// @Summary Export price history
+// @Description Stream price history records for a restaurant, a city or the whole database as CSV, NDJSON or Parquet, oldest first.
+// @Tags export
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.apache.parquet
+// @Param format query string false "Output format: csv, ndjson or parquet (default: csv)"
+// @Param restaurant_id query int false "Only export this restaurant"
+// @Param city query string false "Only export restaurants in this city"
+// @Param from query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "End date, exclusive (YYYY-MM-DD or RFC3339)"
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /export/price-history [get]
+func (h *ExportHandler) ExportPriceHistory(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, "price-history", h.exporter.ExportPriceHistory)
+}
+
+func (h *ExportHandler) export(w http.ResponseWriter, r *http.Request, name string, run exportFunc) {
+	q := r.URL.Query()
+
+	format := q.Get("format")
+	if format == "" {
+		format = services.ExportFormatCSV
+	}
+	if !services.ValidExportFormat(format) {
+		respondWithError(w, http.StatusBadRequest, "Invalid format; use csv, ndjson or parquet")
+		return
+	}
+
+	var filter services.ExportFilter
+
+	if restaurantID := q.Get("restaurant_id"); restaurantID != "" {
+		id, err := strconv.ParseUint(restaurantID, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid restaurant_id")
+			return
+		}
+		filter.RestaurantID = uint(id)
+	}
+
+	filter.City = q.Get("city")
+
+	if fromStr := q.Get("from"); fromStr != "" {
+		from, err := parseDateParam(fromStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid from date")
+			return
+		}
+		filter.From = &from
+	}
+
+	if toStr := q.Get("to"); toStr != "" {
+		to, err := parseDateParam(toStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid to date")
+			return
+		}
+		filter.To = &to
+	}
+
+	// Large exports can run well past the server's write timeout; they are
+	// bounded by the client staying connected instead.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	out := &exportWriter{ResponseWriter: w}
+	out.Header().Set("Content-Type", exportContentTypes[format])
+	out.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+
+	if err := run(r.Context(), database.GetDB().WithContext(r.Context()), out, format, filter); err != nil {
+		slog.ErrorContext(r.Context(), "export failed", "export", name, "error", err)
+
+		// Once the first bytes are out the status can't change any more and
+		// the client just sees a truncated file.
+		if !out.started {
+			out.Header().Del("Content-Disposition")
+			respondWithError(w, http.StatusInternalServerError, "Failed to export "+name)
+		}
+	}
+}
+
+// exportWriter records whether any part of the response has been written.
+type exportWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
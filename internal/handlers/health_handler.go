@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"cheapeats-api/internal/health"
+)
+
+type HealthHandler struct {
+	checker  *health.Checker
+	draining atomic.Bool
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Drain makes readiness fail from now on so load balancers stop routing new
+// requests while the server shuts down.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Reports that the process is up and serving HTTP. It checks no dependencies; also served at /healthz.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /health [get]
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Checks the database, schema, ingest queue, last successful ingest and Places API reachability. Returns 503 when a critical component fails or the server is shutting down; non-critical problems report "degraded" with 200. Also served at /readyz.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /ready [get]
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Run(r.Context())
+
+	if h.draining.Load() {
+		report.Status = health.StatusFail
+		report.Components["server"] = health.ComponentStatus{
+			Status:   health.StatusFail,
+			Critical: true,
+			Message:  "shutting down",
+		}
+	}
+
+	code := http.StatusOK
+	if report.Status == health.StatusFail {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, report)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cheapeats-api/internal/services"
+)
+
+type IngestHandler struct {
+	queue *services.IngestQueue
+}
+
+func NewIngestHandler(queue *services.IngestQueue) *IngestHandler {
+	return &IngestHandler{
+		queue: queue,
+	}
+}
+
+// IngestInput is the request body for scheduling an ingest.
+type IngestInput struct {
+	Lat    *float64 `json:"lat"`
+	Lng    *float64 `json:"lng"`
+	Radius int      `json:"radius"`
+}
+
+// CreateIngestJob godoc
+// @Summary Schedule an ingest
+// @Description Queue a background fetch of the restaurants around a location from the Places API. Returns immediately.
+// @Tags ingest
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param job body IngestInput true "Location to ingest; radius in meters (default: 1000)"
+// @Success 202 {object} services.IngestJob
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /ingest [post]
+func (h *IngestHandler) CreateIngestJob(w http.ResponseWriter, r *http.Request) {
+	var input IngestInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.Lat == nil || input.Lng == nil {
+		respondWithError(w, http.StatusBadRequest, "Latitude and longitude are required")
+		return
+	}
+	if *input.Lat < -90 || *input.Lat > 90 || *input.Lng < -180 || *input.Lng > 180 {
+		respondWithError(w, http.StatusBadRequest, "Invalid coordinates")
+		return
+	}
+
+	radius := input.Radius
+	if radius == 0 {
+		radius = 1000
+	}
+	if radius < 0 || radius > 50000 {
+		respondWithError(w, http.StatusBadRequest, "Radius must be between 1 and 50000 meters")
+		return
+	}
+
+	job, err := h.queue.Enqueue(r.Context(), *input.Lat, *input.Lng, radius)
+	if err != nil {
+		if errors.Is(err, services.ErrQueueFull) {
+			w.Header().Set("Retry-After", "30")
+		}
+		respondWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, job)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+	"cheapeats-api/internal/services"
+)
+
+// ListMenuMatches godoc
+// @Summary List menu match candidates
+// @Description List new menu items that may be an existing item of the same restaurant under another name, with both items, most confident first. Imports and ingests queue them when the names are too far apart to rename the existing item automatically.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending (default), merged or dismissed"
+// @Param limit query int false "Number of candidates (default 50, at most 200)"
+// @Success 200 {array} models.MenuMatchCandidate
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/menu-matches [get]
+func (h *AdminHandler) ListMenuMatches(w http.ResponseWriter, r *http.Request) {
+	status, limit, ok := parseReviewQuery(w, r)
+	if !ok {
+		return
+	}
+
+	candidates, err := h.repos.MenuMatches.List(r.Context(), status, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch menu match candidates")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, candidates)
+}
+
+// MergeMenuMatch godoc
+// @Summary Merge a menu match candidate
+// @Description Merge a queued match: the existing item takes the new item's name, as when renaming it, and the new item is merged into it, so that one item carries the whole price history
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Candidate ID"
+// @Success 200 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/menu-matches/{id}/merge [post]
+func (h *AdminHandler) MergeMenuMatch(w http.ResponseWriter, r *http.Request) {
+	candidate, ok := h.pendingMenuMatch(w, r)
+	if !ok {
+		return
+	}
+
+	item, err := services.MergeMenuMatch(r.Context(), h.repos.Menus, candidate)
+	respondWithMergedItem(w, item, err)
+}
+
+// DismissMenuMatch godoc
+// @Summary Dismiss a menu match candidate
+// @Description Mark a queued match as two different menu items, so that it isn't queued again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Candidate ID"
+// @Success 200 {object} models.MenuMatchCandidate
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/menu-matches/{id}/dismiss [post]
+func (h *AdminHandler) DismissMenuMatch(w http.ResponseWriter, r *http.Request) {
+	candidate, ok := h.pendingMenuMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repos.MenuMatches.Dismiss(r.Context(), candidate); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to dismiss menu match candidate")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, candidate)
+}
+
+// pendingMenuMatch loads the candidate named by the id URL parameter,
+// responding with an error unless it exists and is pending.
+func (h *AdminHandler) pendingMenuMatch(w http.ResponseWriter, r *http.Request) (*models.MenuMatchCandidate, bool) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid candidate ID")
+		return nil, false
+	}
+
+	candidate, err := h.repos.MenuMatches.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Menu match candidate not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch menu match candidate")
+		}
+		return nil, false
+	}
+	if candidate.Status != models.DuplicatePending {
+		respondWithError(w, http.StatusConflict, "Menu match candidate was already "+candidate.Status)
+		return nil, false
+	}
+	return candidate, true
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+	"cheapeats-api/internal/services"
+)
+
+// RenameMenuItemInput is the request body for renaming a menu item.
+type RenameMenuItemInput struct {
+	Name string `json:"name" example:"Classic Cheeseburger"`
+}
+
+// MergeMenuItemInput is the request body for merging one menu item into
+// another.
+type MergeMenuItemInput struct {
+	SourceID uint `json:"source_id" example:"42"`
+}
+
+// RenameMenuItem godoc
+// @Summary Rename a menu item
+// @Description Rename a menu item, keeping its price history. If the restaurant already has an item by the new name, typically created when an import or ingest didn't recognise the rename, that item is merged into this one: its price history, variants and modifiers move over, and its price is kept if it was recorded last.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param rename body RenameMenuItemInput true "New name"
+// @Success 200 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/rename [post]
+func (h *AdminHandler) RenameMenuItem(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	var input RenameMenuItemInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	item, err := services.RenameMenuItem(r.Context(), h.repos.Menus, id, name)
+	respondWithMergedItem(w, item, err)
+}
+
+// MergeMenuItem godoc
+// @Summary Merge a menu item into another
+// @Description Merge the menu item source_id into this one, of the same restaurant, and delete it. This item keeps its name and gains the other's price history, promos, variants and modifiers, and takes the other's price if it was recorded last.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "ID of the menu item to keep"
+// @Param merge body MergeMenuItemInput true "Menu item to merge"
+// @Success 200 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/merge [post]
+func (h *AdminHandler) MergeMenuItem(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	var input MergeMenuItemInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.SourceID == 0 {
+		respondWithError(w, http.StatusBadRequest, "source_id is required")
+		return
+	}
+
+	item, err := services.MergeMenuItems(r.Context(), h.repos.Menus, id, input.SourceID)
+	respondWithMergedItem(w, item, err)
+}
+
+func respondWithMergedItem(w http.ResponseWriter, item *models.MenuItem, err error) {
+	switch {
+	case errors.Is(err, services.ErrMergeSelf), errors.Is(err, services.ErrMergeOtherRestaurant):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Menu item not found")
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Failed to update menu item")
+	default:
+		respondWithJSON(w, http.StatusOK, item)
+	}
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"cheapeats-api/internal/metrics"
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
+)
+
+// MenuItemOptionInput is the request body for creating and updating variants
+// and modifiers. Fields left out of a PATCH request keep their current
+// value. A new option is priced in its item's currency unless one is given.
+type MenuItemOptionInput struct {
+	Name        *string      `json:"name" example:"Large"`
+	Price       *json.Number `json:"price" swaggertype:"string" example:"4.50"`
+	Currency    *string      `json:"currency"`
+	IsAvailable *bool        `json:"is_available"`
+}
+
+func (in *MenuItemOptionInput) validate(full bool) error {
+	item := MenuItemInput{Name: in.Name, Price: in.Price, Currency: in.Currency}
+	return item.validate(full)
+}
+
+// price returns the option's price after applying the input, keeping the
+// current currency when none is given.
+func (in *MenuItemOptionInput) price(current money.Money) (money.Money, error) {
+	amount := current.String()
+	if in.Price != nil {
+		amount = in.Price.String()
+	}
+	return money.Parse(amount, valueOr(in.Currency, current.Currency))
+}
+
+// apply copies everything except the price onto the option.
+func (in *MenuItemOptionInput) apply(option *models.MenuItemOption, full bool) {
+	setString(&option.Name, in.Name, full)
+
+	if in.IsAvailable != nil {
+		option.IsAvailable = *in.IsAvailable
+	} else if full {
+		option.IsAvailable = true
+	}
+}
+
+// optionKind lets variants and modifiers share their endpoints' code.
+type optionKind struct {
+	name      string
+	param     string
+	newOption func() models.PricedOption
+}
+
+var (
+	variantKind = optionKind{
+		name:      "variant",
+		param:     "variantId",
+		newOption: func() models.PricedOption { return &models.MenuItemVariant{} },
+	}
+	modifierKind = optionKind{
+		name:      "modifier",
+		param:     "modifierId",
+		newOption: func() models.PricedOption { return &models.Modifier{} },
+	}
+)
+
+// CreateVariant godoc
+// @Summary Add a variant to a menu item
+// @Description Add a size or other variant with its own price, e.g. "Large". Its price is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param variant body MenuItemOptionInput true "Variant"
+// @Success 201 {object} models.MenuItemVariant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/variants [post]
+func (h *AdminHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	h.createOption(w, r, variantKind)
+}
+
+// UpdateVariant godoc
+// @Summary Replace a menu item variant
+// @Description Replace all editable fields of a variant. A price change is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param variantId path int true "Variant ID"
+// @Param variant body MenuItemOptionInput true "Variant"
+// @Success 200 {object} models.MenuItemVariant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/variants/{variantId} [put]
+func (h *AdminHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	h.updateOption(w, r, variantKind, true)
+}
+
+// PatchVariant godoc
+// @Summary Update a menu item variant
+// @Description Update only the fields present in the request body. A price change is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param variantId path int true "Variant ID"
+// @Param variant body MenuItemOptionInput true "Fields to update"
+// @Success 200 {object} models.MenuItemVariant
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/variants/{variantId} [patch]
+func (h *AdminHandler) PatchVariant(w http.ResponseWriter, r *http.Request) {
+	h.updateOption(w, r, variantKind, false)
+}
+
+// DeleteVariant godoc
+// @Summary Delete a menu item variant
+// @Description Soft-delete a variant. Its price history is kept.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param variantId path int true "Variant ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/variants/{variantId} [delete]
+func (h *AdminHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	h.deleteOption(w, r, variantKind)
+}
+
+// CreateModifier godoc
+// @Summary Add a modifier to a menu item
+// @Description Add an optional add-on with its own price, e.g. "Extra Cheese". Its price is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param modifier body MenuItemOptionInput true "Modifier"
+// @Success 201 {object} models.Modifier
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/modifiers [post]
+func (h *AdminHandler) CreateModifier(w http.ResponseWriter, r *http.Request) {
+	h.createOption(w, r, modifierKind)
+}
+
+// UpdateModifier godoc
+// @Summary Replace a menu item modifier
+// @Description Replace all editable fields of a modifier. A price change is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param modifierId path int true "Modifier ID"
+// @Param modifier body MenuItemOptionInput true "Modifier"
+// @Success 200 {object} models.Modifier
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/modifiers/{modifierId} [put]
+func (h *AdminHandler) UpdateModifier(w http.ResponseWriter, r *http.Request) {
+	h.updateOption(w, r, modifierKind, true)
+}
+
+// PatchModifier godoc
+// @Summary Update a menu item modifier
+// @Description Update only the fields present in the request body. A price change is recorded in the price history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param modifierId path int true "Modifier ID"
+// @Param modifier body MenuItemOptionInput true "Fields to update"
+// @Success 200 {object} models.Modifier
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/modifiers/{modifierId} [patch]
+func (h *AdminHandler) PatchModifier(w http.ResponseWriter, r *http.Request) {
+	h.updateOption(w, r, modifierKind, false)
+}
+
+// DeleteModifier godoc
+// @Summary Delete a menu item modifier
+// @Description Soft-delete a modifier. Its price history is kept.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param itemId path int true "Menu Item ID"
+// @Param modifierId path int true "Modifier ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu-items/{itemId}/modifiers/{modifierId} [delete]
+func (h *AdminHandler) DeleteModifier(w http.ResponseWriter, r *http.Request) {
+	h.deleteOption(w, r, modifierKind)
+}
+
+func (h *AdminHandler) createOption(w http.ResponseWriter, r *http.Request, kind optionKind) {
+	itemID, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	var input MenuItemOptionInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := input.validate(true); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, err := h.repos.Menus.Get(r.Context(), itemID)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Menu item not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch menu item")
+		return
+	}
+
+	price, err := input.price(money.Money{Currency: item.Price.Currency})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	option := kind.newOption()
+	fields := option.Option()
+	fields.MenuItemID = item.ID
+	fields.Price = price
+	input.apply(fields, true)
+
+	if !h.checkOptionName(w, r, kind, option) {
+		return
+	}
+
+	if err := h.repos.Menus.CreateOption(r.Context(), option, manualProvenance); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create "+kind.name)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, option)
+}
+
+func (h *AdminHandler) updateOption(w http.ResponseWriter, r *http.Request, kind optionKind, full bool) {
+	option, ok := h.findOption(w, r, kind)
+	if !ok {
+		return
+	}
+
+	var input MenuItemOptionInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := input.validate(full); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fields := option.Option()
+	price, err := input.price(fields.Price)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input.apply(fields, full)
+	if !h.checkOptionName(w, r, kind, option) {
+		return
+	}
+
+	changed, err := h.repos.Menus.UpdateOption(r.Context(), option, price, manualProvenance)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update "+kind.name)
+		return
+	}
+	if changed {
+		metrics.PriceChanges.Inc()
+	}
+
+	respondWithJSON(w, http.StatusOK, option)
+}
+
+func (h *AdminHandler) deleteOption(w http.ResponseWriter, r *http.Request, kind optionKind) {
+	option, ok := h.findOption(w, r, kind)
+	if !ok {
+		return
+	}
+
+	if err := h.repos.Menus.DeleteOption(r.Context(), option); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete "+kind.name)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findOption loads the option named by the request path, responding with an
+// error when it doesn't exist or belongs to another menu item.
+func (h *AdminHandler) findOption(w http.ResponseWriter, r *http.Request, kind optionKind) (models.PricedOption, bool) {
+	itemID, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return nil, false
+	}
+	id, err := parseIDParam(r, kind.param)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid "+kind.name+" ID")
+		return nil, false
+	}
+
+	option := kind.newOption()
+	err = h.repos.Menus.GetOption(r.Context(), option, itemID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, strings.ToUpper(kind.name[:1])+kind.name[1:]+" not found")
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch "+kind.name)
+		return nil, false
+	}
+	return option, true
+}
+
+// checkOptionName responds with a conflict when another live option of the
+// same kind and item has the option's name, ignoring case.
+func (h *AdminHandler) checkOptionName(w http.ResponseWriter, r *http.Request, kind optionKind, option models.PricedOption) bool {
+	taken, err := h.repos.Menus.OptionNameTaken(r.Context(), option)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check "+kind.name+" name")
+		return false
+	}
+	if taken {
+		respondWithError(w, http.StatusConflict, "The menu item already has a "+kind.name+" with this name")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+)
+
+// requestDietFilter parses the diet and exclude_allergen query parameters,
+// each given either repeated or comma-separated.
+func requestDietFilter(r *http.Request) (repository.DietFilter, error) {
+	var filter repository.DietFilter
+	for _, diet := range queryList(r, "diet") {
+		parsed, err := models.ParseDiet(diet)
+		if err != nil {
+			return filter, err
+		}
+		filter.Diets = append(filter.Diets, parsed)
+	}
+	for _, allergen := range queryList(r, "exclude_allergen") {
+		parsed, err := models.ParseAllergen(allergen)
+		if err != nil {
+			return filter, err
+		}
+		filter.ExcludeAllergens = append(filter.ExcludeAllergens, parsed)
+	}
+	return filter, nil
+}
+
+func queryList(r *http.Request, name string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[name] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
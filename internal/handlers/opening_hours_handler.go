@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+)
+
+// OpeningHoursInput is the request body for setting a restaurant's opening
+// hours. Times are in the restaurant's time zone.
+type OpeningHoursInput struct {
+	Periods     []models.OpeningPeriod `json:"periods"`
+	SpecialDays []models.SpecialDay    `json:"special_days"`
+}
+
+// localTimeLayouts are the formats of time parameters without a UTC offset,
+// which are read in each restaurant's own time zone.
+var localTimeLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+// parseTimeParam parses a time query parameter. With a UTC offset it is an
+// instant; without one it is a restaurant-local date and time, and local is
+// true.
+func parseTimeParam(name, value string) (t time.Time, local bool, err error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%s must be a date and time such as 2026-10-16T19:00, optionally with a UTC offset", name)
+}
+
+// requestOpenAt parses the open_now and open_at query parameters.
+func requestOpenAt(r *http.Request) (*repository.OpenAt, error) {
+	query := r.URL.Query()
+
+	if value := query.Get("open_at"); value != "" {
+		if query.Get("open_now") != "" {
+			return nil, errors.New("open_now and open_at can't be combined")
+		}
+		t, local, err := parseTimeParam("open_at", value)
+		if err != nil {
+			return nil, err
+		}
+		return &repository.OpenAt{Time: t, Local: local}, nil
+	}
+
+	if value := query.Get("open_now"); value != "" {
+		openNow, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("open_now must be true or false")
+		}
+		if openNow {
+			return &repository.OpenAt{Time: time.Now()}, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetOpeningHours godoc
+// @Summary Set a restaurant's opening hours
+// @Description Replace a restaurant's weekly opening periods and special days. Days count from 0 (Sunday) to 6 (Saturday) and times are "HH:MM" in the restaurant's time zone. A period without a close time never closes; a special day without hours is closed all day.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Param hours body OpeningHoursInput true "Opening hours"
+// @Success 200 {object} models.OpeningHours
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/opening-hours [put]
+func (h *AdminHandler) SetOpeningHours(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	var input OpeningHoursInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hours := models.OpeningHours{
+		RestaurantID: id,
+		Periods:      input.Periods,
+		SpecialDays:  input.SpecialDays,
+	}
+	if err := hours.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := h.repos.Restaurants.Get(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Restaurant not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch restaurant")
+		}
+		return
+	}
+
+	if err := h.repos.Restaurants.SaveOpeningHours(r.Context(), &hours); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save opening hours")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, hours)
+}
+
+// DeleteOpeningHours godoc
+// @Summary Clear a restaurant's opening hours
+// @Description Remove a restaurant's opening hours, leaving them unknown. Restaurants without opening hours don't match open_now or open_at.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/opening-hours [delete]
+func (h *AdminHandler) DeleteOpeningHours(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	err = h.repos.Restaurants.DeleteOpeningHours(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Opening hours not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete opening hours")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
+)
+
+// PromoPriceInput is the request body for creating and updating promos.
+// Fields left out of a PATCH request keep their current value. A fixed
+// price is in the menu item's currency.
+type PromoPriceInput struct {
+	MenuItemID      *uint        `json:"menu_item_id"`
+	Name            *string      `json:"name" example:"Happy hour"`
+	Price           *json.Number `json:"price" swaggertype:"string" example:"5.00"`
+	DiscountPercent *int         `json:"discount_percent" example:"25"`
+	Days            []int        `json:"days"`
+	StartTime       *string      `json:"start_time" example:"16:00"`
+	EndTime         *string      `json:"end_time" example:"18:00"`
+	StartsOn        *string      `json:"starts_on" example:"2026-10-01"`
+	EndsOn          *string      `json:"ends_on" example:"2026-12-31"`
+	IsActive        *bool        `json:"is_active"`
+}
+
+// apply copies the input onto the promo. With full set, fields missing from
+// the input are reset, giving PUT semantics. Price is left to the caller,
+// which knows the item's currency.
+func (in *PromoPriceInput) apply(promo *models.PromoPrice, full bool) error {
+	if in.MenuItemID != nil || full {
+		promo.MenuItemID = in.MenuItemID
+	}
+	setString(&promo.Name, in.Name, full)
+	setString(&promo.StartTime, in.StartTime, full)
+	setString(&promo.EndTime, in.EndTime, full)
+
+	if in.DiscountPercent != nil || full {
+		promo.DiscountPercent = in.DiscountPercent
+	}
+	if in.Days != nil || full {
+		promo.Days = models.Weekdays(in.Days)
+	}
+	if in.IsActive != nil {
+		promo.IsActive = *in.IsActive
+	} else if full {
+		promo.IsActive = true
+	}
+
+	for _, field := range []struct {
+		name  string
+		value *string
+		dst   **time.Time
+	}{
+		{"starts_on", in.StartsOn, &promo.StartsOn},
+		{"ends_on", in.EndsOn, &promo.EndsOn},
+	} {
+		if field.value == nil {
+			if full {
+				*field.dst = nil
+			}
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, *field.value)
+		if err != nil {
+			return fmt.Errorf("%s must be a date such as 2026-10-01", field.name)
+		}
+		*field.dst = &date
+	}
+	return nil
+}
+
+// CreatePromo godoc
+// @Summary Add a promo to a restaurant
+// @Description Add a time-limited deal such as a happy hour: a fixed price for one menu item, or a discount_percent off one item or, without menu_item_id, the whole menu. It runs on days (0 is Sunday; every day when empty), between start_time and end_time in the restaurant's time zone (all day when empty), from starts_on to ends_on when set.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Restaurant ID"
+// @Param promo body PromoPriceInput true "Promo"
+// @Success 201 {object} models.PromoPrice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/promos [post]
+func (h *AdminHandler) CreatePromo(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+
+	var input PromoPriceInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := h.repos.Restaurants.Get(r.Context(), restaurantID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Restaurant not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch restaurant")
+		}
+		return
+	}
+
+	promo := models.PromoPrice{RestaurantID: restaurantID}
+	if !h.applyPromoInput(w, r, &input, &promo, true) {
+		return
+	}
+
+	if err := h.repos.Promos.Create(r.Context(), &promo); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create promo")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, promo)
+}
+
+// UpdatePromo godoc
+// @Summary Replace a promo
+// @Description Replace all editable fields of a promo
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param promoId path int true "Promo ID"
+// @Param promo body PromoPriceInput true "Promo"
+// @Success 200 {object} models.PromoPrice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /promos/{promoId} [put]
+func (h *AdminHandler) UpdatePromo(w http.ResponseWriter, r *http.Request) {
+	h.updatePromo(w, r, true)
+}
+
+// PatchPromo godoc
+// @Summary Update a promo
+// @Description Update only the fields present in the request body
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param promoId path int true "Promo ID"
+// @Param promo body PromoPriceInput true "Fields to update"
+// @Success 200 {object} models.PromoPrice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /promos/{promoId} [patch]
+func (h *AdminHandler) PatchPromo(w http.ResponseWriter, r *http.Request) {
+	h.updatePromo(w, r, false)
+}
+
+func (h *AdminHandler) updatePromo(w http.ResponseWriter, r *http.Request, full bool) {
+	promo, ok := h.findPromo(w, r)
+	if !ok {
+		return
+	}
+
+	var input PromoPriceInput
+	if err := decodeJSONBody(r, &input); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !h.applyPromoInput(w, r, &input, promo, full) {
+		return
+	}
+
+	if err := h.repos.Promos.Update(r.Context(), promo); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update promo")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, promo)
+}
+
+// DeletePromo godoc
+// @Summary Delete a promo
+// @Description Soft-delete a promo
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param promoId path int true "Promo ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /promos/{promoId} [delete]
+func (h *AdminHandler) DeletePromo(w http.ResponseWriter, r *http.Request) {
+	promo, ok := h.findPromo(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repos.Promos.Delete(r.Context(), promo); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete promo")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyPromoInput applies and validates the input, pricing a fixed price in
+// the currency of the promo's menu item, which must belong to the promo's
+// restaurant. It responds with an error and returns false when it fails.
+func (h *AdminHandler) applyPromoInput(w http.ResponseWriter, r *http.Request, input *PromoPriceInput, promo *models.PromoPrice, full bool) bool {
+	if err := input.apply(promo, full); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	item := &models.MenuItem{}
+	if promo.MenuItemID != nil {
+		var err error
+		item, err = h.repos.Menus.Get(r.Context(), *promo.MenuItemID)
+		if err != nil || item.RestaurantID != promo.RestaurantID {
+			respondWithError(w, http.StatusBadRequest, "menu_item_id must be a menu item of the restaurant")
+			return false
+		}
+	}
+
+	switch {
+	case input.Price != nil:
+		if promo.MenuItemID == nil {
+			respondWithError(w, http.StatusBadRequest, "a fixed price needs a menu_item_id; use discount_percent for the whole menu")
+			return false
+		}
+		price, err := money.Parse(input.Price.String(), item.Price.Currency)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "price must be a decimal number")
+			return false
+		}
+		promo.Price = &price
+		if input.DiscountPercent == nil {
+			promo.DiscountPercent = nil
+		}
+	case input.DiscountPercent != nil || full:
+		promo.Price = nil
+	}
+
+	if err := promo.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// findPromo loads the promo named by the request path, responding with an
+// error when it doesn't exist.
+func (h *AdminHandler) findPromo(w http.ResponseWriter, r *http.Request) (*models.PromoPrice, bool) {
+	id, err := parseIDParam(r, "promoId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid promo ID")
+		return nil, false
+	}
+
+	promo, err := h.repos.Promos.Get(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Promo not found")
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch promo")
+		return nil, false
+	}
+	return promo, true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/money"
+)
+
+type ReportHandler struct{}
+
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{}
+}
+
+// AreaPriceRow is one bucket of the area price report: the median recorded
+// price for a category in a ZIP code and city over a single period, in one
+// of the currencies prices were recorded in.
+type AreaPriceRow struct {
+	Period      time.Time `json:"period"`
+	ZipCode     string    `json:"zip_code"`
+	City        string    `json:"city"`
+	State       string    `json:"state"`
+	Category    string    `json:"category"`
+	MedianPrice float64   `json:"median_price"`
+	Currency    string    `json:"currency" example:"USD"`
+	SampleSize  int64     `json:"sample_size"`
+	ItemCount   int64     `json:"item_count"`
+}
+
+var reportIntervals = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+	"year":  true,
+}
+
+// GetAreaPrices godoc
+// @Summary Area price report
+// @Description Median menu item price by category per ZIP code and city, bucketed over time. Prices aren't converted: an area with prices recorded in more than one currency gets a row per currency. Use format=csv for a CSV download.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Produce text/csv
+// @Param zip query string false "Filter by ZIP code"
+// @Param city query string false "Filter by city"
+// @Param category query string false "Filter by menu category"
+// @Param from query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "End date, exclusive (YYYY-MM-DD or RFC3339)"
+// @Param interval query string false "Bucket size: day, week, month or year (default: month)"
+// @Param format query string false "Response format: json or csv (default: json)"
+// @Security BearerAuth
+// @Success 200 {array} AreaPriceRow
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/area-prices [get]
+func (h *ReportHandler) GetAreaPrices(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "month"
+	}
+	if !reportIntervals[interval] {
+		respondWithError(w, http.StatusBadRequest, "Invalid interval")
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		respondWithError(w, http.StatusBadRequest, "Invalid format")
+		return
+	}
+
+	db := database.GetDB().WithContext(r.Context())
+
+	query := db.Table("price_histories AS ph").
+		Select(`date_trunc(?, ph.recorded_at) AS period,
+			r.zip_code, r.city, r.state, mi.category,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY ph.price) AS median_price,
+			ph.currency,
+			count(*) AS sample_size,
+			count(DISTINCT mi.id) AS item_count`, interval).
+		Joins("JOIN menu_items mi ON mi.id = ph.menu_item_id AND mi.deleted_at IS NULL").
+		Joins("JOIN restaurants r ON r.id = mi.restaurant_id AND r.deleted_at IS NULL").
+		// Variant and modifier prices would skew an item's median.
+		Where("ph.variant_id IS NULL AND ph.modifier_id IS NULL")
+
+	if zip := q.Get("zip"); zip != "" {
+		query = query.Where("r.zip_code = ?", zip)
+	}
+
+	if city := q.Get("city"); city != "" {
+		query = query.Where("r.city = ?", city)
+	}
+
+	if category := q.Get("category"); category != "" {
+		query = query.Where("mi.category = ?", category)
+	}
+
+	if fromStr := q.Get("from"); fromStr != "" {
+		from, err := parseDateParam(fromStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid from date")
+			return
+		}
+		query = query.Where("ph.recorded_at >= ?", from)
+	}
+
+	if toStr := q.Get("to"); toStr != "" {
+		to, err := parseDateParam(toStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid to date")
+			return
+		}
+		query = query.Where("ph.recorded_at < ?", to)
+	}
+
+	var rows []AreaPriceRow
+	if err := query.
+		Group("1, r.zip_code, r.city, r.state, mi.category, ph.currency").
+		Order("period, r.zip_code, r.city, mi.category, ph.currency").
+		Scan(&rows).Error; err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to build area price report")
+		return
+	}
+
+	if format == "csv" {
+		respondWithAreaPricesCSV(w, rows)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, rows)
+}
+
+func respondWithAreaPricesCSV(w http.ResponseWriter, rows []AreaPriceRow) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="area-prices.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"period", "zip_code", "city", "state", "category", "median_price", "currency", "sample_size", "item_count"})
+	for _, row := range rows {
+		cw.Write([]string{
+			row.Period.Format("2006-01-02"),
+			row.ZipCode,
+			row.City,
+			row.State,
+			row.Category,
+			strconv.FormatFloat(row.MedianPrice, 'f', money.Exponent(row.Currency), 64),
+			row.Currency,
+			strconv.FormatInt(row.SampleSize, 10),
+			strconv.FormatInt(row.ItemCount, 10),
+		})
+	}
+	cw.Flush()
+}
+
+// parseDateParam accepts either a plain date or a full RFC3339 timestamp.
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
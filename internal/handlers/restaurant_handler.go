@@ -2,23 +2,30 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
-	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/exchange"
 	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
 	"cheapeats-api/internal/services"
-
-	"github.com/go-chi/chi/v5"
 )
 
 type RestaurantHandler struct {
 	priceFetcher *services.PriceFetcher
+	repos        repository.Repositories
+	converter    *exchange.Converter
 }
 
-func NewRestaurantHandler(priceFetcher *services.PriceFetcher) *RestaurantHandler {
+func NewRestaurantHandler(priceFetcher *services.PriceFetcher, repos repository.Repositories, converter *exchange.Converter) *RestaurantHandler {
 	return &RestaurantHandler{
 		priceFetcher: priceFetcher,
+		repos:        repos,
+		converter:    converter,
 	}
 }
 
@@ -29,31 +36,39 @@ func NewRestaurantHandler(priceFetcher *services.PriceFetcher) *RestaurantHandle
 // @Accept json
 // @Produce json
 // @Param city query string false "Filter by city"
-// @Param cuisine query string false "Filter by cuisine type"
+// @Param cuisine query string false "Only restaurants with any of these cuisines, comma-separated slugs such as thai,vietnamese (see /cuisines)"
 // @Param price_range query string false "Filter by price range (e.g., $, $$, $$$, $$$$)"
+// @Param open_now query bool false "Only restaurants open now"
+// @Param open_at query string false "Only restaurants open at this time, e.g. 2026-10-16T19:00 in each restaurant's time zone, or with a UTC offset for an exact instant"
+// @Param diet query string false "Only restaurants with an available item suitable for these diets, comma-separated, e.g. vegan,gluten_free"
+// @Param exclude_allergen query string false "Only restaurants with an available item not containing these allergens, comma-separated, e.g. peanut"
+// @Security BearerAuth
 // @Success 200 {array} models.Restaurant
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /restaurants [get]
 func (h *RestaurantHandler) GetAllRestaurants(w http.ResponseWriter, r *http.Request) {
-	db := database.GetDB()
-	
-	var restaurants []models.Restaurant
-	
-	query := db.Model(&models.Restaurant{})
-	
-	if city := r.URL.Query().Get("city"); city != "" {
-		query = query.Where("city = ?", city)
+	filter := repository.RestaurantFilter{
+		City:       r.URL.Query().Get("city"),
+		Cuisines:   queryList(r, "cuisine"),
+		PriceRange: r.URL.Query().Get("price_range"),
 	}
 	
-	if cuisine := r.URL.Query().Get("cuisine"); cuisine != "" {
-		query = query.Where("cuisine_type = ?", cuisine)
+	var err error
+	filter.OpenAt, err = requestOpenAt(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 	
-	if priceRange := r.URL.Query().Get("price_range"); priceRange != "" {
-		query = query.Where("price_range = ?", priceRange)
+	filter.Diet, err = requestDietFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 	
-	if err := query.Find(&restaurants).Error; err != nil {
+	restaurants, err := h.repos.Restaurants.List(r.Context(), filter)
+	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch restaurants")
 		return
 	}
@@ -68,19 +83,37 @@ func (h *RestaurantHandler) GetAllRestaurants(w http.ResponseWriter, r *http.Req
 // @Accept json
 // @Produce json
 // @Param id path int true "Restaurant ID"
+// @Param currency query string false "Convert menu prices to this currency (ISO 4217)"
+// @Security BearerAuth
 // @Success 200 {object} models.Restaurant
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
 // @Router /restaurants/{id} [get]
 func (h *RestaurantHandler) GetRestaurant(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
 	
-	db := database.GetDB()
-	var restaurant models.Restaurant
+	currency, err := requestCurrency(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	
-	if err := db.Preload("MenuItems").First(&restaurant, id).Error; err != nil {
+	restaurant, err := h.repos.Restaurants.Get(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
 		respondWithError(w, http.StatusNotFound, "Restaurant not found")
 		return
 	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch restaurant")
+		return
+	}
+	
+	convertMenuItems(h.converter, restaurant.MenuItems, currency)
 	
 	respondWithJSON(w, http.StatusOK, restaurant)
 }
@@ -94,8 +127,16 @@ func (h *RestaurantHandler) GetRestaurant(w http.ResponseWriter, r *http.Request
 // @Param lat query number true "Latitude"
 // @Param lng query number true "Longitude"
 // @Param radius query int false "Search radius in meters (default: 1000)"
+// @Param cuisine query string false "Only restaurants with any of these cuisines, comma-separated slugs such as thai,vietnamese (see /cuisines)"
+// @Param open_now query bool false "Only restaurants open now"
+// @Param open_at query string false "Only restaurants open at this time, e.g. 2026-10-16T19:00 in each restaurant's time zone, or with a UTC offset for an exact instant"
+// @Param diet query string false "Only restaurants with an available item suitable for these diets, comma-separated, e.g. vegan,gluten_free"
+// @Param exclude_allergen query string false "Only restaurants with an available item not containing these allergens, comma-separated, e.g. peanut"
+// @Security BearerAuth
 // @Success 200 {array} models.Restaurant
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /restaurants/search [get]
 func (h *RestaurantHandler) SearchNearby(w http.ResponseWriter, r *http.Request) {
@@ -129,40 +170,26 @@ func (h *RestaurantHandler) SearchNearby(w http.ResponseWriter, r *http.Request)
 		}
 	}
 	
+	filter := repository.RestaurantFilter{Cuisines: queryList(r, "cuisine")}
+	filter.OpenAt, err = requestOpenAt(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	filter.Diet, err = requestDietFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
 	if err := h.priceFetcher.FetchAndSaveRestaurants(r.Context(), lat, lng, radius); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch restaurants")
 		return
 	}
 	
-	db := database.GetDB()
-	var restaurants []models.Restaurant
-	
-	earthRadius := 6371.0
-	query := db.Raw(`
-		SELECT * FROM restaurants 
-		WHERE (
-			? * acos(
-				cos(radians(?)) * 
-				cos(radians(latitude)) * 
-				cos(radians(longitude) - radians(?)) + 
-				sin(radians(?)) * 
-				sin(radians(latitude))
-			)
-		) <= ?
-		ORDER BY (
-			? * acos(
-				cos(radians(?)) * 
-				cos(radians(latitude)) * 
-				cos(radians(longitude) - radians(?)) + 
-				sin(radians(?)) * 
-				sin(radians(latitude))
-			)
-		)`,
-		earthRadius, lat, lng, lat, float64(radius)/1000.0,
-		earthRadius, lat, lng, lat,
-	)
-	
-	if err := query.Scan(&restaurants).Error; err != nil {
+	restaurants, err := h.repos.Restaurants.Nearby(r.Context(), lat, lng, radius, filter)
+	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch nearby restaurants")
 		return
 	}
@@ -172,43 +199,122 @@ func (h *RestaurantHandler) SearchNearby(w http.ResponseWriter, r *http.Request)
 
 // GetMenuItems godoc
 // @Summary Get restaurant menu items
-// @Description Get all menu items for a specific restaurant
+// @Description Get all menu items for a specific restaurant. Each item's effective_price is what it costs with the cheapest promo running at the requested time, and that promo is returned as promo. max_price and sort apply to effective_price.
 // @Tags restaurants
 // @Accept json
 // @Produce json
 // @Param id path int true "Restaurant ID"
 // @Param category query string false "Filter by category"
-// @Param max_price query number false "Maximum price filter"
+// @Param max_price query number false "Maximum price, in the requested currency (default USD)"
+// @Param currency query string false "Convert prices to this currency (ISO 4217)"
+// @Param sort query string false "Sort by price: price or -price" Enums(price, -price)
+// @Param diet query string false "Only items suitable for these diets, comma-separated, e.g. vegan,gluten_free" Enums(vegetarian, vegan, pescatarian, gluten_free, dairy_free, halal, kosher)
+// @Param exclude_allergen query string false "Leave out items containing these allergens, comma-separated, e.g. peanut,milk"
+// @Param at query string false "Work out effective_price at this time instead of now, e.g. 2026-10-16T17:00 in the restaurant's time zone, or with a UTC offset"
+// @Security BearerAuth
 // @Success 200 {array} models.MenuItem
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /restaurants/{id}/menu [get]
 func (h *RestaurantHandler) GetMenuItems(w http.ResponseWriter, r *http.Request) {
-	restaurantID := chi.URLParam(r, "id")
+	restaurantID, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
 	
-	db := database.GetDB()
-	var menuItems []models.MenuItem
+	currency, err := requestCurrency(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	
-	query := db.Where("restaurant_id = ?", restaurantID)
+	filter := repository.MenuItemFilter{
+		Category: r.URL.Query().Get("category"),
+	}
+	
+	sortOrder := r.URL.Query().Get("sort")
+	if sortOrder != "" && sortOrder != sortPriceAsc && sortOrder != sortPriceDesc {
+		respondWithError(w, http.StatusBadRequest, "sort must be price or -price")
+		return
+	}
+	
+	maxPrice, err := requestMaxPrice(r, currency)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	
-	if category := r.URL.Query().Get("category"); category != "" {
-		query = query.Where("category = ?", category)
+	filter.Diet, err = requestDietFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 	
-	if maxPrice := r.URL.Query().Get("max_price"); maxPrice != "" {
-		price, err := strconv.ParseFloat(maxPrice, 64)
-		if err == nil {
-			query = query.Where("price <= ?", price)
+	at, local := time.Now(), false
+	if value := r.URL.Query().Get("at"); value != "" {
+		at, local, err = parseTimeParam("at", value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
 		}
 	}
 	
-	if err := query.Find(&menuItems).Error; err != nil {
+	menuItems, err := h.repos.Menus.ListByRestaurant(r.Context(), restaurantID, filter)
+	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch menu items")
 		return
 	}
 	
+	promos, err := h.repos.Promos.ListByRestaurant(r.Context(), restaurantID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch promos")
+		return
+	}
+	applyPromos(menuItems, promos, at, local)
+	menuItems = filterByEffectivePrice(h.converter, menuItems, maxPrice)
+	sortByEffectivePrice(h.converter, menuItems, sortOrder)
+	
+	convertMenuItems(h.converter, menuItems, currency)
+	
 	respondWithJSON(w, http.StatusOK, menuItems)
 }
 
+// GetPromos godoc
+// @Summary Get restaurant promos
+// @Description Get the active promos of a restaurant, such as happy hours and lunch specials. Days count from 0 (Sunday) and times are in the restaurant's time zone.
+// @Tags restaurants
+// @Accept json
+// @Produce json
+// @Param id path int true "Restaurant ID"
+// @Param currency query string false "Convert fixed promo prices to this currency (ISO 4217)"
+// @Security BearerAuth
+// @Success 200 {array} models.PromoPrice
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /restaurants/{id}/promos [get]
+func (h *RestaurantHandler) GetPromos(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := parseIDParam(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid restaurant ID")
+		return
+	}
+	currency, err := requestCurrency(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	promos, err := h.repos.Promos.ListByRestaurant(r.Context(), restaurantID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch promos")
+		return
+	}
+	convertPromos(h.converter, promos, currency)
+	
+	respondWithJSON(w, http.StatusOK, promos)
+}
+
 // GetMenuItem godoc
 // @Summary Get menu item by ID
 // @Description Get detailed information about a specific menu item including price history
@@ -216,21 +322,40 @@ func (h *RestaurantHandler) GetMenuItems(w http.ResponseWriter, r *http.Request)
 // @Accept json
 // @Produce json
 // @Param itemId path int true "Menu Item ID"
+// @Param currency query string false "Convert prices to this currency (ISO 4217)"
+// @Security BearerAuth
 // @Success 200 {object} models.MenuItem
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
 // @Router /menu-items/{itemId} [get]
 func (h *RestaurantHandler) GetMenuItem(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "itemId")
+	id, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
 	
-	db := database.GetDB()
-	var menuItem models.MenuItem
+	currency, err := requestCurrency(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	
-	if err := db.Preload("PriceHistory").First(&menuItem, id).Error; err != nil {
+	menuItem, err := h.repos.Menus.Get(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
 		respondWithError(w, http.StatusNotFound, "Menu item not found")
 		return
 	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch menu item")
+		return
+	}
+	
+	items := []models.MenuItem{*menuItem}
+	convertMenuItems(h.converter, items, currency)
 	
-	respondWithJSON(w, http.StatusOK, menuItem)
+	respondWithJSON(w, http.StatusOK, items[0])
 }
 
 // GetPriceHistory godoc
@@ -240,25 +365,109 @@ func (h *RestaurantHandler) GetMenuItem(w http.ResponseWriter, r *http.Request)
 // @Accept json
 // @Produce json
 // @Param itemId path int true "Menu Item ID"
+// @Param currency query string false "Convert prices to this currency at the rate of each entry's day (ISO 4217)"
+// @Param source query string false "Only entries from this source" Enums(google_places, website_jsonld, manual, import)
+// @Param variant_id query int false "History of this variant of the item instead of the item's own"
+// @Param modifier_id query int false "History of this modifier of the item instead of the item's own"
+// @Security BearerAuth
 // @Success 200 {array} models.PriceHistory
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /menu-items/{itemId}/price-history [get]
 func (h *RestaurantHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
-	itemID := chi.URLParam(r, "itemId")
+	itemID, err := parseIDParam(r, "itemId")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid menu item ID")
+		return
+	}
 	
-	db := database.GetDB()
-	var priceHistory []models.PriceHistory
+	currency, err := requestCurrency(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	source := r.URL.Query().Get("source")
+	if source != "" && !models.ValidPriceSource(source) {
+		respondWithError(w, http.StatusBadRequest, "source must be google_places, website_jsonld, manual or import")
+		return
+	}
 	
-	if err := db.Where("menu_item_id = ?", itemID).
-		Order("recorded_at DESC").
-		Find(&priceHistory).Error; err != nil {
+	filter := repository.PriceHistoryFilter{Source: source}
+	if filter.VariantID, err = optionalIDQuery(r, "variant_id"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid variant_id")
+		return
+	}
+	if filter.ModifierID, err = optionalIDQuery(r, "modifier_id"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid modifier_id")
+		return
+	}
+	if filter.VariantID != nil && filter.ModifierID != nil {
+		respondWithError(w, http.StatusBadRequest, "Pass either variant_id or modifier_id")
+		return
+	}
+	
+	priceHistory, err := h.repos.PriceHistory.ListByMenuItem(r.Context(), itemID, filter)
+	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch price history")
 		return
 	}
 	
+	convertPriceHistory(h.converter, priceHistory, currency)
+	
 	respondWithJSON(w, http.StatusOK, priceHistory)
 }
 
+// Sort orders for menu listings.
+const (
+	sortPriceAsc  = "price"
+	sortPriceDesc = "-price"
+)
+
+// applyPromos sets the effective price of items at the requested time, read
+// in the restaurant's time zone.
+func applyPromos(items []models.MenuItem, promos []models.PromoPrice, at time.Time, local bool) {
+	if len(promos) > 0 && promos[0].Restaurant != nil {
+		at = promos[0].Restaurant.LocalTime(at, local)
+	}
+	for i := range items {
+		item := &items[i]
+		price, promo := models.EffectivePrice(item, promos, at)
+		item.EffectivePrice, item.Promo = &price, promo
+	}
+}
+
+// filterByEffectivePrice keeps the items whose effective price is at most
+// max, when given.
+func filterByEffectivePrice(converter *exchange.Converter, items []models.MenuItem, max *money.Money) []models.MenuItem {
+	if max == nil {
+		return items
+	}
+	kept := items[:0]
+	for _, item := range items {
+		if withinMaxPrice(converter, *item.EffectivePrice, *max) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// sortByEffectivePrice orders items by effective price, compared in euros,
+// for sortPriceAsc or sortPriceDesc, and leaves them in ID order otherwise.
+func sortByEffectivePrice(converter *exchange.Converter, items []models.MenuItem, order string) {
+	if order == "" {
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a := comparablePrice(converter, *items[i].EffectivePrice)
+		b := comparablePrice(converter, *items[j].EffectivePrice)
+		if order == sortPriceDesc {
+			return a > b
+		}
+		return a < b
+	})
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 	w.Header().Set("Content-Type", "application/json")
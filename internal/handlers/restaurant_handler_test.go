@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"cheapeats-api/internal/exchange"
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
+)
+
+func newTestRestaurantHandler() (*RestaurantHandler, repository.Repositories) {
+	repos := repository.NewMemoryRepositories()
+	return NewRestaurantHandler(nil, repos, exchange.NewConverter()), repos
+}
+
+func TestGetRestaurant(t *testing.T) {
+	h, repos := newTestRestaurantHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	createTestItem(t, repos, restaurant.ID, "Margherita Pizza", "12.00")
+
+	rec := serveRoute(t, h.GetRestaurant, http.MethodGet, "/restaurants/{id}", "/restaurants/999", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown restaurant: status = %d, want 404", rec.Code)
+	}
+
+	rec = serveRoute(t, h.GetRestaurant, http.MethodGet, "/restaurants/{id}", "/restaurants/"+jsonID(restaurant.ID), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got struct {
+		Name      string         `json:"name"`
+		MenuItems []responseItem `json:"menu_items"`
+	}
+	decodeResponse(t, rec, &got)
+	if got.Name != "Luigi's" || len(got.MenuItems) != 1 || got.MenuItems[0].Price != "12.00" {
+		t.Errorf("restaurant = %+v, want Luigi's with its pizza at 12.00", got)
+	}
+
+	rec = serveRoute(t, h.GetRestaurant, http.MethodGet, "/restaurants/{id}", "/restaurants/"+jsonID(restaurant.ID)+"?currency=US", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid currency: status = %d, want 400", rec.Code)
+	}
+}
+
+// No rates are loaded, so prices stay as stored whatever currency is asked
+// for, including their own.
+func TestGetRestaurantCurrencyWithoutRate(t *testing.T) {
+	h, repos := newTestRestaurantHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	createTestItem(t, repos, restaurant.ID, "Margherita Pizza", "12.00")
+
+	for _, currency := range []string{"USD", "usd", "XYZ"} {
+		rec := serveRoute(t, h.GetRestaurant, http.MethodGet, "/restaurants/{id}", "/restaurants/"+jsonID(restaurant.ID)+"?currency="+currency, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("currency %s: status = %d, body %s", currency, rec.Code, rec.Body)
+		}
+		var got struct {
+			MenuItems []struct {
+				responseItem
+				OriginalPrice string `json:"original_price"`
+			} `json:"menu_items"`
+		}
+		decodeResponse(t, rec, &got)
+		if len(got.MenuItems) != 1 || got.MenuItems[0].Price != "12.00" || got.MenuItems[0].Currency != "USD" || got.MenuItems[0].OriginalPrice != "" {
+			t.Errorf("currency %s: items = %+v, want the pizza at 12.00 USD", currency, got.MenuItems)
+		}
+	}
+}
+
+func TestGetPromosConvertsPrices(t *testing.T) {
+	h, repos := newTestRestaurantHandler()
+	h.converter.Load([]models.ExchangeRate{{Currency: "USD", Date: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Rate: 1.1}})
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	pizza := createTestItem(t, repos, restaurant.ID, "Margherita Pizza", "12.00")
+
+	price := money.New(550, "USD")
+	promo := &models.PromoPrice{RestaurantID: restaurant.ID, MenuItemID: &pizza.ID, Name: "Lunch special", Price: &price, IsActive: true}
+	if err := repos.Promos.Create(context.Background(), promo); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query                          string
+		price, currency, originalPrice string
+	}{
+		{"", "5.50", "USD", ""},
+		{"?currency=USD", "5.50", "USD", ""},
+		{"?currency=eur", "5.00", "EUR", "5.50"},
+		{"?currency=XYZ", "5.50", "USD", ""},
+	}
+	for _, tt := range tests {
+		rec := serveRoute(t, h.GetPromos, http.MethodGet, "/restaurants/{id}/promos", "/restaurants/"+jsonID(restaurant.ID)+"/promos"+tt.query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, body %s", tt.query, rec.Code, rec.Body)
+		}
+		var got []struct {
+			Price            string `json:"price"`
+			Currency         string `json:"currency"`
+			OriginalPrice    string `json:"original_price"`
+			OriginalCurrency string `json:"original_currency"`
+		}
+		decodeResponse(t, rec, &got)
+		if len(got) != 1 || got[0].Price != tt.price || got[0].Currency != tt.currency || got[0].OriginalPrice != tt.originalPrice {
+			t.Errorf("%q: promos = %+v, want %s %s from %q", tt.query, got, tt.price, tt.currency, tt.originalPrice)
+		}
+		if tt.originalPrice != "" && got[0].OriginalCurrency != "USD" {
+			t.Errorf("%q: original currency = %q, want USD", tt.query, got[0].OriginalCurrency)
+		}
+	}
+
+	rec := serveRoute(t, h.GetPromos, http.MethodGet, "/restaurants/{id}/promos", "/restaurants/"+jsonID(restaurant.ID)+"/promos?currency=EURO", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid currency: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestGetMenuItemsAppliesPromos(t *testing.T) {
+	h, repos := newTestRestaurantHandler()
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	pizza := &models.MenuItem{RestaurantID: restaurant.ID, Name: "Margherita Pizza", Category: "Pizza", Price: money.New(1200, "USD"), IsAvailable: true}
+	salad := &models.MenuItem{RestaurantID: restaurant.ID, Name: "Caesar Salad", Category: "Salad", Price: money.New(1000, "USD"), IsAvailable: true}
+	tiramisu := &models.MenuItem{RestaurantID: restaurant.ID, Name: "Tiramisu", Category: "Dessert", Price: money.New(600, "USD"), IsAvailable: true}
+	for _, item := range []*models.MenuItem{pizza, salad, tiramisu} {
+		if err := repos.Menus.Create(context.Background(), item, manualProvenance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	discount := 25
+	promo := &models.PromoPrice{RestaurantID: restaurant.ID, MenuItemID: &pizza.ID, Name: "Lunch", DiscountPercent: &discount, StartTime: "11:00", EndTime: "14:00", IsActive: true}
+	if err := repos.Promos.Create(context.Background(), promo); err != nil {
+		t.Fatal(err)
+	}
+
+	const lunch, evening = "at=2026-10-16T12:00", "at=2026-10-16T18:00"
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "during promo", query: lunch, want: []string{"Margherita Pizza 9.00", "Caesar Salad 10.00", "Tiramisu 6.00"}},
+		{name: "outside promo", query: evening, want: []string{"Margherita Pizza 12.00", "Caesar Salad 10.00", "Tiramisu 6.00"}},
+		{name: "category", query: "category=Dessert&" + lunch, want: []string{"Tiramisu 6.00"}},
+		{name: "sort during promo", query: "sort=price&" + lunch, want: []string{"Tiramisu 6.00", "Margherita Pizza 9.00", "Caesar Salad 10.00"}},
+		{name: "sort outside promo", query: "sort=-price&" + evening, want: []string{"Margherita Pizza 12.00", "Caesar Salad 10.00", "Tiramisu 6.00"}},
+		{name: "max price during promo", query: "max_price=9.50&" + lunch, want: []string{"Margherita Pizza 9.00", "Tiramisu 6.00"}},
+		{name: "max price outside promo", query: "max_price=9.50&" + evening, want: []string{"Tiramisu 6.00"}},
+		{name: "max price without rate", query: "max_price=9.50&currency=EUR&" + lunch, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveRoute(t, h.GetMenuItems, http.MethodGet, "/restaurants/{id}/menu", "/restaurants/"+jsonID(restaurant.ID)+"/menu?"+tt.query, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			var items []struct {
+				Name           string `json:"name"`
+				EffectivePrice string `json:"effective_price"`
+			}
+			decodeResponse(t, rec, &items)
+
+			got := make([]string, len(items))
+			for i, item := range items {
+				got[i] = item.Name + " " + item.EffectivePrice
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Deals rank items by their regular price; promos only show on menus.
+func TestGetCheapestIgnoresPromos(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	h := NewDealHandler(repos.Menus, exchange.NewConverter())
+	restaurant := createTestRestaurant(t, repos, "Luigi's")
+	pizza := createTestItem(t, repos, restaurant.ID, "Margherita Pizza", "12.00")
+	createTestItem(t, repos, restaurant.ID, "Caesar Salad", "10.00")
+
+	discount := 50
+	promo := &models.PromoPrice{RestaurantID: restaurant.ID, MenuItemID: &pizza.ID, Name: "Half price", DiscountPercent: &discount, IsActive: true}
+	if err := repos.Promos.Create(context.Background(), promo); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serveRoute(t, h.GetCheapest, http.MethodGet, "/deals/cheapest", "/deals/cheapest?max_price=11", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var items []responseItem
+	decodeResponse(t, rec, &items)
+	if len(items) != 1 || items[0].Name != "Caesar Salad" || items[0].Price != "10.00" {
+		t.Errorf("deals = %+v, want only the salad at its regular price", items)
+	}
+}
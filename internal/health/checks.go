@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Database pings the connection pool.
+func Database(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) ComponentStatus {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return Fail(err.Error())
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return Fail("ping failed: " + err.Error())
+		}
+
+		stats := sqlDB.Stats()
+		status := OK()
+		status.Details = map[string]interface{}{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+		}
+		return status
+	}
+}
+
+// SchemaVersion compares the database's migration version with the one the
+// binary expects. check returns the current version and an error on a
+// mismatch.
+func SchemaVersion(check func(ctx context.Context) (int64, error), expected int64) CheckFunc {
+	return func(ctx context.Context) ComponentStatus {
+		current, err := check(ctx)
+
+		status := OK()
+		if err != nil {
+			status = Fail(err.Error())
+		}
+		status.Details = map[string]interface{}{
+			"version":  current,
+			"expected": expected,
+		}
+		return status
+	}
+}
+
+// QueueDepth reports degraded once the queue is at least 80% full.
+func QueueDepth(depth func() int, capacity int) CheckFunc {
+	return func(ctx context.Context) ComponentStatus {
+		current := depth()
+
+		status := OK()
+		if capacity > 0 && current*5 >= capacity*4 {
+			status = Degraded("ingest queue is nearly full")
+		}
+		status.Details = map[string]interface{}{
+			"depth":    current,
+			"capacity": capacity,
+		}
+		return status
+	}
+}
+
+// LastIngest reports degraded when the newest scraped data, as returned by
+// lastScrapedAt, is older than maxAge, or when nothing has been ingested at
+// all (the zero time).
+func LastIngest(lastScrapedAt func(ctx context.Context) (time.Time, error), maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) ComponentStatus {
+		last, err := lastScrapedAt(ctx)
+		if err != nil {
+			return Fail(err.Error())
+		}
+
+		if last.IsZero() {
+			return Degraded("no successful ingest recorded")
+		}
+
+		age := time.Since(last)
+		status := OK()
+		if age > maxAge {
+			status = Degraded(fmt.Sprintf("last ingest is older than %s", maxAge))
+		}
+		status.Details = map[string]interface{}{
+			"last_ingest_at": last.UTC(),
+			"age_seconds":    int64(age.Seconds()),
+		}
+		return status
+	}
+}
+
+// Reachable turns a ping function into a check.
+func Reachable(ping func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) ComponentStatus {
+		if err := ping(ctx); err != nil {
+			return Fail(err.Error())
+		}
+		return OK()
+	}
+}
+
+// Cached reuses a check's result for ttl, for checks that are expensive or
+// call third parties.
+func Cached(fn CheckFunc, ttl time.Duration) CheckFunc {
+	var mu sync.Mutex
+	var result ComponentStatus
+	var expires time.Time
+
+	return func(ctx context.Context) ComponentStatus {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if time.Now().Before(expires) {
+			return result
+		}
+
+		result = fn(ctx)
+		expires = time.Now().Add(ttl)
+		return result
+	}
+}
@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Component and overall statuses.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// ComponentStatus is the result of a single check.
+type ComponentStatus struct {
+	Status    string                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	LatencyMS int64                  `json:"latency_ms"`
+}
+
+// Report is the combined result of all checks.
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+}
+
+// CheckFunc inspects one dependency. It should return promptly once ctx is
+// done.
+type CheckFunc func(ctx context.Context) ComponentStatus
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// Checker runs a set of readiness checks concurrently.
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+	}
+}
+
+// Register adds a check. A failing critical check makes the service not
+// ready; any other failure only marks it degraded.
+func (c *Checker) Register(name string, critical bool, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// Run executes every check with the checker's timeout and combines the
+// results.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{
+		Status:     StatusOK,
+		Components: make(map[string]ComponentStatus, len(c.checks)),
+		CheckedAt:  time.Now().UTC(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+
+			start := time.Now()
+			result := runCheck(ctx, chk.fn)
+			result.Critical = chk.critical
+			result.LatencyMS = time.Since(start).Milliseconds()
+
+			mu.Lock()
+			report.Components[chk.name] = result
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	for _, result := range report.Components {
+		switch {
+		case result.Status == StatusOK:
+		case result.Critical && result.Status == StatusFail:
+			report.Status = StatusFail
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// runCheck turns a check that ignores its context or panics into a failure
+// instead of blocking or crashing the probe.
+func runCheck(ctx context.Context, fn CheckFunc) (result ComponentStatus) {
+	done := make(chan ComponentStatus, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- Fail("check panicked")
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case result = <-done:
+		return result
+	case <-ctx.Done():
+		return Fail("check timed out")
+	}
+}
+
+// OK returns a passing status.
+func OK() ComponentStatus {
+	return ComponentStatus{Status: StatusOK}
+}
+
+// Fail returns a failing status with an explanation.
+func Fail(message string) ComponentStatus {
+	return ComponentStatus{Status: StatusFail, Message: message}
+}
+
+// Degraded returns a status for a dependency that works but needs attention.
+func Degraded(message string) ComponentStatus {
+	return ComponentStatus{Status: StatusDegraded, Message: message}
+}
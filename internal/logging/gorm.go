@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM's logs to slog. Failed statements are logged as
+// errors and statements slower than the threshold as warnings; everything
+// else is only logged at debug level.
+type GormLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+// NewGormLogger creates the adapter. A zero slowThreshold disables slow-query
+// warnings.
+func NewGormLogger(l *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{
+		logger:        l,
+		slowThreshold: slowThreshold,
+		level:         logger.Info,
+	}
+}
+
+func (g *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *g
+	clone.level = level
+	return &clone
+}
+
+func (g *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Info {
+		g.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Warn {
+		g.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Error {
+		g.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && g.level >= logger.Error:
+		sql, rows := fc()
+		g.logger.ErrorContext(ctx, "sql error",
+			slog.String("error", err.Error()),
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("duration", elapsed),
+		)
+	case g.slowThreshold > 0 && elapsed > g.slowThreshold && g.level >= logger.Warn:
+		sql, rows := fc()
+		g.logger.WarnContext(ctx, "slow sql",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("duration", elapsed),
+			slog.Duration("threshold", g.slowThreshold),
+		)
+	case g.logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		g.logger.DebugContext(ctx, "sql",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("duration", elapsed),
+		)
+	}
+}
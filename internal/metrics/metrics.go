@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cheapeats"
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route pattern, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database statement latency by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+
+	DBQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Database statements that returned an error, by operation and table.",
+	}, []string{"operation", "table"})
+
+	PlacesRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "places_api_requests_total",
+		Help:      "Outbound Places API requests by endpoint and HTTP status (\"error\" for transport failures).",
+	}, []string{"endpoint", "status"})
+
+	PlacesRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "places_api_request_duration_seconds",
+		Help:      "Outbound Places API latency by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	IngestJobs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_jobs_total",
+		Help:      "Background ingest jobs by result (success, failure, dropped).",
+	}, []string{"result"})
+
+	RestaurantsIngested = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "restaurants_ingested_total",
+		Help:      "Restaurants created or updated from the Places API.",
+	})
+
+	PriceChanges = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "price_changes_total",
+		Help:      "Menu item price changes recorded in the price history.",
+	})
+)
+
+// RegisterQueueDepth exposes the ingest queue depth, read on every scrape.
+func RegisterQueueDepth(depth func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ingest_queue_depth",
+		Help:      "Ingest jobs waiting for a worker.",
+	}, func() float64 {
+		return float64(depth())
+	})
+}
+
+// Handler serves the default registry, including Go runtime and process
+// metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transport counts and times outbound Places API requests.
+type Transport struct {
+	next http.RoundTripper
+}
+
+// NewTransport wraps next, or http.DefaultTransport when next is nil.
+func NewTransport(next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := placesEndpoint(req.URL.Path)
+	start := time.Now()
+
+	resp, err := t.next.RoundTrip(req)
+
+	PlacesRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	PlacesRequests.WithLabelValues(endpoint, status).Inc()
+
+	return resp, err
+}
+
+// placesEndpoint reduces a path such as "/maps/api/place/nearbysearch/json"
+// to "nearbysearch".
+func placesEndpoint(path string) string {
+	rest, ok := strings.CutPrefix(path, "/maps/api/place/")
+	if !ok {
+		return "other"
+	}
+	endpoint, _, _ := strings.Cut(rest, "/")
+	if endpoint == "" {
+		return "root"
+	}
+	return endpoint
+}
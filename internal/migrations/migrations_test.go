@@ -0,0 +1,245 @@
+package migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func file(body string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(body)}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0010_add_reports.up.sql":   file("CREATE TABLE reports ();"),
+		"sql/0010_add_reports.down.sql": file("DROP TABLE reports;"),
+		"sql/0002_add_menus.up.sql":     file("CREATE TABLE menus ();"),
+		"sql/0001_init.up.sql":          file("CREATE TABLE restaurants ();"),
+		"sql/0001_init.down.sql":        file("DROP TABLE restaurants;"),
+	}
+
+	migrations, err := load(fsys, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Migration{
+		{Version: 1, Name: "init", Up: "CREATE TABLE restaurants ();", Down: "DROP TABLE restaurants;"},
+		{Version: 2, Name: "add_menus", Up: "CREATE TABLE menus ();"},
+		{Version: 10, Name: "add_reports", Up: "CREATE TABLE reports ();", Down: "DROP TABLE reports;"},
+	}
+	if !reflect.DeepEqual(migrations, want) {
+		t.Errorf("load() = %+v, want %+v", migrations, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		fsys  fstest.MapFS
+		error string
+	}{
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"sql/0003_add_menus.up.sql":  file("CREATE TABLE menus ();"),
+				"sql/0003_add_promos.up.sql": file("CREATE TABLE promos ();"),
+			},
+			error: "migration 3 has two names",
+		},
+		{
+			name: "duplicate version written differently",
+			fsys: fstest.MapFS{
+				"sql/3_add_menus.up.sql":       file("CREATE TABLE menus ();"),
+				"sql/0003_add_promos.up.sql":   file("CREATE TABLE promos ();"),
+				"sql/0003_add_promos.down.sql": file("DROP TABLE promos;"),
+			},
+			error: "migration 3 has two names",
+		},
+		{
+			name:  "down without up",
+			fsys:  fstest.MapFS{"sql/0001_init.down.sql": file("DROP TABLE restaurants;")},
+			error: "migration 1_init has no up script",
+		},
+		{
+			name:  "invalid name",
+			fsys:  fstest.MapFS{"sql/0001_Init.up.sql": file("SELECT 1;")},
+			error: `invalid migration file name "0001_Init.up.sql"`,
+		},
+		{
+			name:  "other file",
+			fsys:  fstest.MapFS{"sql/README.md": file("notes")},
+			error: `invalid migration file name "README.md"`,
+		},
+		{
+			name:  "missing directory",
+			fsys:  fstest.MapFS{},
+			error: "failed to read migrations",
+		},
+	}
+	for _, tt := range tests {
+		if _, err := load(tt.fsys, "sql"); err == nil || !strings.Contains(err.Error(), tt.error) {
+			t.Errorf("%s: load() error = %v, want %q", tt.name, err, tt.error)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := load(files, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("migration %d_%s is numbered out of sequence, want %d", migration.Version, migration.Name, i+1)
+		}
+		if migration.Down == "" {
+			t.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "add_reports", want: "add_reports"},
+		{name: "  Add Reports Table ", want: "add_reports_table"},
+		{name: "add-menu--items!", want: "add_menu_items"},
+		{name: "2fa codes", want: "2fa_codes"},
+		{name: "", wantErr: true},
+		{name: "   ", wantErr: true},
+		{name: "--!", wantErr: true},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		up, down, err := Create(dir, tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Create(%q) succeeded, want error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Create(%q) error = %v", tt.name, err)
+		}
+		if want := filepath.Join(dir, "0001_"+tt.want+".up.sql"); up != want {
+			t.Errorf("Create(%q) up = %q, want %q", tt.name, up, want)
+		}
+		if want := filepath.Join(dir, "0001_"+tt.want+".down.sql"); down != want {
+			t.Errorf("Create(%q) down = %q, want %q", tt.name, down, want)
+		}
+	}
+}
+
+func TestCreateNumbersAfterNewest(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"0001_init.up.sql":        "SELECT 1;",
+		"0001_init.down.sql":      "SELECT 1;",
+		"0009_add_menus.up.sql":   "SELECT 1;",
+		"0009_add_menus.down.sql": "SELECT 1;",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	up, _, err := Create(dir, "add reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0010_add_reports.up.sql" {
+		t.Errorf("Create() up = %q, want 0010_add_reports.up.sql", filepath.Base(up))
+	}
+	migrations, err := load(os.DirFS(dir), ".")
+	if err != nil || len(migrations) != 3 {
+		t.Errorf("after Create() load() = %+v, %v, want 3 migrations", migrations, err)
+	}
+
+	// A directory with a broken migration is left alone.
+	if err := os.WriteFile(filepath.Join(dir, "0011_Broken.up.sql"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Create(dir, "add promos"); err == nil {
+		t.Error("Create() succeeded next to an invalid migration file")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "init"},
+		{Version: 2, Name: "add_menus"},
+		{Version: 3, Name: "add_reports"},
+	}
+	applied := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		done    map[int64]appliedRecord
+		want    []Status
+		current int64
+		err     error
+	}{
+		{
+			name: "fresh database",
+			done: map[int64]appliedRecord{},
+			want: []Status{
+				{Version: 1, Name: "init"},
+				{Version: 2, Name: "add_menus"},
+				{Version: 3, Name: "add_reports"},
+			},
+			err: ErrPending,
+		},
+		{
+			name: "partly applied",
+			done: map[int64]appliedRecord{1: {"init", applied}, 2: {"add_menus", applied}},
+			want: []Status{
+				{Version: 1, Name: "init", AppliedAt: &applied},
+				{Version: 2, Name: "add_menus", AppliedAt: &applied},
+				{Version: 3, Name: "add_reports"},
+			},
+			current: 2,
+			err:     ErrPending,
+		},
+		{
+			name: "up to date",
+			done: map[int64]appliedRecord{1: {"init", applied}, 2: {"add_menus", applied}, 3: {"add_reports", applied}},
+			want: []Status{
+				{Version: 1, Name: "init", AppliedAt: &applied},
+				{Version: 2, Name: "add_menus", AppliedAt: &applied},
+				{Version: 3, Name: "add_reports", AppliedAt: &applied},
+			},
+			current: 3,
+		},
+		{
+			name: "migrated by a newer release",
+			done: map[int64]appliedRecord{1: {"init", applied}, 2: {"add_menus", applied}, 3: {"add_reports", applied}, 4: {"add_promos", applied}},
+			want: []Status{
+				{Version: 1, Name: "init", AppliedAt: &applied},
+				{Version: 2, Name: "add_menus", AppliedAt: &applied},
+				{Version: 3, Name: "add_reports", AppliedAt: &applied},
+				{Version: 4, Name: "add_promos", AppliedAt: &applied, Unknown: true},
+			},
+			current: 4,
+			err:     ErrUnknown,
+		},
+	}
+	for _, tt := range tests {
+		statuses := statusOf(migrations, tt.done)
+		if !reflect.DeepEqual(statuses, tt.want) {
+			t.Errorf("%s: statusOf() = %+v, want %+v", tt.name, statuses, tt.want)
+		}
+		current, err := check(statuses)
+		if current != tt.current || !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+			t.Errorf("%s: check() = %d, %v, want %d, %v", tt.name, current, err, tt.current, tt.err)
+		}
+	}
+}
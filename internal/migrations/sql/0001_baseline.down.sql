@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS api_key_usages;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS scraped_data;
+DROP TABLE IF EXISTS price_histories;
+DROP TABLE IF EXISTS menu_items;
+DROP TABLE IF EXISTS restaurants;
@@ -0,0 +1,124 @@
+-- Baseline schema, matching what GORM's AutoMigrate created before versioned
+-- migrations were introduced. Every statement is idempotent so databases
+-- created by AutoMigrate can adopt it without changes.
+
+CREATE TABLE IF NOT EXISTS restaurants (
+    id           bigserial PRIMARY KEY,
+    external_id  varchar(255),
+    name         varchar(255) NOT NULL,
+    address      text,
+    city         varchar(100),
+    state        varchar(50),
+    zip_code     varchar(20),
+    country      varchar(100),
+    latitude     decimal,
+    longitude    decimal,
+    cuisine_type varchar(100),
+    phone        varchar(50),
+    website      varchar(255),
+    rating       decimal,
+    price_range  varchar(10),
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_restaurants_external_id ON restaurants (external_id);
+CREATE INDEX IF NOT EXISTS idx_location ON restaurants (latitude, longitude);
+CREATE INDEX IF NOT EXISTS idx_restaurants_deleted_at ON restaurants (deleted_at);
+
+CREATE TABLE IF NOT EXISTS menu_items (
+    id            bigserial PRIMARY KEY,
+    restaurant_id bigint,
+    name          varchar(255) NOT NULL,
+    description   text,
+    category      varchar(100),
+    price         decimal NOT NULL,
+    currency      varchar(10) DEFAULT 'USD',
+    is_available  boolean DEFAULT true,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_items_restaurant_id ON menu_items (restaurant_id);
+CREATE INDEX IF NOT EXISTS idx_menu_items_price ON menu_items (price);
+CREATE INDEX IF NOT EXISTS idx_menu_items_deleted_at ON menu_items (deleted_at);
+
+CREATE TABLE IF NOT EXISTS price_histories (
+    id           bigserial PRIMARY KEY,
+    menu_item_id bigint,
+    price        decimal NOT NULL,
+    recorded_at  timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_histories_menu_item_id ON price_histories (menu_item_id);
+
+CREATE TABLE IF NOT EXISTS scraped_data (
+    id            bigserial PRIMARY KEY,
+    source        varchar(100) NOT NULL,
+    restaurant_id bigint,
+    raw_data      jsonb,
+    scraped_at    timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scraped_data_source ON scraped_data (source);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id            bigserial PRIMARY KEY,
+    name          varchar(100) NOT NULL,
+    prefix        varchar(16),
+    key_hash      varchar(64) NOT NULL,
+    scopes        varchar(255) NOT NULL,
+    rate_limit    bigint DEFAULT 0,
+    request_count bigint DEFAULT 0,
+    last_used_at  timestamptz,
+    expires_at    timestamptz,
+    revoked_at    timestamptz,
+    created_at    timestamptz,
+    updated_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_revoked_at ON api_keys (revoked_at);
+
+CREATE TABLE IF NOT EXISTS api_key_usages (
+    id           bigserial PRIMARY KEY,
+    api_key_id   bigint NOT NULL,
+    day          date NOT NULL,
+    requests     bigint NOT NULL DEFAULT 0,
+    rate_limited bigint NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_key_usage_day ON api_key_usages (api_key_id, day);
+
+-- AutoMigrate named its foreign keys after the association, so only add them
+-- where the column has no foreign key yet.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = 'menu_items'::regclass AND contype = 'f'
+    ) THEN
+        ALTER TABLE menu_items ADD CONSTRAINT fk_restaurants_menu_items
+            FOREIGN KEY (restaurant_id) REFERENCES restaurants (id);
+    END IF;
+
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = 'price_histories'::regclass AND contype = 'f'
+    ) THEN
+        ALTER TABLE price_histories ADD CONSTRAINT fk_menu_items_price_history
+            FOREIGN KEY (menu_item_id) REFERENCES menu_items (id);
+    END IF;
+
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = 'scraped_data'::regclass AND contype = 'f'
+    ) THEN
+        ALTER TABLE scraped_data ADD CONSTRAINT fk_scraped_data_restaurant
+            FOREIGN KEY (restaurant_id) REFERENCES restaurants (id);
+    END IF;
+END
+$$;
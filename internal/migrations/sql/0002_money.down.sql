@@ -0,0 +1,8 @@
+ALTER TABLE price_histories
+    ALTER COLUMN price TYPE decimal,
+    DROP COLUMN currency;
+
+ALTER TABLE menu_items
+    ALTER COLUMN price TYPE decimal,
+    ALTER COLUMN currency DROP NOT NULL,
+    ALTER COLUMN currency TYPE varchar(10);
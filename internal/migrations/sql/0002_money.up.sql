@@ -0,0 +1,44 @@
+-- Store prices as exact decimals with an ISO 4217 currency on every row,
+-- rounding existing values to each currency's minor unit.
+
+CREATE FUNCTION pg_temp.currency_exponent(code text) RETURNS integer
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT CASE
+        WHEN code IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                      'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 0
+        WHEN code IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 3
+        WHEN code IN ('CLF', 'UYW') THEN 4
+        ELSE 2
+    END
+$$;
+
+UPDATE menu_items
+SET currency = CASE
+    WHEN upper(trim(currency)) ~ '^[A-Z]{3}$' THEN upper(trim(currency))
+    ELSE 'USD'
+END
+WHERE currency IS NULL OR currency !~ '^[A-Z]{3}$';
+
+ALTER TABLE menu_items
+    ALTER COLUMN currency TYPE varchar(3),
+    ALTER COLUMN currency SET DEFAULT 'USD',
+    ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN price TYPE numeric(12,4)
+        USING round(price::numeric, pg_temp.currency_exponent(currency));
+
+ALTER TABLE price_histories ADD COLUMN currency varchar(3);
+
+UPDATE price_histories ph
+SET currency = mi.currency
+FROM menu_items mi
+WHERE mi.id = ph.menu_item_id;
+
+UPDATE price_histories SET currency = 'USD' WHERE currency IS NULL;
+
+ALTER TABLE price_histories
+    ALTER COLUMN currency SET DEFAULT 'USD',
+    ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN price TYPE numeric(12,4)
+        USING round(price::numeric, pg_temp.currency_exponent(currency));
+
+DROP FUNCTION pg_temp.currency_exponent(text);
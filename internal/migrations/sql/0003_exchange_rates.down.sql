@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS latest_exchange_rates;
+DROP TABLE IF EXISTS exchange_rates;
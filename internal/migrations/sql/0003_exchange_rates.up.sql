@@ -0,0 +1,18 @@
+-- Exchange rates per euro, one row per currency and publication day.
+
+CREATE TABLE exchange_rates (
+    id         bigserial PRIMARY KEY,
+    currency   varchar(3) NOT NULL,
+    rate_date  date NOT NULL,
+    rate       numeric(18,8) NOT NULL CHECK (rate > 0),
+    source     varchar(255),
+    created_at timestamptz
+);
+
+CREATE UNIQUE INDEX idx_exchange_rate_day ON exchange_rates (currency, rate_date);
+
+-- The newest rate of each currency, used to filter and sort prices in euros.
+CREATE VIEW latest_exchange_rates AS
+SELECT DISTINCT ON (currency) currency, rate_date, rate
+FROM exchange_rates
+ORDER BY currency, rate_date DESC;
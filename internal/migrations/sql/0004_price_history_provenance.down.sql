@@ -0,0 +1,5 @@
+ALTER TABLE price_histories
+    DROP COLUMN scraped_data_id,
+    DROP COLUMN source,
+    DROP COLUMN previous_currency,
+    DROP COLUMN previous_price;
@@ -0,0 +1,35 @@
+-- Where each recorded price came from and what it replaced.
+
+ALTER TABLE price_histories
+    ADD COLUMN previous_price    numeric(12,4),
+    ADD COLUMN previous_currency varchar(3),
+    ADD COLUMN source            varchar(50) NOT NULL DEFAULT 'manual',
+    ADD COLUMN scraped_data_id   bigint
+        CONSTRAINT fk_price_histories_scraped_data REFERENCES scraped_data (id) ON DELETE SET NULL;
+
+-- Existing history has no provenance. Prices of restaurants found through
+-- Places (external IDs other than the seed: and manual: ones) came from
+-- ingest; everything else was entered by hand.
+UPDATE price_histories ph
+SET source = 'google_places'
+FROM menu_items mi
+JOIN restaurants r ON r.id = mi.restaurant_id
+WHERE mi.id = ph.menu_item_id
+  AND r.external_id <> ''
+  AND r.external_id NOT LIKE 'seed:%'
+  AND r.external_id NOT LIKE 'manual:%';
+
+-- The previous price is the entry before it for the same item.
+UPDATE price_histories ph
+SET previous_price = prev.price, previous_currency = prev.currency
+FROM (
+    SELECT id,
+           lag(price) OVER w AS price,
+           lag(currency) OVER w AS currency
+    FROM price_histories
+    WINDOW w AS (PARTITION BY menu_item_id ORDER BY recorded_at, id)
+) prev
+WHERE prev.id = ph.id AND prev.price IS NOT NULL;
+
+CREATE INDEX idx_price_histories_source ON price_histories (source);
+CREATE INDEX idx_price_histories_scraped_data_id ON price_histories (scraped_data_id);
@@ -0,0 +1,9 @@
+DELETE FROM price_histories WHERE variant_id IS NOT NULL OR modifier_id IS NOT NULL;
+
+ALTER TABLE price_histories
+    DROP CONSTRAINT chk_price_histories_option,
+    DROP COLUMN modifier_id,
+    DROP COLUMN variant_id;
+
+DROP TABLE modifiers;
+DROP TABLE menu_item_variants;
@@ -0,0 +1,46 @@
+-- Variants (sizes, combos) and modifiers (add-ons) of menu items, each with
+-- its own price and price history.
+
+CREATE TABLE menu_item_variants (
+    id           bigserial PRIMARY KEY,
+    menu_item_id bigint NOT NULL CONSTRAINT fk_menu_items_variants REFERENCES menu_items (id),
+    name         varchar(100) NOT NULL,
+    price        numeric(12,4) NOT NULL,
+    currency     varchar(3) NOT NULL DEFAULT 'USD',
+    is_available boolean DEFAULT true,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz
+);
+
+CREATE INDEX idx_menu_item_variants_menu_item_id ON menu_item_variants (menu_item_id);
+CREATE INDEX idx_menu_item_variants_deleted_at ON menu_item_variants (deleted_at);
+CREATE UNIQUE INDEX idx_menu_item_variants_name ON menu_item_variants (menu_item_id, lower(name))
+    WHERE deleted_at IS NULL;
+
+CREATE TABLE modifiers (
+    id           bigserial PRIMARY KEY,
+    menu_item_id bigint NOT NULL CONSTRAINT fk_menu_items_modifiers REFERENCES menu_items (id),
+    name         varchar(100) NOT NULL,
+    price        numeric(12,4) NOT NULL,
+    currency     varchar(3) NOT NULL DEFAULT 'USD',
+    is_available boolean DEFAULT true,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz
+);
+
+CREATE INDEX idx_modifiers_menu_item_id ON modifiers (menu_item_id);
+CREATE INDEX idx_modifiers_deleted_at ON modifiers (deleted_at);
+CREATE UNIQUE INDEX idx_modifiers_name ON modifiers (menu_item_id, lower(name))
+    WHERE deleted_at IS NULL;
+
+-- History entries of a variant or modifier keep menu_item_id pointing at the
+-- item, so an item's whole history stays in one place.
+ALTER TABLE price_histories
+    ADD COLUMN variant_id  bigint CONSTRAINT fk_price_histories_variant REFERENCES menu_item_variants (id),
+    ADD COLUMN modifier_id bigint CONSTRAINT fk_price_histories_modifier REFERENCES modifiers (id),
+    ADD CONSTRAINT chk_price_histories_option CHECK (variant_id IS NULL OR modifier_id IS NULL);
+
+CREATE INDEX idx_price_histories_variant_id ON price_histories (variant_id);
+CREATE INDEX idx_price_histories_modifier_id ON price_histories (modifier_id);
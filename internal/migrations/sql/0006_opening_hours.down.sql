@@ -0,0 +1,5 @@
+DROP TABLE opening_hours;
+
+ALTER TABLE restaurants
+    DROP COLUMN utc_offset_minutes,
+    DROP COLUMN time_zone;
@@ -0,0 +1,16 @@
+-- Opening hours of restaurants: weekly periods and special days, both as
+-- JSON, read in the restaurant's time zone.
+
+ALTER TABLE restaurants
+    ADD COLUMN time_zone          varchar(64),
+    ADD COLUMN utc_offset_minutes integer;
+
+CREATE TABLE opening_hours (
+    id            bigserial PRIMARY KEY,
+    restaurant_id bigint NOT NULL CONSTRAINT fk_restaurants_opening_hours REFERENCES restaurants (id) ON DELETE CASCADE,
+    periods       jsonb NOT NULL DEFAULT '[]',
+    special_days  jsonb NOT NULL DEFAULT '[]',
+    updated_at    timestamptz
+);
+
+CREATE UNIQUE INDEX idx_opening_hours_restaurant_id ON opening_hours (restaurant_id);
@@ -0,0 +1 @@
+DROP TABLE promo_prices;
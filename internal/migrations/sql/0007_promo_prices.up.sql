@@ -0,0 +1,26 @@
+-- Time-limited deals: a fixed price for a menu item, or a percentage off an
+-- item or a whole menu, on some days and hours.
+
+CREATE TABLE promo_prices (
+    id               bigserial PRIMARY KEY,
+    restaurant_id    bigint NOT NULL CONSTRAINT fk_restaurants_promo_prices REFERENCES restaurants (id),
+    menu_item_id     bigint CONSTRAINT fk_menu_items_promo_prices REFERENCES menu_items (id),
+    name             varchar(100) NOT NULL,
+    price            numeric(12,4),
+    currency         varchar(3),
+    discount_percent integer CHECK (discount_percent BETWEEN 1 AND 100),
+    days             jsonb NOT NULL DEFAULT '[]',
+    start_time       varchar(5),
+    end_time         varchar(5),
+    starts_on        date,
+    ends_on          date,
+    is_active        boolean DEFAULT true,
+    created_at       timestamptz,
+    updated_at       timestamptz,
+    deleted_at       timestamptz,
+    CONSTRAINT chk_promo_prices_discount CHECK ((price IS NULL) <> (discount_percent IS NULL))
+);
+
+CREATE INDEX idx_promo_prices_restaurant_id ON promo_prices (restaurant_id);
+CREATE INDEX idx_promo_prices_menu_item_id ON promo_prices (menu_item_id);
+CREATE INDEX idx_promo_prices_deleted_at ON promo_prices (deleted_at);
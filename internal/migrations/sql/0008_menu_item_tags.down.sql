@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_menu_items_allergens;
+DROP INDEX IF EXISTS idx_menu_items_diets;
+
+ALTER TABLE menu_items
+    DROP COLUMN spice_level,
+    DROP COLUMN allergens,
+    DROP COLUMN diets;
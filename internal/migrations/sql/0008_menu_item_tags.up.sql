@@ -0,0 +1,10 @@
+-- Dietary and allergen tags and a spice level on menu items. Empty tag lists
+-- mean unknown.
+
+ALTER TABLE menu_items
+    ADD COLUMN diets       jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN allergens   jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN spice_level smallint CONSTRAINT chk_menu_items_spice_level CHECK (spice_level BETWEEN 0 AND 3);
+
+CREATE INDEX idx_menu_items_diets ON menu_items USING gin (diets);
+CREATE INDEX idx_menu_items_allergens ON menu_items USING gin (allergens);
@@ -0,0 +1,2 @@
+DROP TABLE restaurant_cuisines;
+DROP TABLE cuisines;
@@ -0,0 +1,54 @@
+-- Cuisine taxonomy, with any number of cuisines per restaurant. The
+-- restaurants' cuisine_type stays as the name of the main one.
+
+CREATE TABLE cuisines (
+    id   bigserial PRIMARY KEY,
+    slug varchar(50) NOT NULL,
+    name varchar(100) NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_cuisines_slug ON cuisines (slug);
+
+CREATE TABLE restaurant_cuisines (
+    restaurant_id bigint NOT NULL CONSTRAINT fk_restaurant_cuisines_restaurant REFERENCES restaurants (id) ON DELETE CASCADE,
+    cuisine_id    bigint NOT NULL CONSTRAINT fk_restaurant_cuisines_cuisine REFERENCES cuisines (id) ON DELETE CASCADE,
+    PRIMARY KEY (restaurant_id, cuisine_id)
+);
+
+CREATE INDEX idx_restaurant_cuisines_cuisine_id ON restaurant_cuisines (cuisine_id);
+
+-- The cuisines of the built-in classification rules.
+INSERT INTO cuisines (slug, name) VALUES
+    ('chinese', 'Chinese'),
+    ('japanese', 'Japanese'),
+    ('sushi', 'Sushi'),
+    ('ramen', 'Ramen'),
+    ('korean', 'Korean'),
+    ('thai', 'Thai'),
+    ('vietnamese', 'Vietnamese'),
+    ('indian', 'Indian'),
+    ('italian', 'Italian'),
+    ('pizza', 'Pizza'),
+    ('mexican', 'Mexican'),
+    ('french', 'French'),
+    ('spanish', 'Spanish'),
+    ('greek', 'Greek'),
+    ('mediterranean', 'Mediterranean'),
+    ('middle_eastern', 'Middle Eastern'),
+    ('american', 'American'),
+    ('burger', 'Burger'),
+    ('bbq', 'Barbecue'),
+    ('steakhouse', 'Steakhouse'),
+    ('seafood', 'Seafood'),
+    ('vegetarian', 'Vegetarian'),
+    ('breakfast', 'Breakfast & Brunch'),
+    ('cafe', 'Cafe'),
+    ('bakery', 'Bakery'),
+    ('dessert', 'Dessert'),
+    ('bar', 'Bar');
+
+-- Carry over the single cuisine restaurants had so far.
+INSERT INTO restaurant_cuisines (restaurant_id, cuisine_id)
+SELECT r.id, c.id
+FROM restaurants r
+JOIN cuisines c ON lower(r.cuisine_type) IN (lower(c.name), c.slug);
@@ -0,0 +1,2 @@
+DROP TABLE duplicate_candidates;
+DROP TABLE restaurant_source_links;
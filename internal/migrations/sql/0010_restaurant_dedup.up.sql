@@ -0,0 +1,31 @@
+-- Restaurant deduplication: external IDs linked to a restaurant besides its
+-- own, and pairs of restaurants queued for review as possible duplicates.
+
+CREATE TABLE restaurant_source_links (
+    id            bigserial PRIMARY KEY,
+    restaurant_id bigint NOT NULL CONSTRAINT fk_restaurants_source_links REFERENCES restaurants (id) ON DELETE CASCADE,
+    source        varchar(50) NOT NULL,
+    external_id   varchar(255) NOT NULL,
+    created_at    timestamptz
+);
+
+CREATE UNIQUE INDEX idx_restaurant_source_links_external_id ON restaurant_source_links (source, external_id);
+CREATE INDEX idx_restaurant_source_links_restaurant_id ON restaurant_source_links (restaurant_id);
+
+CREATE TABLE duplicate_candidates (
+    id              bigserial PRIMARY KEY,
+    restaurant_id   bigint NOT NULL CONSTRAINT fk_duplicate_candidates_restaurant REFERENCES restaurants (id) ON DELETE CASCADE,
+    duplicate_id    bigint NOT NULL CONSTRAINT fk_duplicate_candidates_duplicate REFERENCES restaurants (id) ON DELETE CASCADE,
+    score           double precision NOT NULL,
+    name_similarity double precision NOT NULL,
+    distance_meters double precision NOT NULL,
+    phone_match     boolean,
+    status          varchar(20) NOT NULL DEFAULT 'pending',
+    created_at      timestamptz,
+    resolved_at     timestamptz,
+    CONSTRAINT chk_duplicate_candidates_order CHECK (restaurant_id < duplicate_id)
+);
+
+CREATE UNIQUE INDEX idx_duplicate_candidates_pair ON duplicate_candidates (restaurant_id, duplicate_id);
+CREATE INDEX idx_duplicate_candidates_duplicate_id ON duplicate_candidates (duplicate_id);
+CREATE INDEX idx_duplicate_candidates_status ON duplicate_candidates (status);
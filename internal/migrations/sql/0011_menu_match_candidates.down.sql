@@ -0,0 +1 @@
+DROP TABLE menu_match_candidates;
@@ -0,0 +1,17 @@
+-- New menu items that may be an existing item of the same restaurant under
+-- another name, queued for review when the names are too far apart to rename
+-- the existing item automatically.
+
+CREATE TABLE menu_match_candidates (
+    id           bigserial PRIMARY KEY,
+    menu_item_id bigint NOT NULL CONSTRAINT fk_menu_match_candidates_menu_item REFERENCES menu_items (id) ON DELETE CASCADE,
+    new_item_id  bigint NOT NULL CONSTRAINT fk_menu_match_candidates_new_item REFERENCES menu_items (id) ON DELETE CASCADE,
+    confidence   double precision NOT NULL,
+    status       varchar(20) NOT NULL DEFAULT 'pending',
+    created_at   timestamptz,
+    resolved_at  timestamptz
+);
+
+CREATE UNIQUE INDEX idx_menu_match_candidates_pair ON menu_match_candidates (menu_item_id, new_item_id);
+CREATE INDEX idx_menu_match_candidates_new_item_id ON menu_match_candidates (new_item_id);
+CREATE INDEX idx_menu_match_candidates_status ON menu_match_candidates (status);
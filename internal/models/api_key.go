@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type APIKey struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"not null;size:100" json:"name"`
+	Prefix       string     `gorm:"size:16;index" json:"prefix"`
+	KeyHash      string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	Scopes       string     `gorm:"size:255;not null" json:"scopes"`
+	RateLimit    int        `gorm:"default:0" json:"rate_limit"`
+	RequestCount int64      `gorm:"default:0" json:"request_count"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RevokedAt    *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ScopeList returns the key's scopes as a slice.
+func (k *APIKey) ScopeList() []string {
+	var scopes []string
+	for _, scope := range strings.Split(k.Scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// Active reports whether the key can currently be used.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// APIKeyUsage holds per-day request counters for an API key.
+type APIKeyUsage struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	APIKeyID    uint      `gorm:"not null;uniqueIndex:idx_api_key_usage_day" json:"api_key_id"`
+	Day         time.Time `gorm:"type:date;not null;uniqueIndex:idx_api_key_usage_day" json:"day"`
+	Requests    int64     `gorm:"not null;default:0" json:"requests"`
+	RateLimited int64     `gorm:"not null;default:0" json:"rate_limited"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Cuisine is an entry in the cuisine taxonomy. A restaurant can have several,
+// such as Japanese and Sushi. Slug is the stable name the cuisine filter
+// matches; Name is for display.
+type Cuisine struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Slug string `gorm:"uniqueIndex;not null;size:50" json:"slug" example:"japanese"`
+	Name string `gorm:"not null;size:100" json:"name" example:"Japanese"`
+}
+
+var cuisineSlugPattern = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// ValidCuisineSlug reports whether slug is lowercase words joined by
+// underscores, such as "middle_eastern".
+func ValidCuisineSlug(slug string) bool {
+	return len(slug) <= 50 && cuisineSlugPattern.MatchString(slug)
+}
+
+// CuisineKey normalizes a cuisine filter value so that "Middle Eastern",
+// "middle-eastern" and "middle_eastern" compare equal to the slug.
+func CuisineKey(value string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(value)))
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Sources a restaurant can be known to.
+const (
+	RestaurantSourceGooglePlaces = "google_places"
+	RestaurantSourceManual       = "manual"
+	RestaurantSourceSeed         = "seed"
+)
+
+// ExternalIDSource returns the source an external ID comes from, going by
+// the prefix that manual and seed IDs carry; anything else is a Places ID.
+func ExternalIDSource(externalID string) string {
+	switch {
+	case strings.HasPrefix(externalID, "manual:"):
+		return RestaurantSourceManual
+	case strings.HasPrefix(externalID, "seed:"):
+		return RestaurantSourceSeed
+	}
+	return RestaurantSourceGooglePlaces
+}
+
+// RestaurantSourceLink maps an ID that a source knows a restaurant by to the
+// restaurant, when it isn't the restaurant's own ExternalID: the IDs of
+// restaurants merged into it, or of the same place in another source.
+type RestaurantSourceLink struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	RestaurantID uint      `gorm:"not null;index" json:"restaurant_id"`
+	Source       string    `gorm:"not null;size:50;uniqueIndex:idx_restaurant_source_links_external_id" json:"source" example:"google_places"`
+	ExternalID   string    `gorm:"not null;size:255;uniqueIndex:idx_restaurant_source_links_external_id" json:"external_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Review states of a DuplicateCandidate or MenuMatchCandidate.
+const (
+	DuplicatePending   = "pending"
+	DuplicateMerged    = "merged"
+	DuplicateDismissed = "dismissed"
+)
+
+// ValidDuplicateStatus reports whether status is one of the Duplicate
+// constants.
+func ValidDuplicateStatus(status string) bool {
+	switch status {
+	case DuplicatePending, DuplicateMerged, DuplicateDismissed:
+		return true
+	}
+	return false
+}
+
+// DuplicateCandidate is a pair of restaurants that may be the same place,
+// queued for review. RestaurantID is the older of the two, which a merge
+// keeps unless told otherwise. Score, from 0 to 1, combines how alike the
+// names are, how close the restaurants are and whether their phone numbers
+// match; PhoneMatch is nil when either has no phone number.
+type DuplicateCandidate struct {
+	ID             uint        `gorm:"primaryKey" json:"id"`
+	RestaurantID   uint        `gorm:"not null;uniqueIndex:idx_duplicate_candidates_pair" json:"restaurant_id"`
+	Restaurant     *Restaurant `json:"restaurant,omitempty"`
+	DuplicateID    uint        `gorm:"not null;uniqueIndex:idx_duplicate_candidates_pair;index" json:"duplicate_id"`
+	Duplicate      *Restaurant `json:"duplicate,omitempty"`
+	Score          float64     `json:"score" example:"0.82"`
+	NameSimilarity float64     `json:"name_similarity" example:"0.9"`
+	DistanceMeters float64     `json:"distance_meters" example:"18.5"`
+	PhoneMatch     *bool       `json:"phone_match,omitempty"`
+	Status         string      `gorm:"not null;size:20;default:pending;index" json:"status" example:"pending"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ResolvedAt     *time.Time  `json:"resolved_at,omitempty"`
+}
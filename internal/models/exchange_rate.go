@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate is how many units of Currency one euro bought on Date. Rates
+// are kept for every day they were published so historical prices can be
+// converted at the rate of their day.
+type ExchangeRate struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	Currency  string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rate_day" json:"currency"`
+	Date      time.Time `gorm:"column:rate_date;type:date;not null;uniqueIndex:idx_exchange_rate_day" json:"date"`
+	Rate      float64   `gorm:"type:numeric(18,8);not null" json:"rate"`
+	Source    string    `gorm:"size:255" json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
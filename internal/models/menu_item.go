@@ -1,23 +1,59 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
+	"cheapeats-api/internal/money"
+
 	"gorm.io/gorm"
 )
 
+// MenuItem is a dish or drink on a restaurant's menu. OriginalPrice is only set
+// in responses where Price was converted to another currency. DealVariant is
+// only set in deal listings, when one of the item's variants is cheaper than
+// the item itself. EffectivePrice is only set in menu listings: the price
+// after the cheapest promo running at the requested time, which is set as
+// Promo. PriceHistory holds the item's own prices; those of its variants and
+// modifiers are on them. Diets and Allergens use the tags in menu_tags.go;
+// empty lists mean unknown, not none.
 type MenuItem struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`
-	RestaurantID uint           `gorm:"index" json:"restaurant_id"`
-	Restaurant   *Restaurant    `json:"restaurant,omitempty"`
-	Name         string         `gorm:"not null;size:255" json:"name"`
-	Description  string         `json:"description"`
-	Category     string         `gorm:"size:100" json:"category"`
-	Price        float64        `gorm:"not null;index" json:"price"`
-	Currency     string         `gorm:"default:USD;size:10" json:"currency"`
-	IsAvailable  bool           `gorm:"default:true" json:"is_available"`
-	PriceHistory []PriceHistory `gorm:"foreignKey:MenuItemID" json:"price_history,omitempty"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
-}
\ No newline at end of file
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	RestaurantID   uint              `gorm:"index" json:"restaurant_id"`
+	Restaurant     *Restaurant       `json:"restaurant,omitempty"`
+	Name           string            `gorm:"not null;size:255" json:"name"`
+	Description    string            `json:"description"`
+	Category       string            `gorm:"size:100" json:"category"`
+	Price          money.Money       `gorm:"embedded" json:"price" swaggertype:"string" example:"12.50"`
+	OriginalPrice  *money.Money      `gorm:"-" json:"original_price,omitempty" swaggertype:"string"`
+	IsAvailable    bool              `gorm:"default:true" json:"is_available"`
+	Diets          TagList           `gorm:"type:jsonb;not null" json:"diets" swaggertype:"array,string" example:"vegetarian,gluten_free"`
+	Allergens      TagList           `gorm:"type:jsonb;not null" json:"allergens" swaggertype:"array,string" example:"milk"`
+	SpiceLevel     *int              `json:"spice_level,omitempty" example:"1"`
+	Variants       []MenuItemVariant `gorm:"foreignKey:MenuItemID" json:"variants,omitempty"`
+	Modifiers      []Modifier        `gorm:"foreignKey:MenuItemID" json:"modifiers,omitempty"`
+	DealVariant    *MenuItemVariant  `gorm:"-" json:"deal_variant,omitempty"`
+	EffectivePrice *money.Money      `gorm:"-" json:"effective_price,omitempty" swaggertype:"string" example:"9.00"`
+	Promo          *PromoPrice       `gorm:"-" json:"promo,omitempty"`
+	PriceHistory   []PriceHistory    `gorm:"foreignKey:MenuItemID" json:"price_history,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// MarshalJSON adds the currencies next to the prices.
+func (m MenuItem) MarshalJSON() ([]byte, error) {
+	type menuItem MenuItem
+	return json.Marshal(struct {
+		menuItem
+		Currency         string `json:"currency"`
+		OriginalCurrency string `json:"original_currency,omitempty"`
+	}{menuItem(m), m.Price.Currency, currencyOf(m.OriginalPrice)})
+}
+
+func currencyOf(m *money.Money) string {
+	if m == nil {
+		return ""
+	}
+	return m.Currency
+}
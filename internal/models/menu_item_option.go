@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"cheapeats-api/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// MenuItemOption holds what variants and modifiers have in common: a name
+// and a price of their own, tracked in the price history like an item's.
+// OriginalPrice is only set in responses where Price was converted to
+// another currency.
+type MenuItemOption struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	MenuItemID    uint           `gorm:"index" json:"menu_item_id"`
+	Name          string         `gorm:"not null;size:100" json:"name"`
+	Price         money.Money    `gorm:"embedded" json:"price" swaggertype:"string" example:"4.50"`
+	OriginalPrice *money.Money   `gorm:"-" json:"original_price,omitempty" swaggertype:"string"`
+	IsAvailable   bool           `gorm:"default:true" json:"is_available"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PricedOption is a variant or a modifier.
+type PricedOption interface {
+	Option() *MenuItemOption
+	// NewPriceHistory returns the history entry for the option changing from
+	// previous to price; previous is nil for a new option.
+	NewPriceHistory(price money.Money, previous *money.Money, provenance PriceProvenance) PriceHistory
+}
+
+// MenuItemVariant is one way of ordering a menu item at its own price, such
+// as a size ("Small", "Large") or a combo. The item is sold at the price of
+// the variant chosen, so deals use the cheapest available one.
+type MenuItemVariant struct {
+	MenuItemOption
+	PriceHistory []PriceHistory `gorm:"foreignKey:VariantID" json:"price_history,omitempty"`
+}
+
+// Modifier is an optional add-on to a menu item, such as extra cheese, whose
+// price is paid on top of the item's.
+type Modifier struct {
+	MenuItemOption
+	PriceHistory []PriceHistory `gorm:"foreignKey:ModifierID" json:"price_history,omitempty"`
+}
+
+func (v *MenuItemVariant) Option() *MenuItemOption { return &v.MenuItemOption }
+
+func (m *Modifier) Option() *MenuItemOption { return &m.MenuItemOption }
+
+func (v *MenuItemVariant) NewPriceHistory(price money.Money, previous *money.Money, provenance PriceProvenance) PriceHistory {
+	entry := NewPriceHistory(v.MenuItemID, price, previous, provenance)
+	entry.VariantID = &v.ID
+	return entry
+}
+
+func (m *Modifier) NewPriceHistory(price money.Money, previous *money.Money, provenance PriceProvenance) PriceHistory {
+	entry := NewPriceHistory(m.MenuItemID, price, previous, provenance)
+	entry.ModifierID = &m.ID
+	return entry
+}
+
+// MarshalJSON adds the currencies next to the prices.
+func (v MenuItemVariant) MarshalJSON() ([]byte, error) {
+	type variant MenuItemVariant
+	return json.Marshal(struct {
+		variant
+		Currency         string `json:"currency"`
+		OriginalCurrency string `json:"original_currency,omitempty"`
+	}{variant(v), v.Price.Currency, currencyOf(v.OriginalPrice)})
+}
+
+// MarshalJSON adds the currencies next to the prices.
+func (m Modifier) MarshalJSON() ([]byte, error) {
+	type modifier Modifier
+	return json.Marshal(struct {
+		modifier
+		Currency         string `json:"currency"`
+		OriginalCurrency string `json:"original_currency,omitempty"`
+	}{modifier(m), m.Price.Currency, currencyOf(m.OriginalPrice)})
+}
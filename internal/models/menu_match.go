@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// MenuMatchCandidate is a new menu item that may be an existing item of the
+// same restaurant under another name, queued for review. Merging it keeps
+// MenuItemID, with its price history, under NewItemID's name. Confidence is
+// the match's score from 0 to 1. Status is one of the Duplicate constants.
+type MenuMatchCandidate struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	MenuItemID uint       `gorm:"not null;uniqueIndex:idx_menu_match_candidates_pair" json:"menu_item_id"`
+	MenuItem   *MenuItem  `json:"menu_item,omitempty"`
+	NewItemID  uint       `gorm:"not null;uniqueIndex:idx_menu_match_candidates_pair;index" json:"new_item_id"`
+	NewItem    *MenuItem  `json:"new_item,omitempty"`
+	Confidence float64    `json:"confidence" example:"0.81"`
+	Status     string     `gorm:"not null;size:20;default:pending;index" json:"status" example:"pending"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
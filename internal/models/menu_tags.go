@@ -0,0 +1,195 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diets a menu item can be suitable for.
+const (
+	DietVegetarian  = "vegetarian"
+	DietVegan       = "vegan"
+	DietPescatarian = "pescatarian"
+	DietGlutenFree  = "gluten_free"
+	DietDairyFree   = "dairy_free"
+	DietHalal       = "halal"
+	DietKosher      = "kosher"
+)
+
+// Allergens a menu item can contain, after the major food allergens of the
+// EU and US labelling rules.
+const (
+	AllergenPeanut    = "peanut"
+	AllergenTreeNut   = "tree_nut"
+	AllergenMilk      = "milk"
+	AllergenEgg       = "egg"
+	AllergenGluten    = "gluten"
+	AllergenSoy       = "soy"
+	AllergenFish      = "fish"
+	AllergenShellfish = "shellfish"
+	AllergenSesame    = "sesame"
+	AllergenMustard   = "mustard"
+	AllergenCelery    = "celery"
+	AllergenLupin     = "lupin"
+	AllergenSulphite  = "sulphite"
+)
+
+// MaxSpiceLevel is the hottest spice level; 0 is not spicy.
+const MaxSpiceLevel = 3
+
+var (
+	Diets     = []string{DietVegetarian, DietVegan, DietPescatarian, DietGlutenFree, DietDairyFree, DietHalal, DietKosher}
+	Allergens = []string{AllergenPeanut, AllergenTreeNut, AllergenMilk, AllergenEgg, AllergenGluten, AllergenSoy, AllergenFish, AllergenShellfish, AllergenSesame, AllergenMustard, AllergenCelery, AllergenLupin, AllergenSulphite}
+)
+
+// dietAliases maps other spellings, including schema.org's RestrictedDiet
+// names, to diets.
+var dietAliases = map[string]string{
+	"veg":            DietVegetarian,
+	"vegetariandiet": DietVegetarian,
+	"vegandiet":      DietVegan,
+	"pescetarian":    DietPescatarian,
+	"glutenfree":     DietGlutenFree,
+	"glutenfreediet": DietGlutenFree,
+	"gf":             DietGlutenFree,
+	"dairyfree":      DietDairyFree,
+	"halaldiet":      DietHalal,
+	"kosherdiet":     DietKosher,
+}
+
+var allergenAliases = map[string]string{
+	"peanuts":    AllergenPeanut,
+	"tree_nuts":  AllergenTreeNut,
+	"nuts":       AllergenTreeNut,
+	"nut":        AllergenTreeNut,
+	"dairy":      AllergenMilk,
+	"lactose":    AllergenMilk,
+	"eggs":       AllergenEgg,
+	"wheat":      AllergenGluten,
+	"soya":       AllergenSoy,
+	"crustacean": AllergenShellfish,
+	"mollusc":    AllergenShellfish,
+	"sulfite":    AllergenSulphite,
+	"sulphites":  AllergenSulphite,
+	"sulfites":   AllergenSulphite,
+}
+
+// dietImplies lists the diets an item suitable for a diet is also suitable
+// for.
+var dietImplies = map[string][]string{
+	DietVegan:      {DietVegetarian, DietPescatarian, DietDairyFree},
+	DietVegetarian: {DietPescatarian},
+}
+
+// TagList is a set of tags stored as a JSON array.
+type TagList []string
+
+func (t TagList) Value() (driver.Value, error) { return jsonValue(t, t == nil) }
+
+func (t *TagList) Scan(value interface{}) error { return scanJSON(value, t) }
+
+// MarshalJSON writes an empty list rather than null.
+func (t TagList) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(t))
+}
+
+// Contains reports whether the list has tag.
+func (t TagList) Contains(tag string) bool {
+	for _, have := range t {
+		if have == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// tagKey lowercases a tag and turns spaces and hyphens into underscores, so
+// that "Gluten-free" and "gluten free" become "gluten_free". Schema.org URLs
+// are reduced to their last path segment.
+func tagKey(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if i := strings.LastIndex(tag, "/"); i >= 0 {
+		tag = tag[i+1:]
+	}
+	tag = strings.ToLower(tag)
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(tag)
+}
+
+// ParseDiet returns the diet named by s, accepting aliases and schema.org
+// RestrictedDiet values such as "https://schema.org/VeganDiet".
+func ParseDiet(s string) (string, error) {
+	return parseTag(s, Diets, dietAliases, "diet")
+}
+
+// ParseAllergen returns the allergen named by s, accepting aliases such as
+// "peanuts" or "dairy".
+func ParseAllergen(s string) (string, error) {
+	return parseTag(s, Allergens, allergenAliases, "allergen")
+}
+
+func parseTag(s string, known []string, aliases map[string]string, kind string) (string, error) {
+	key := tagKey(s)
+	for _, tag := range known {
+		if key == tag {
+			return tag, nil
+		}
+	}
+	if tag, ok := aliases[key]; ok {
+		return tag, nil
+	}
+	if tag, ok := aliases[strings.ReplaceAll(key, "_", "")]; ok {
+		return tag, nil
+	}
+	return "", fmt.Errorf("unknown %s %q; use one of %s", kind, s, strings.Join(known, ", "))
+}
+
+// ParseDiets parses diets, adding the ones they imply (a vegan item is also
+// vegetarian), and returns them sorted without duplicates.
+func ParseDiets(values []string) (TagList, error) {
+	set := map[string]bool{}
+	for _, value := range values {
+		diet, err := ParseDiet(value)
+		if err != nil {
+			return nil, err
+		}
+		set[diet] = true
+		for _, implied := range dietImplies[diet] {
+			set[implied] = true
+		}
+	}
+	return sortedTags(set), nil
+}
+
+// ParseAllergens parses allergens and returns them sorted without
+// duplicates.
+func ParseAllergens(values []string) (TagList, error) {
+	set := map[string]bool{}
+	for _, value := range values {
+		allergen, err := ParseAllergen(value)
+		if err != nil {
+			return nil, err
+		}
+		set[allergen] = true
+	}
+	return sortedTags(set), nil
+}
+
+// ValidSpiceLevel reports whether level is between 0 and MaxSpiceLevel.
+func ValidSpiceLevel(level int) bool {
+	return level >= 0 && level <= MaxSpiceLevel
+}
+
+func sortedTags(set map[string]bool) TagList {
+	tags := make(TagList, 0, len(set))
+	for tag := range set {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,223 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// OpeningHours is a restaurant's weekly schedule, with exceptions for
+// special days such as holidays. All times are restaurant-local.
+type OpeningHours struct {
+	ID           uint           `gorm:"primaryKey" json:"-"`
+	RestaurantID uint           `gorm:"uniqueIndex" json:"-"`
+	Periods      OpeningPeriods `gorm:"type:jsonb;not null" json:"periods"`
+	SpecialDays  SpecialDays    `gorm:"type:jsonb;not null" json:"special_days"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// OpeningPeriod is a span of the week during which a restaurant is open.
+// Close is nil for a restaurant that never closes.
+type OpeningPeriod struct {
+	Open  WeekTime  `json:"open"`
+	Close *WeekTime `json:"close,omitempty"`
+}
+
+// WeekTime is a time of the week. Day counts from Sunday (0) to Saturday
+// (6), like time.Weekday, and Time is "HH:MM".
+type WeekTime struct {
+	Day  int    `json:"day" example:"1"`
+	Time string `json:"time" example:"11:30"`
+}
+
+// SpecialDay replaces the weekly schedule on one date ("YYYY-MM-DD"). No
+// hours means closed all day.
+type SpecialDay struct {
+	Date  string      `json:"date" example:"2026-12-25"`
+	Hours []TimeRange `json:"hours"`
+}
+
+// TimeRange is an opening span within a day, "HH:MM" to "HH:MM". A close
+// time not after the open time runs past midnight.
+type TimeRange struct {
+	Open  string `json:"open" example:"11:30"`
+	Close string `json:"close" example:"15:00"`
+}
+
+type OpeningPeriods []OpeningPeriod
+
+type SpecialDays []SpecialDay
+
+func (p OpeningPeriods) Value() (driver.Value, error) { return jsonValue(p, p == nil) }
+
+func (p *OpeningPeriods) Scan(value interface{}) error { return scanJSON(value, p) }
+
+func (d SpecialDays) Value() (driver.Value, error) { return jsonValue(d, d == nil) }
+
+func (d *SpecialDays) Scan(value interface{}) error { return scanJSON(value, d) }
+
+func jsonValue(v interface{}, empty bool) (driver.Value, error) {
+	if empty {
+		return "[]", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func scanJSON(value interface{}, dst interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), dst)
+	case []byte:
+		return json.Unmarshal(data, dst)
+	}
+	return fmt.Errorf("cannot scan %T into %T", value, dst)
+}
+
+// ParseClock parses "HH:MM" (or "HHMM", as Places writes it) into minutes
+// after midnight. "24:00" is accepted as the end of the day.
+func ParseClock(clock string) (int, error) {
+	layout := "15:04"
+	if len(clock) == 4 {
+		layout = "1504"
+	}
+	if clock == "24:00" || clock == "2400" {
+		return minutesPerDay, nil
+	}
+	t, err := time.Parse(layout, clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate checks days, dates and times.
+func (h *OpeningHours) Validate() error {
+	for _, period := range h.Periods {
+		for _, at := range []*WeekTime{&period.Open, period.Close} {
+			if at == nil {
+				continue
+			}
+			if at.Day < 0 || at.Day > 6 {
+				return errors.New("period days must be between 0 (Sunday) and 6 (Saturday)")
+			}
+			if _, err := ParseClock(at.Time); err != nil {
+				return err
+			}
+		}
+	}
+	for _, day := range h.SpecialDays {
+		if _, err := time.Parse(time.DateOnly, day.Date); err != nil {
+			return fmt.Errorf("invalid special day date %q, want YYYY-MM-DD", day.Date)
+		}
+		for _, hours := range day.Hours {
+			if _, err := ParseClock(hours.Open); err != nil {
+				return err
+			}
+			if _, err := ParseClock(hours.Close); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IsOpenAt reports whether the schedule is open at t, which must already be
+// in the restaurant's time zone.
+func (h *OpeningHours) IsOpenAt(t time.Time) bool {
+	now := t.Hour()*60 + t.Minute()
+
+	// A special day decides its own date, and the early hours of the next
+	// one when its last span runs past midnight.
+	if day, ok := h.specialDay(t); ok {
+		for _, hours := range day.Hours {
+			open, close, ok := hours.minutes()
+			if ok && now >= open && (close <= open || now < close) {
+				return true
+			}
+		}
+	}
+	if day, ok := h.specialDay(t.AddDate(0, 0, -1)); ok {
+		for _, hours := range day.Hours {
+			open, close, ok := hours.minutes()
+			if ok && close <= open && now < close {
+				return true
+			}
+		}
+	}
+
+	minute := int(t.Weekday())*minutesPerDay + now
+	for _, period := range h.Periods {
+		open, close, ok := period.minutes()
+		if !ok {
+			continue
+		}
+		// Match the period both at this week's position and a week later,
+		// for periods that wrap from Saturday into Sunday.
+		for _, m := range []int{minute, minute + minutesPerWeek} {
+			if m < open || m >= close {
+				continue
+			}
+			// Periods opening on a special day are replaced by it.
+			opened := t.Add(-time.Duration(m-open) * time.Minute)
+			if _, special := h.specialDay(opened); !special {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (h *OpeningHours) specialDay(t time.Time) (SpecialDay, bool) {
+	date := t.Format(time.DateOnly)
+	for _, day := range h.SpecialDays {
+		if day.Date == date {
+			return day, true
+		}
+	}
+	return SpecialDay{}, false
+}
+
+// minutes returns the period's open and close as minutes of the week, with
+// close after open.
+func (p OpeningPeriod) minutes() (int, int, bool) {
+	open, err := ParseClock(p.Open.Time)
+	if err != nil {
+		return 0, 0, false
+	}
+	open += p.Open.Day * minutesPerDay
+	if p.Close == nil {
+		return open, open + minutesPerWeek, true
+	}
+
+	close, err := ParseClock(p.Close.Time)
+	if err != nil {
+		return 0, 0, false
+	}
+	close += p.Close.Day * minutesPerDay
+	if close <= open {
+		close += minutesPerWeek
+	}
+	return open, close, true
+}
+
+func (r TimeRange) minutes() (int, int, bool) {
+	open, err := ParseClock(r.Open)
+	if err != nil {
+		return 0, 0, false
+	}
+	close, err := ParseClock(r.Close)
+	if err != nil {
+		return 0, 0, false
+	}
+	return open, close, true
+}
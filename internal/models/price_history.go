@@ -1,13 +1,89 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"cheapeats-api/internal/money"
+
+	"gorm.io/gorm"
 )
 
+// Sources a recorded price can come from.
+const (
+	PriceSourceGooglePlaces  = "google_places"
+	PriceSourceWebsiteJSONLD = "website_jsonld"
+	PriceSourceManual        = "manual"
+	PriceSourceImport        = "import"
+)
+
+// ValidPriceSource reports whether source is one of the PriceSource
+// constants.
+func ValidPriceSource(source string) bool {
+	switch source {
+	case PriceSourceGooglePlaces, PriceSourceWebsiteJSONLD, PriceSourceManual, PriceSourceImport:
+		return true
+	}
+	return false
+}
+
+// PriceProvenance says where a price being recorded came from.
+// ScrapedDataID links to the raw data it was read from, if any.
+type PriceProvenance struct {
+	Source        string
+	ScrapedDataID *uint
+}
+
+// PriceHistory records a menu item's price at a point in time, where it came
+// from and what it replaced. Entries for one of the item's variants or
+// modifiers have VariantID or ModifierID set. PreviousPrice is nil for the
+// first price.
+// OriginalPrice is only set in responses where Price was converted to
+// another currency.
 type PriceHistory struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	MenuItemID uint      `gorm:"index" json:"menu_item_id"`
-	MenuItem   *MenuItem `json:"menu_item,omitempty"`
-	Price      float64   `gorm:"not null" json:"price"`
-	RecordedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"recorded_at"`
-}
\ No newline at end of file
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	MenuItemID    uint         `gorm:"index" json:"menu_item_id"`
+	MenuItem      *MenuItem    `json:"menu_item,omitempty"`
+	VariantID     *uint        `gorm:"index" json:"variant_id,omitempty"`
+	ModifierID    *uint        `gorm:"index" json:"modifier_id,omitempty"`
+	Price         money.Money  `gorm:"embedded" json:"price" swaggertype:"string" example:"12.50"`
+	OriginalPrice *money.Money `gorm:"-" json:"original_price,omitempty" swaggertype:"string"`
+	PreviousPrice *money.Money `gorm:"embedded;embeddedPrefix:previous_" json:"previous_price,omitempty" swaggertype:"string" example:"11.95"`
+	Source        string       `gorm:"size:50;not null;index" json:"source" example:"google_places"`
+	ScrapedDataID *uint        `gorm:"index" json:"scraped_data_id,omitempty"`
+	ScrapedData   *ScrapedData `json:"-"`
+	RecordedAt    time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"recorded_at"`
+}
+
+// NewPriceHistory returns the history entry for a menu item changing from
+// previous to price; previous is nil for a new item.
+func NewPriceHistory(menuItemID uint, price money.Money, previous *money.Money, provenance PriceProvenance) PriceHistory {
+	return PriceHistory{
+		MenuItemID:    menuItemID,
+		Price:         price,
+		PreviousPrice: previous,
+		Source:        provenance.Source,
+		ScrapedDataID: provenance.ScrapedDataID,
+		RecordedAt:    time.Now(),
+	}
+}
+
+// AfterFind clears PreviousPrice when its columns are NULL; GORM always
+// allocates embedded pointers when scanning.
+func (p *PriceHistory) AfterFind(tx *gorm.DB) error {
+	if p.PreviousPrice != nil && p.PreviousPrice.Currency == "" {
+		p.PreviousPrice = nil
+	}
+	return nil
+}
+
+// MarshalJSON adds the currencies next to the prices.
+func (p PriceHistory) MarshalJSON() ([]byte, error) {
+	type priceHistory PriceHistory
+	return json.Marshal(struct {
+		priceHistory
+		Currency         string `json:"currency"`
+		OriginalCurrency string `json:"original_currency,omitempty"`
+		PreviousCurrency string `json:"previous_currency,omitempty"`
+	}{priceHistory(p), p.Price.Currency, currencyOf(p.OriginalPrice), currencyOf(p.PreviousPrice)})
+}
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"cheapeats-api/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// PromoPrice is a time-limited deal such as a happy hour or a lunch special.
+// It sets a fixed Price for one menu item, or takes DiscountPercent off one
+// item or, without MenuItemID, every item of the restaurant.
+//
+// It applies on Days (every day when empty), between StartTime and EndTime
+// ("HH:MM", all day when empty; an end not after the start runs past
+// midnight), from StartsOn to EndsOn inclusive when set. Days and dates are
+// those the window starts on, in the restaurant's time zone.
+//
+// OriginalPrice is only set in responses where Price was converted to
+// another currency.
+type PromoPrice struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	RestaurantID    uint           `gorm:"index" json:"restaurant_id"`
+	Restaurant      *Restaurant    `json:"-"`
+	MenuItemID      *uint          `gorm:"index" json:"menu_item_id,omitempty"`
+	Name            string         `gorm:"not null;size:100" json:"name" example:"Happy hour"`
+	Price           *money.Money   `gorm:"embedded" json:"price,omitempty" swaggertype:"string" example:"5.00"`
+	OriginalPrice   *money.Money   `gorm:"-" json:"original_price,omitempty" swaggertype:"string"`
+	DiscountPercent *int           `json:"discount_percent,omitempty" example:"25"`
+	Days            Weekdays       `gorm:"type:jsonb;not null" json:"days"`
+	StartTime       string         `gorm:"size:5" json:"start_time,omitempty" example:"16:00"`
+	EndTime         string         `gorm:"size:5" json:"end_time,omitempty" example:"18:00"`
+	StartsOn        *time.Time     `gorm:"type:date" json:"starts_on,omitempty" swaggertype:"string" example:"2026-10-01"`
+	EndsOn          *time.Time     `gorm:"type:date" json:"ends_on,omitempty" swaggertype:"string" example:"2026-12-31"`
+	IsActive        bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Weekdays are days of the week, from Sunday (0) to Saturday (6).
+type Weekdays []int
+
+func (d Weekdays) Value() (driver.Value, error) { return jsonValue(d, d == nil) }
+
+func (d *Weekdays) Scan(value interface{}) error { return scanJSON(value, d) }
+
+// AfterFind clears Price when its columns are NULL; GORM always allocates
+// embedded pointers when scanning.
+func (p *PromoPrice) AfterFind(tx *gorm.DB) error {
+	if p.Price != nil && p.Price.Currency == "" {
+		p.Price = nil
+	}
+	return nil
+}
+
+// MarshalJSON writes the dates as "YYYY-MM-DD" and adds the currencies next
+// to the prices.
+func (p PromoPrice) MarshalJSON() ([]byte, error) {
+	type promoPrice PromoPrice
+	return json.Marshal(struct {
+		promoPrice
+		Currency         string `json:"currency,omitempty"`
+		OriginalCurrency string `json:"original_currency,omitempty"`
+		StartsOn         string `json:"starts_on,omitempty"`
+		EndsOn           string `json:"ends_on,omitempty"`
+	}{promoPrice(p), currencyOf(p.Price), currencyOf(p.OriginalPrice), formatDate(p.StartsOn), formatDate(p.EndsOn)})
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+// Validate checks that the promo sets exactly one kind of discount and that
+// its days, times and dates make sense.
+func (p *PromoPrice) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	switch {
+	case p.Price != nil && p.DiscountPercent != nil:
+		return errors.New("set either price or discount_percent, not both")
+	case p.Price != nil:
+		if p.MenuItemID == nil {
+			return errors.New("a fixed price needs a menu_item_id; use discount_percent for the whole menu")
+		}
+		if p.Price.IsNegative() {
+			return errors.New("price must not be negative")
+		}
+	case p.DiscountPercent != nil:
+		if *p.DiscountPercent < 1 || *p.DiscountPercent > 100 {
+			return errors.New("discount_percent must be between 1 and 100")
+		}
+	default:
+		return errors.New("price or discount_percent is required")
+	}
+
+	for _, day := range p.Days {
+		if day < 0 || day > 6 {
+			return errors.New("days must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	if (p.StartTime == "") != (p.EndTime == "") {
+		return errors.New("start_time and end_time must be set together")
+	}
+	if p.StartTime != "" {
+		if _, err := ParseClock(p.StartTime); err != nil {
+			return err
+		}
+		if _, err := ParseClock(p.EndTime); err != nil {
+			return err
+		}
+	}
+	if p.StartsOn != nil && p.EndsOn != nil && p.EndsOn.Before(*p.StartsOn) {
+		return errors.New("ends_on must not be before starts_on")
+	}
+	return nil
+}
+
+// AppliesTo reports whether the promo covers the item.
+func (p *PromoPrice) AppliesTo(item *MenuItem) bool {
+	if p.MenuItemID != nil {
+		return *p.MenuItemID == item.ID
+	}
+	return p.RestaurantID == item.RestaurantID
+}
+
+// ActiveAt reports whether the promo applies at t, which must already be in
+// the restaurant's time zone.
+func (p *PromoPrice) ActiveAt(t time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+
+	day := t
+	if p.StartTime != "" {
+		start, end, ok := TimeRange{Open: p.StartTime, Close: p.EndTime}.minutes()
+		if !ok {
+			return false
+		}
+		now := t.Hour()*60 + t.Minute()
+		switch {
+		case end > start && now >= start && now < end:
+		case end <= start && now >= start:
+		case end <= start && now < end:
+			day = t.AddDate(0, 0, -1)
+		default:
+			return false
+		}
+	}
+
+	if len(p.Days) > 0 && !containsDay(p.Days, int(day.Weekday())) {
+		return false
+	}
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	if p.StartsOn != nil && date.Before(*p.StartsOn) {
+		return false
+	}
+	if p.EndsOn != nil && date.After(*p.EndsOn) {
+		return false
+	}
+	return true
+}
+
+// Apply returns what price costs under the promo, and false when the promo
+// can't be applied to it, such as a fixed price in another currency.
+func (p *PromoPrice) Apply(price money.Money) (money.Money, bool) {
+	switch {
+	case p.Price != nil:
+		return *p.Price, p.Price.Currency == price.Currency
+	case p.DiscountPercent != nil:
+		return price.Percent(int64(100 - *p.DiscountPercent)), true
+	}
+	return price, false
+}
+
+// EffectivePrice returns what the item costs at t, in the restaurant's time
+// zone, with the cheapest of the promos that apply, and that promo. It
+// returns the item's price and nil when none applies.
+func EffectivePrice(item *MenuItem, promos []PromoPrice, t time.Time) (money.Money, *PromoPrice) {
+	best, chosen := item.Price, (*PromoPrice)(nil)
+	for i := range promos {
+		promo := &promos[i]
+		if !promo.AppliesTo(item) || !promo.ActiveAt(t) {
+			continue
+		}
+		if price, ok := promo.Apply(item.Price); ok && price.Amount < best.Amount {
+			best, chosen = price, promo
+		}
+	}
+	return best, chosen
+}
+
+func containsDay(days Weekdays, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -7,23 +7,65 @@ import (
 )
 
 type Restaurant struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	ExternalID  string         `gorm:"uniqueIndex;size:255" json:"external_id"`
-	Name        string         `gorm:"not null;size:255" json:"name"`
-	Address     string         `json:"address"`
-	City        string         `gorm:"size:100" json:"city"`
-	State       string         `gorm:"size:50" json:"state"`
-	ZipCode     string         `gorm:"size:20" json:"zip_code"`
-	Country     string         `gorm:"size:100" json:"country"`
-	Latitude    float64        `gorm:"index:idx_location" json:"latitude"`
-	Longitude   float64        `gorm:"index:idx_location" json:"longitude"`
-	CuisineType string         `gorm:"size:100" json:"cuisine_type"`
-	Phone       string         `gorm:"size:50" json:"phone"`
-	Website     string         `gorm:"size:255" json:"website"`
-	Rating      float32        `json:"rating"`
-	PriceRange  string         `gorm:"size:10" json:"price_range"`
-	MenuItems   []MenuItem     `gorm:"foreignKey:RestaurantID" json:"menu_items,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-}
\ No newline at end of file
+	ID         uint    `gorm:"primaryKey" json:"id"`
+	ExternalID string  `gorm:"uniqueIndex;size:255" json:"external_id"`
+	Name       string  `gorm:"not null;size:255" json:"name"`
+	Address    string  `json:"address"`
+	City       string  `gorm:"size:100" json:"city"`
+	State      string  `gorm:"size:50" json:"state"`
+	ZipCode    string  `gorm:"size:20" json:"zip_code"`
+	Country    string  `gorm:"size:100" json:"country"`
+	Latitude   float64 `gorm:"index:idx_location" json:"latitude"`
+	Longitude  float64 `gorm:"index:idx_location" json:"longitude"`
+	// CuisineType is the name of the restaurant's main cuisine, the first
+	// of Cuisines.
+	CuisineType string  `gorm:"size:100" json:"cuisine_type"`
+	Phone       string  `gorm:"size:50" json:"phone"`
+	Website     string  `gorm:"size:255" json:"website"`
+	Rating      float32 `json:"rating"`
+	PriceRange  string  `gorm:"size:10" json:"price_range"`
+	// TimeZone is the IANA name of the restaurant's time zone. Without one,
+	// UTCOffsetMinutes, as last reported by Places, stands in for it.
+	TimeZone         string                 `gorm:"size:64" json:"time_zone,omitempty" example:"America/New_York"`
+	UTCOffsetMinutes *int                   `json:"utc_offset_minutes,omitempty" example:"-240"`
+	OpeningHours     *OpeningHours          `gorm:"foreignKey:RestaurantID" json:"opening_hours,omitempty"`
+	Cuisines         []Cuisine              `gorm:"many2many:restaurant_cuisines" json:"cuisines,omitempty"`
+	MenuItems        []MenuItem             `gorm:"foreignKey:RestaurantID" json:"menu_items,omitempty"`
+	SourceLinks      []RestaurantSourceLink `gorm:"foreignKey:RestaurantID" json:"source_links,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt         `gorm:"index" json:"-"`
+}
+
+// Location returns the restaurant's time zone, falling back to its UTC
+// offset and then to UTC.
+func (r *Restaurant) Location() *time.Location {
+	if r.TimeZone != "" {
+		if loc, err := time.LoadLocation(r.TimeZone); err == nil {
+			return loc
+		}
+	}
+	if r.UTCOffsetMinutes != nil {
+		return time.FixedZone("", *r.UTCOffsetMinutes*60)
+	}
+	return time.UTC
+}
+
+// LocalTime returns t in the restaurant's time zone. With wallClock set,
+// t's date and clock time are kept and read in that zone instead.
+func (r *Restaurant) LocalTime(t time.Time, wallClock bool) time.Time {
+	loc := r.Location()
+	if wallClock {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+	}
+	return t.In(loc)
+}
+
+// IsOpenAt reports whether the restaurant is open at t. Restaurants whose
+// opening hours aren't known are never open.
+func (r *Restaurant) IsOpenAt(t time.Time) bool {
+	if r.OpeningHours == nil {
+		return false
+	}
+	return r.OpeningHours.IsOpenAt(t.In(r.Location()))
+}
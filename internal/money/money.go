@@ -0,0 +1,239 @@
+// Package money represents prices exactly, as an integer amount in a given
+// ISO 4217 currency, instead of as floats.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places an Amount is held to. It covers
+// every ISO 4217 minor unit and matches the numeric(12,4) price columns.
+const Scale = 4
+
+const scaleFactor = 10000
+
+// DefaultCurrency is assumed when no currency is given.
+const DefaultCurrency = "USD"
+
+var (
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrInvalidCurrency = errors.New("currency must be a three-letter ISO 4217 code")
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// exponents lists the ISO 4217 currencies whose minor unit isn't a hundredth.
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 code.
+func ValidCurrency(code string) bool {
+	return currencyPattern.MatchString(code)
+}
+
+// Exponent returns the number of decimal places of a currency's minor unit.
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// NormalizeCurrency upper-cases a currency code and falls back to
+// DefaultCurrency when it is empty.
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// Amount is a decimal amount in ten-thousandths of a major unit. It is
+// stored in numeric columns.
+type Amount int64
+
+// Scan implements sql.Scanner for numeric columns.
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.parse(string(v))
+	case string:
+		return a.parse(v)
+	case int64:
+		*a = Amount(v * scaleFactor)
+		return nil
+	case float64:
+		*a = Amount(math.Round(v * scaleFactor))
+		return nil
+	}
+	return fmt.Errorf("money: cannot scan %T into Amount", value)
+}
+
+func (a *Amount) parse(s string) error {
+	units, err := parseDecimal(s, Scale)
+	if err != nil {
+		return err
+	}
+	*a = Amount(units)
+	return nil
+}
+
+// Value implements driver.Valuer, writing the amount as a decimal string so
+// no precision is lost on the way to Postgres.
+func (a Amount) Value() (driver.Value, error) {
+	return formatDecimal(int64(a), Scale), nil
+}
+
+// Money is an amount of a currency, always a whole number of the currency's
+// minor units. Its fields map onto a price and a currency column; embed it
+// with an embeddedPrefix to store more than one amount in a table.
+type Money struct {
+	Amount   Amount `gorm:"column:price;type:numeric(12,4);not null"`
+	Currency string `gorm:"column:currency;size:3;not null"`
+}
+
+// New returns an amount given in minor units, e.g. cents.
+func New(minor int64, currency string) Money {
+	currency = NormalizeCurrency(currency)
+	return Money{Amount: Amount(minor * pow10(Scale-Exponent(currency))), Currency: currency}
+}
+
+// Parse reads a decimal string such as "12.5" and rounds it half away from
+// zero to the currency's minor unit.
+func Parse(s, currency string) (Money, error) {
+	currency = NormalizeCurrency(currency)
+	if !ValidCurrency(currency) {
+		return Money{}, ErrInvalidCurrency
+	}
+	minor, err := parseDecimal(s, Exponent(currency))
+	if err != nil {
+		return Money{}, err
+	}
+	return New(minor, currency), nil
+}
+
+// FromFloat rounds f half away from zero to the currency's minor unit. Use
+// Parse when the amount is available as text.
+func FromFloat(f float64, currency string) Money {
+	currency = NormalizeCurrency(currency)
+	minor := math.Round(f * float64(pow10(Exponent(currency))))
+	return New(int64(minor), currency)
+}
+
+// Minor returns the amount in the currency's minor units.
+func (m Money) Minor() int64 {
+	return int64(m.Amount) / pow10(Scale-Exponent(m.Currency))
+}
+
+// Float64 returns the amount in major units, for statistics and sorting.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / scaleFactor
+}
+
+// String formats the amount with as many decimals as the currency's minor
+// unit, e.g. "12.50" for USD and "1200" for JPY.
+func (m Money) String() string {
+	return formatDecimal(m.Minor(), Exponent(m.Currency))
+}
+
+// Equal reports whether m and other are the same amount of the same
+// currency.
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.Currency == other.Currency
+}
+
+// IsNegative reports whether the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// MarshalJSON writes the amount as a decimal string, e.g. "12.50", so that
+// clients don't have to deal with float rounding.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func parseDecimal(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return 0, ErrInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	roundUp := false
+	if len(frac) > scale {
+		roundUp = frac[scale] >= '5'
+		frac = frac[:scale]
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || units > math.MaxInt64/scaleFactor {
+		return 0, ErrInvalidAmount
+	}
+	if roundUp {
+		units++
+	}
+	if negative {
+		units = -units
+	}
+	return units, nil
+}
+
+func formatDecimal(units int64, scale int) string {
+	sign := ""
+	if units < 0 {
+		sign, units = "-", -units
+	}
+	digits := strconv.FormatInt(units, 10)
+	if scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// Percent returns percent per cent of m, rounded half away from zero to the
+// currency's minor unit.
+func (m Money) Percent(percent int64) Money {
+	scaled := m.Minor() * percent
+	minor := scaled / 100
+	if rest := scaled % 100; rest >= 50 {
+		minor++
+	} else if rest <= -50 {
+		minor--
+	}
+	return New(minor, m.Currency)
+}
@@ -0,0 +1,122 @@
+package money
+
+import "testing"
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in    string
+		scale int
+		want  int64
+	}{
+		{"12.5", 2, 1250},
+		{"12.50", 2, 1250},
+		{" 12 ", 2, 1200},
+		{".5", 2, 50},
+		{"7.", 2, 700},
+		{"+3.25", 2, 325},
+		{"12.345", 2, 1235},
+		{"12.344", 2, 1234},
+		{"12.3449", 2, 1234},
+		{"0.005", 2, 1},
+		{"-12.345", 2, -1235},
+		{"-0.004", 2, 0},
+		{"1199.5", 0, 1200},
+		{"1199.4", 0, 1199},
+		{"1.2345", 3, 1235},
+		{"1.5", 3, 1500},
+		{"0.00005", 4, 1},
+	}
+	for _, tt := range tests {
+		got, err := parseDecimal(tt.in, tt.scale)
+		if err != nil {
+			t.Errorf("parseDecimal(%q, %d) error = %v", tt.in, tt.scale, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDecimal(%q, %d) = %d, want %d", tt.in, tt.scale, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", ".", "-", "abc", "1,50", "1.2.3", "1e3", "--1", "99999999999999999999"} {
+		if _, err := parseDecimal(in, 2); err != ErrInvalidAmount {
+			t.Errorf("parseDecimal(%q) error = %v, want ErrInvalidAmount", in, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     string
+		minor    int64
+	}{
+		{"12.5", "USD", "12.50", 1250},
+		{"12.5", "", "12.50", 1250},
+		{"12.5", "eur", "12.50", 1250},
+		{"1200", "JPY", "1200", 1200},
+		{"1199.5", "JPY", "1200", 1200},
+		{"0.4", "JPY", "0", 0},
+		{"1.2345", "KWD", "1.235", 1235},
+		{"3", "BHD", "3.000", 3000},
+		{"0.1", "CLF", "0.1000", 1000},
+		{"-2.005", "USD", "-2.01", -201},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.currency)
+		if err != nil {
+			t.Errorf("Parse(%q, %q) error = %v", tt.in, tt.currency, err)
+			continue
+		}
+		if got.String() != tt.want || got.Minor() != tt.minor {
+			t.Errorf("Parse(%q, %q) = %s (%d minor), want %s (%d minor)", tt.in, tt.currency, got, got.Minor(), tt.want, tt.minor)
+		}
+	}
+
+	if _, err := Parse("1", "US"); err != ErrInvalidCurrency {
+		t.Errorf("Parse with currency US error = %v, want ErrInvalidCurrency", err)
+	}
+}
+
+func TestMoneyAcrossExponents(t *testing.T) {
+	usd, jpy, kwd := New(1250, "USD"), New(1250, "JPY"), New(1250, "KWD")
+	if !usd.Equal(FromFloat(12.5, "USD")) || !jpy.Equal(FromFloat(1250, "JPY")) || !kwd.Equal(FromFloat(1.25, "KWD")) {
+		t.Errorf("FromFloat doesn't match New for USD, JPY or KWD")
+	}
+	if usd.Float64() != 12.5 || jpy.Float64() != 1250 || kwd.Float64() != 1.25 {
+		t.Errorf("Float64() = %v, %v, %v, want 12.5, 1250, 1.25", usd.Float64(), jpy.Float64(), kwd.Float64())
+	}
+	if usd.Equal(New(1250, "EUR")) {
+		t.Error("Equal() ignores the currency")
+	}
+
+	data, err := kwd.MarshalJSON()
+	if err != nil || string(data) != `"1.250"` {
+		t.Errorf("MarshalJSON() = %s, %v, want \"1.250\"", data, err)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		price   Money
+		percent int64
+		want    string
+	}{
+		{New(1200, "USD"), 75, "9.00"},
+		{New(999, "USD"), 50, "5.00"},
+		{New(999, "USD"), 10, "1.00"},
+		{New(1001, "USD"), 25, "2.50"},
+		{New(1003, "USD"), 50, "5.02"},
+		{New(1050, "USD"), 0, "0.00"},
+		{New(1050, "USD"), 100, "10.50"},
+		{New(-1003, "USD"), 50, "-5.02"},
+		{New(1999, "JPY"), 15, "300"},
+		{New(1001, "KWD"), 50, "0.501"},
+	}
+	for _, tt := range tests {
+		got := tt.price.Percent(tt.percent)
+		if got.String() != tt.want || got.Currency != tt.price.Currency {
+			t.Errorf("%s %s.Percent(%d) = %s %s, want %s", tt.price, tt.price.Currency, tt.percent, got, got.Currency, tt.want)
+		}
+	}
+}
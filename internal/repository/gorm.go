@@ -0,0 +1,1117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const earthRadiusKm = 6371.0
+
+// NewGormRepositories returns repositories backed by db.
+func NewGormRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Restaurants:   &GormRestaurantRepository{db: db},
+		Menus:         &GormMenuRepository{db: db},
+		PriceHistory:  &GormPriceHistoryRepository{db: db},
+		Promos:        &GormPromoRepository{db: db},
+		Scrapes:       &GormScrapeRepository{db: db},
+		Duplicates:    &GormDuplicateRepository{db: db},
+		MenuMatches:   &GormMenuMatchRepository{db: db},
+		ExchangeRates: &GormExchangeRateRepository{db: db},
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// duplicate translates a unique constraint violation into ErrDuplicate.
+func duplicate(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrDuplicate
+	}
+	return err
+}
+
+type GormRestaurantRepository struct {
+	db *gorm.DB
+}
+
+func (r *GormRestaurantRepository) List(ctx context.Context, filter RestaurantFilter) ([]models.Restaurant, error) {
+	query := whereRestaurant(r.db.WithContext(ctx).Model(&models.Restaurant{}), filter)
+
+	var restaurants []models.Restaurant
+	if err := query.Preload("OpeningHours").Preload("Cuisines").Find(&restaurants).Error; err != nil {
+		return nil, err
+	}
+	return filterOpen(restaurants, filter.OpenAt), nil
+}
+
+// whereRestaurant applies the filters that can be done in SQL; OpenAt is
+// left to filterOpen.
+func whereRestaurant(query *gorm.DB, filter RestaurantFilter) *gorm.DB {
+	if filter.City != "" {
+		query = query.Where("city = ?", filter.City)
+	}
+	if len(filter.Cuisines) > 0 {
+		keys := make([]string, len(filter.Cuisines))
+		for i, cuisine := range filter.Cuisines {
+			keys[i] = models.CuisineKey(cuisine)
+		}
+		query = query.Where(`EXISTS (SELECT 1 FROM restaurant_cuisines
+			JOIN cuisines ON cuisines.id = restaurant_cuisines.cuisine_id
+			WHERE restaurant_cuisines.restaurant_id = restaurants.id
+				AND cuisines.slug IN ?)`, keys)
+	}
+	if filter.PriceRange != "" {
+		query = query.Where("price_range = ?", filter.PriceRange)
+	}
+	if condition, args := dietCondition(filter.Diet); condition != "" {
+		query = query.Where(`EXISTS (SELECT 1 FROM menu_items
+			WHERE menu_items.restaurant_id = restaurants.id AND menu_items.is_available
+				AND menu_items.deleted_at IS NULL AND `+condition+`)`, args...)
+	}
+	return query
+}
+
+// dietCondition returns the SQL condition on menu_items for filter, or ""
+// when it doesn't filter.
+func dietCondition(filter DietFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if len(filter.Diets) > 0 {
+		conditions = append(conditions, "menu_items.diets @> ?")
+		args = append(args, models.TagList(filter.Diets))
+	}
+	for _, allergen := range filter.ExcludeAllergens {
+		conditions = append(conditions, "NOT menu_items.allergens @> ?")
+		args = append(args, models.TagList{allergen})
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func whereDiet(query *gorm.DB, filter DietFilter) *gorm.DB {
+	if condition, args := dietCondition(filter); condition != "" {
+		query = query.Where(condition, args...)
+	}
+	return query
+}
+
+func (r *GormRestaurantRepository) Get(ctx context.Context, id uint) (*models.Restaurant, error) {
+	var restaurant models.Restaurant
+	if err := r.db.WithContext(ctx).Preload("OpeningHours").Preload("Cuisines").Preload("MenuItems").Preload("SourceLinks").First(&restaurant, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &restaurant, nil
+}
+
+func (r *GormRestaurantRepository) GetByExternalID(ctx context.Context, externalID string) (*models.Restaurant, error) {
+	db := r.db.WithContext(ctx)
+	linked := db.Model(&models.RestaurantSourceLink{}).Select("restaurant_id").Where("external_id = ?", externalID)
+
+	var restaurant models.Restaurant
+	err := db.Unscoped().Where("id IN (?)", linked).First(&restaurant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = db.Unscoped().Where("external_id = ?", externalID).First(&restaurant).Error
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &restaurant, nil
+}
+
+// distanceKm is the great-circle distance of a restaurant from a point. The
+// cosine is clamped to [-1, 1] because rounding can take it just past 1 for a
+// restaurant at the point itself, and acos errors outside that range.
+func distanceKm(lat, lng float64) clause.Expr {
+	return clause.Expr{
+		SQL: `? * acos(LEAST(1.0, GREATEST(-1.0,
+			cos(radians(?)) *
+			cos(radians(latitude)) *
+			cos(radians(longitude) - radians(?)) +
+			sin(radians(?)) *
+			sin(radians(latitude))
+		)))`,
+		Vars: []interface{}{earthRadiusKm, lat, lng, lat},
+	}
+}
+
+func (r *GormRestaurantRepository) Nearby(ctx context.Context, lat, lng float64, radius int, filter RestaurantFilter) ([]models.Restaurant, error) {
+	distance := distanceKm(lat, lng)
+	query := r.db.WithContext(ctx).Model(&models.Restaurant{}).
+		Where("? <= ?", distance, float64(radius)/1000.0)
+	query = whereRestaurant(query, filter)
+
+	var restaurants []models.Restaurant
+	err := query.
+		Preload("OpeningHours").
+		Preload("Cuisines").
+		Clauses(clause.OrderBy{Expression: distance}).
+		Find(&restaurants).Error
+	if err != nil {
+		return nil, err
+	}
+	return filterOpen(restaurants, filter.OpenAt), nil
+}
+
+func (r *GormRestaurantRepository) Create(ctx context.Context, restaurant *models.Restaurant) error {
+	return duplicate(r.db.WithContext(ctx).Create(restaurant).Error)
+}
+
+func (r *GormRestaurantRepository) Update(ctx context.Context, restaurant *models.Restaurant) error {
+	return duplicate(r.db.WithContext(ctx).Omit("OpeningHours", "Cuisines", "MenuItems", "SourceLinks").Save(restaurant).Error)
+}
+
+func (r *GormRestaurantRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var restaurant models.Restaurant
+		if err := tx.First(&restaurant, id).Error; err != nil {
+			return notFound(err)
+		}
+		if err := tx.Delete(&restaurant).Error; err != nil {
+			return err
+		}
+
+		// Reload to pick up the exact deleted_at written above, so that a
+		// restore can bring back the menu items deleted alongside it.
+		if err := tx.Unscoped().First(&restaurant, id).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.MenuItem{}).
+			Where("restaurant_id = ?", id).
+			Update("deleted_at", restaurant.DeletedAt).Error
+	})
+}
+
+func (r *GormRestaurantRepository) Restore(ctx context.Context, id uint) (*models.Restaurant, error) {
+	var restaurant models.Restaurant
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&restaurant, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.MenuItem{}).
+			Where("restaurant_id = ? AND deleted_at = ?", id, restaurant.DeletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&restaurant).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	restaurant.DeletedAt = gorm.DeletedAt{}
+	return &restaurant, nil
+}
+
+func (r *GormRestaurantRepository) SetCuisines(ctx context.Context, restaurant *models.Restaurant, cuisines []models.Cuisine) error {
+	cuisineType := ""
+	if len(cuisines) > 0 {
+		cuisineType = cuisines[0].Name
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		association := tx.Model(restaurant).Association("Cuisines")
+		if len(cuisines) == 0 {
+			if err := association.Clear(); err != nil {
+				return err
+			}
+		} else {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "slug"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name"}),
+			}).Create(&cuisines).Error
+			if err != nil {
+				return err
+			}
+			if err := association.Replace(cuisines); err != nil {
+				return err
+			}
+		}
+
+		restaurant.CuisineType = cuisineType
+		return tx.Model(restaurant).Update("cuisine_type", cuisineType).Error
+	})
+}
+
+func (r *GormRestaurantRepository) ListCuisines(ctx context.Context) ([]models.Cuisine, error) {
+	var cuisines []models.Cuisine
+	if err := r.db.WithContext(ctx).Order("name").Find(&cuisines).Error; err != nil {
+		return nil, err
+	}
+	return cuisines, nil
+}
+
+func (r *GormRestaurantRepository) LinkSource(ctx context.Context, link *models.RestaurantSourceLink) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}, {Name: "external_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"restaurant_id"}),
+	}).Create(link).Error
+}
+
+func (r *GormRestaurantRepository) SaveOpeningHours(ctx context.Context, hours *models.OpeningHours) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "restaurant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"periods", "special_days", "updated_at"}),
+	}).Create(hours).Error
+}
+
+func (r *GormRestaurantRepository) DeleteOpeningHours(ctx context.Context, restaurantID uint) error {
+	result := r.db.WithContext(ctx).Where("restaurant_id = ?", restaurantID).Delete(&models.OpeningHours{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormRestaurantRepository) Merge(ctx context.Context, keepID, duplicateID uint, match MenuMatcher) (*RestaurantMerge, error) {
+	result := &RestaurantMerge{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var keep, duplicate models.Restaurant
+		if err := tx.Preload("Cuisines").Preload("OpeningHours").First(&keep, keepID).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Cuisines").Preload("OpeningHours").First(&duplicate, duplicateID).Error; err != nil {
+			return err
+		}
+
+		if err := mergeMenus(tx, &keep, &duplicate, match, result); err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{&models.PromoPrice{}, &models.ScrapedData{}, &models.RestaurantSourceLink{}} {
+			if err := tx.Unscoped().Model(model).Where("restaurant_id = ?", duplicate.ID).Update("restaurant_id", keep.ID).Error; err != nil {
+				return err
+			}
+		}
+		link := models.RestaurantSourceLink{
+			RestaurantID: keep.ID,
+			Source:       models.ExternalIDSource(duplicate.ExternalID),
+			ExternalID:   duplicate.ExternalID,
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "source"}, {Name: "external_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"restaurant_id"}),
+		}).Create(&link).Error
+		if err != nil {
+			return err
+		}
+
+		if err := mergeCuisines(tx, &keep, &duplicate); err != nil {
+			return err
+		}
+		if keep.OpeningHours == nil && duplicate.OpeningHours != nil {
+			if err := tx.Model(duplicate.OpeningHours).Update("restaurant_id", keep.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		fillRestaurant(&keep, &duplicate)
+		err = tx.Model(&keep).Omit(clause.Associations).Select("address", "city", "state", "zip_code", "country", "cuisine_type", "phone", "website", "price_range", "time_zone", "utc_offset_minutes").
+			Updates(&keep).Error
+		if err != nil {
+			return err
+		}
+
+		if err := resolveMergedCandidates(tx, keep.ID, duplicate.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&duplicate).Error
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return result, nil
+}
+
+// mergeMenus moves the live menu items of duplicate to keep, merging those
+// that match one of keep's.
+func mergeMenus(tx *gorm.DB, keep, duplicate *models.Restaurant, match MenuMatcher, result *RestaurantMerge) error {
+	var kept, items []models.MenuItem
+	if err := tx.Where("restaurant_id = ?", keep.ID).Order("id").Find(&kept).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("restaurant_id = ?", duplicate.ID).Order("id").Find(&items).Error; err != nil {
+		return err
+	}
+
+	matches := match(items, kept)
+	for i := range items {
+		item := &items[i]
+		if target := matches[i]; target != nil {
+			if err := mergeMenuItem(tx, target, item); err != nil {
+				return err
+			}
+			result.MergedItems++
+			continue
+		}
+		if err := tx.Model(item).Update("restaurant_id", keep.ID).Error; err != nil {
+			return err
+		}
+		result.MovedItems++
+	}
+	return nil
+}
+
+// mergeCuisines adds the cuisines of duplicate that keep lacks after keep's
+// own, so that keep's main cuisine stays first.
+func mergeCuisines(tx *gorm.DB, keep, duplicate *models.Restaurant) error {
+	have := make(map[uint]bool, len(keep.Cuisines))
+	for _, cuisine := range keep.Cuisines {
+		have[cuisine.ID] = true
+	}
+	var missing []models.Cuisine
+	for _, cuisine := range duplicate.Cuisines {
+		if !have[cuisine.ID] {
+			missing = append(missing, cuisine)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return tx.Model(keep).Association("Cuisines").Append(missing)
+}
+
+// resolveMergedCandidates marks the queued candidate for a merged pair as
+// merged and drops the other pending candidates of the deleted restaurant,
+// which a later scan finds again against the kept one.
+func resolveMergedCandidates(tx *gorm.DB, keepID, duplicateID uint) error {
+	pair := models.DuplicateCandidate{RestaurantID: keepID, DuplicateID: duplicateID}
+	orderPair(&pair)
+	err := tx.Model(&models.DuplicateCandidate{}).
+		Where("restaurant_id = ? AND duplicate_id = ?", pair.RestaurantID, pair.DuplicateID).
+		Updates(map[string]interface{}{"status": models.DuplicateMerged, "resolved_at": time.Now()}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("status = ? AND (restaurant_id = ? OR duplicate_id = ?)", models.DuplicatePending, duplicateID, duplicateID).
+		Delete(&models.DuplicateCandidate{}).Error
+}
+
+type GormMenuRepository struct {
+	db *gorm.DB
+}
+
+// itemRate is the rate of a menu item's currency, given a LEFT JOIN of
+// latest_exchange_rates as src: 1 for the euro, which rates are quoted
+// against and so has no row, and NULL for a currency without a rate.
+const itemRate = "CASE WHEN menu_items.currency = '" + baseCurrency + "' THEN 1 ELSE src.rate END"
+
+// euroPrice is a menu item's price in euros. Without a rate for its
+// currency it is the item's own amount, so that prices still sort, and
+// correctly so in a single currency, before any rates are loaded.
+const euroPrice = "COALESCE(menu_items.price / " + itemRate + ", menu_items.price)"
+
+func joinLatestRates(query *gorm.DB) *gorm.DB {
+	return query.Joins("LEFT JOIN latest_exchange_rates src ON src.currency = menu_items.currency")
+}
+
+// variantEuroPrice is euroPrice for a variant v, given a LEFT JOIN of
+// latest_exchange_rates as vr.
+const variantEuroPrice = "COALESCE(v.price / CASE WHEN v.currency = '" + baseCurrency + "' THEN 1 ELSE vr.rate END, v.price)"
+
+// cheapestVariantEuroPrice is the euro price of a menu item's cheapest
+// available variant, or NULL when it has none.
+const cheapestVariantEuroPrice = `(SELECT min(` + variantEuroPrice + `) FROM menu_item_variants v
+	LEFT JOIN latest_exchange_rates vr ON vr.currency = v.currency
+	WHERE v.menu_item_id = menu_items.id AND v.is_available AND v.deleted_at IS NULL)`
+
+// dealEuroPrice is what a menu item costs at the least in euros: its own
+// price or that of its cheapest variant. LEAST ignores NULLs.
+const dealEuroPrice = "LEAST(" + euroPrice + ", " + cheapestVariantEuroPrice + ")"
+
+// sameCurrencyDealPrice is the least of a menu item's own price and that of
+// its cheapest available variant, counting only those in the currency
+// given as its two arguments, or NULL when neither is in it.
+const sameCurrencyDealPrice = `LEAST(CASE WHEN menu_items.currency = ? THEN menu_items.price END,
+	(SELECT min(v.price) FROM menu_item_variants v
+	WHERE v.menu_item_id = menu_items.id AND v.currency = ? AND v.is_available AND v.deleted_at IS NULL))`
+
+// whereMaxPrice keeps items whose deal price is at most max: in max's own
+// currency, or as dealEuroPrice when both currencies have a rate.
+func whereMaxPrice(query *gorm.DB, max money.Money) *gorm.DB {
+	maxEuros := "? / (SELECT rate FROM latest_exchange_rates WHERE currency = ?)"
+	args := []interface{}{max.Currency, max.Currency, max.Amount, max.Amount, max.Currency}
+	if max.Currency == baseCurrency {
+		maxEuros, args = "?", args[:4]
+	}
+	return query.Where(
+		sameCurrencyDealPrice+" <= ? OR ("+itemRate+" IS NOT NULL AND "+dealEuroPrice+" <= "+maxEuros+")",
+		args...,
+	)
+}
+
+func (r *GormMenuRepository) ListByRestaurant(ctx context.Context, restaurantID uint, filter MenuItemFilter) ([]models.MenuItem, error) {
+	query := r.db.WithContext(ctx).Model(&models.MenuItem{}).Where("menu_items.restaurant_id = ?", restaurantID)
+
+	if filter.Category != "" {
+		query = query.Where("menu_items.category = ?", filter.Category)
+	}
+	query = whereDiet(query, filter.Diet)
+
+	var items []models.MenuItem
+	if err := query.Order("menu_items.id").Preload("Variants", byID).Preload("Modifiers", byID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func byID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+func (r *GormMenuRepository) Get(ctx context.Context, id uint) (*models.MenuItem, error) {
+	var item models.MenuItem
+	if err := r.db.WithContext(ctx).
+		Preload("PriceHistory", "variant_id IS NULL AND modifier_id IS NULL").
+		Preload("Variants", byID).
+		Preload("Variants.PriceHistory").
+		Preload("Modifiers", byID).
+		Preload("Modifiers.PriceHistory").
+		First(&item, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &item, nil
+}
+
+func (r *GormMenuRepository) Cheapest(ctx context.Context, filter DealFilter) ([]models.MenuItem, error) {
+	query := r.db.WithContext(ctx).Model(&models.MenuItem{}).
+		Preload("Restaurant").
+		Joins("JOIN restaurants ON restaurants.id = menu_items.restaurant_id AND restaurants.deleted_at IS NULL").
+		Where("menu_items.is_available")
+	query = joinLatestRates(query)
+
+	if filter.City != "" {
+		query = query.Where("restaurants.city = ?", filter.City)
+	}
+	if filter.Category != "" {
+		query = query.Where("menu_items.category = ?", filter.Category)
+	}
+	if filter.MaxPrice != nil {
+		query = whereMaxPrice(query, *filter.MaxPrice)
+	}
+	query = whereDiet(query, filter.Diet)
+
+	var items []models.MenuItem
+	if err := query.Order(dealEuroPrice + ", menu_items.id").Limit(filter.Limit).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	// The cheapest available variant of each item, where it beats the item.
+	var variants []models.MenuItemVariant
+	if err := r.db.WithContext(ctx).Raw(`SELECT DISTINCT ON (v.menu_item_id) v.*
+		FROM menu_item_variants v
+		LEFT JOIN latest_exchange_rates vr ON vr.currency = v.currency
+		JOIN menu_items ON menu_items.id = v.menu_item_id
+		LEFT JOIN latest_exchange_rates src ON src.currency = menu_items.currency
+		WHERE v.menu_item_id IN ? AND v.is_available AND v.deleted_at IS NULL
+			AND `+variantEuroPrice+` < `+euroPrice+`
+		ORDER BY v.menu_item_id, `+variantEuroPrice+`, v.id`, ids).Scan(&variants).Error; err != nil {
+		return nil, err
+	}
+
+	byItem := make(map[uint]*models.MenuItemVariant, len(variants))
+	for i := range variants {
+		byItem[variants[i].MenuItemID] = &variants[i]
+	}
+	for i := range items {
+		items[i].DealVariant = byItem[items[i].ID]
+	}
+	return items, nil
+}
+
+func (r *GormMenuRepository) Rename(ctx context.Context, item *models.MenuItem, name string) error {
+	if err := r.db.WithContext(ctx).Model(item).Update("name", name).Error; err != nil {
+		return err
+	}
+	item.Name = name
+	return nil
+}
+
+func (r *GormMenuRepository) Create(ctx context.Context, item *models.MenuItem, provenance models.PriceProvenance) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createMenuItem(tx, item, provenance)
+	})
+}
+
+// createMenuItem implements MenuRepository.Create within a transaction.
+func createMenuItem(tx *gorm.DB, item *models.MenuItem, provenance models.PriceProvenance) error {
+	if err := tx.Omit(clause.Associations).Create(item).Error; err != nil {
+		return err
+	}
+	entry := models.NewPriceHistory(item.ID, item.Price, nil, provenance)
+	return tx.Create(&entry).Error
+}
+
+func (r *GormMenuRepository) UpdatePrice(ctx context.Context, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	if item.Price.Equal(price) {
+		return false, nil
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := updateMenuItemPrice(tx, item, price, provenance)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// updateMenuItemPrice implements MenuRepository.UpdatePrice within a
+// transaction.
+func updateMenuItemPrice(tx *gorm.DB, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	if item.Price.Equal(price) {
+		return false, nil
+	}
+
+	previous := item.Price
+	entry := models.NewPriceHistory(item.ID, price, &previous, provenance)
+	if err := tx.Create(&entry).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(item).Updates(map[string]interface{}{
+		"price":    price.Amount,
+		"currency": price.Currency,
+	}).Error; err != nil {
+		return false, err
+	}
+	item.Price = price
+	return true, nil
+}
+
+func (r *GormMenuRepository) Update(ctx context.Context, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		changed, err = updateMenuItem(tx, item, price, provenance)
+		return err
+	})
+	return changed, err
+}
+
+// updateMenuItem implements MenuRepository.Update within a transaction.
+func updateMenuItem(tx *gorm.DB, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	if err := tx.Omit(clause.Associations, "price", "currency").Save(item).Error; err != nil {
+		return false, err
+	}
+	return updateMenuItemPrice(tx, item, price, provenance)
+}
+
+func (r *GormMenuRepository) Delete(ctx context.Context, item *models.MenuItem) error {
+	return r.db.WithContext(ctx).Delete(item).Error
+}
+
+func (r *GormMenuRepository) Restore(ctx context.Context, id uint) (*models.MenuItem, error) {
+	var item models.MenuItem
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&item, id).Error; err != nil {
+			return err
+		}
+		var live int64
+		if err := tx.Model(&models.Restaurant{}).Where("id = ?", item.RestaurantID).Count(&live).Error; err != nil {
+			return err
+		}
+		if live == 0 {
+			return ErrRestaurantDeleted
+		}
+		return tx.Unscoped().Model(&item).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	item.DeletedAt = gorm.DeletedAt{}
+	return &item, nil
+}
+
+func (r *GormMenuRepository) Import(ctx context.Context, restaurantID uint, plan MenuPlan, provenance models.PriceProvenance) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.MenuItem
+		if err := tx.Where("restaurant_id = ?", restaurantID).Order("id").Find(&existing).Error; err != nil {
+			return err
+		}
+		prices := make(map[uint]money.Money, len(existing))
+		for _, item := range existing {
+			prices[item.ID] = item.Price
+		}
+
+		create, update, err := plan(existing)
+		if err != nil {
+			return err
+		}
+		for i := range create {
+			create[i].RestaurantID = restaurantID
+			if err := createMenuItem(tx, &create[i], provenance); err != nil {
+				return err
+			}
+		}
+		for i := range update {
+			item := &update[i]
+			price := item.Price
+			item.Price = prices[item.ID]
+			if _, err := updateMenuItem(tx, item, price, provenance); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *GormMenuRepository) SaveVariant(ctx context.Context, variant *models.MenuItemVariant, provenance models.PriceProvenance) (bool, error) {
+	return saveOption(ctx, r.db, variant, provenance)
+}
+
+func (r *GormMenuRepository) SaveModifier(ctx context.Context, modifier *models.Modifier, provenance models.PriceProvenance) (bool, error) {
+	return saveOption(ctx, r.db, modifier, provenance)
+}
+
+// saveOption implements SaveVariant and SaveModifier.
+func saveOption[T any, P interface {
+	*T
+	models.PricedOption
+}](ctx context.Context, db *gorm.DB, option P, provenance models.PriceProvenance) (bool, error) {
+	changed := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fields := option.Option()
+
+		var stored T
+		err := tx.Where("menu_item_id = ? AND lower(name) = lower(?)", fields.MenuItemID, fields.Name).
+			First(P(&stored)).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Create(option).Error; err != nil {
+				return err
+			}
+			entry := option.NewPriceHistory(fields.Price, nil, provenance)
+			return tx.Create(&entry).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		price := fields.Price
+		*fields = *P(&stored).Option()
+		if fields.Price.Equal(price) {
+			return nil
+		}
+
+		previous := fields.Price
+		changed = true
+		entry := option.NewPriceHistory(price, &previous, provenance)
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(option).Updates(map[string]interface{}{
+			"price":    price.Amount,
+			"currency": price.Currency,
+		}).Error; err != nil {
+			return err
+		}
+		fields.Price = price
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+func (r *GormMenuRepository) Merge(ctx context.Context, target, source *models.MenuItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return mergeMenuItem(tx, target, source)
+	})
+}
+
+// mergeMenuItem implements MenuRepository.Merge within a transaction.
+func mergeMenuItem(tx *gorm.DB, target, source *models.MenuItem) error {
+	targetPriced, err := lastPriced(tx, target.ID)
+	if err != nil {
+		return err
+	}
+	sourcePriced, err := lastPriced(tx, source.ID)
+	if err != nil {
+		return err
+	}
+	if sourcePriced.After(targetPriced) {
+		target.Price = source.Price
+		target.IsAvailable = source.IsAvailable
+	}
+
+	for _, options := range []struct{ table, historyColumn string }{
+		{"menu_item_variants", "variant_id"},
+		{"modifiers", "modifier_id"},
+	} {
+		if err := mergeOptions(tx, options.table, options.historyColumn, target.ID, source.ID); err != nil {
+			return err
+		}
+	}
+
+	// What is left is the history of the item's own price, and of options
+	// deleted before the merge.
+	if err := tx.Model(&models.PriceHistory{}).Where("menu_item_id = ?", source.ID).Update("menu_item_id", target.ID).Error; err != nil {
+		return err
+	}
+	err = tx.Unscoped().Model(&models.PromoPrice{}).Where("menu_item_id = ?", source.ID).
+		Updates(map[string]interface{}{"menu_item_id": target.ID, "restaurant_id": target.RestaurantID}).Error
+	if err != nil {
+		return err
+	}
+
+	if err := resolveMergedMenuMatches(tx, target.ID, source.ID); err != nil {
+		return err
+	}
+
+	fillMenuItem(target, source)
+	err = tx.Model(target).Select("price", "currency", "is_available", "description", "category", "diets", "allergens", "spice_level").Updates(target).Error
+	if err != nil {
+		return err
+	}
+	return tx.Delete(source).Error
+}
+
+// resolveMergedMenuMatches marks the queued menu match between two merged
+// items as merged, whichever way round it was queued, and drops the other
+// pending matches of the deleted item.
+func resolveMergedMenuMatches(tx *gorm.DB, targetID, sourceID uint) error {
+	err := tx.Model(&models.MenuMatchCandidate{}).
+		Where("(menu_item_id = ? AND new_item_id = ?) OR (menu_item_id = ? AND new_item_id = ?)", targetID, sourceID, sourceID, targetID).
+		Updates(map[string]interface{}{"status": models.DuplicateMerged, "resolved_at": time.Now()}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("status = ? AND (menu_item_id = ? OR new_item_id = ?)", models.DuplicatePending, sourceID, sourceID).
+		Delete(&models.MenuMatchCandidate{}).Error
+}
+
+// lastPriced returns when a menu item's own price was last recorded, or the
+// zero time if never.
+func lastPriced(tx *gorm.DB, itemID uint) (time.Time, error) {
+	var entries []models.PriceHistory
+	err := tx.Where("menu_item_id = ? AND variant_id IS NULL AND modifier_id IS NULL", itemID).
+		Order("recorded_at DESC, id DESC").Limit(1).Find(&entries).Error
+	if err != nil || len(entries) == 0 {
+		return time.Time{}, err
+	}
+	return entries[0].RecordedAt, nil
+}
+
+// mergeOptions moves the live variants or modifiers of one menu item to
+// another. Where the target has one of the same name, ignoring case, only
+// the price history moves and the source's option is deleted.
+func mergeOptions(tx *gorm.DB, table, historyColumn string, targetID, sourceID uint) error {
+	var kept, options []models.MenuItemOption
+	if err := tx.Table(table).Where("menu_item_id = ?", targetID).Find(&kept).Error; err != nil {
+		return err
+	}
+	if err := tx.Table(table).Where("menu_item_id = ?", sourceID).Order("id").Find(&options).Error; err != nil {
+		return err
+	}
+
+	byName := make(map[string]uint, len(kept))
+	for _, option := range kept {
+		byName[nameKey(option.Name)] = option.ID
+	}
+
+	history := func(optionID uint) *gorm.DB {
+		return tx.Model(&models.PriceHistory{}).Where(historyColumn+" = ?", optionID)
+	}
+	for _, option := range options {
+		if targetOptionID, ok := byName[nameKey(option.Name)]; ok {
+			err := history(option.ID).Updates(map[string]interface{}{"menu_item_id": targetID, historyColumn: targetOptionID}).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Table(table).Where("id = ?", option.ID).Update("deleted_at", time.Now()).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := history(option.ID).Update("menu_item_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(table).Where("id = ?", option.ID).Update("menu_item_id", targetID).Error; err != nil {
+			return err
+		}
+		byName[nameKey(option.Name)] = option.ID
+	}
+	return nil
+}
+
+func (r *GormMenuRepository) GetOption(ctx context.Context, option models.PricedOption, menuItemID, id uint) error {
+	return notFound(r.db.WithContext(ctx).Where("menu_item_id = ?", menuItemID).First(option, id).Error)
+}
+
+func (r *GormMenuRepository) OptionNameTaken(ctx context.Context, option models.PricedOption) (bool, error) {
+	fields := option.Option()
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(option).
+		Where("menu_item_id = ? AND lower(name) = lower(?) AND id <> ?", fields.MenuItemID, fields.Name, fields.ID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormMenuRepository) CreateOption(ctx context.Context, option models.PricedOption, provenance models.PriceProvenance) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("PriceHistory").Create(option).Error; err != nil {
+			return err
+		}
+		entry := option.NewPriceHistory(option.Option().Price, nil, provenance)
+		return tx.Create(&entry).Error
+	})
+}
+
+func (r *GormMenuRepository) UpdateOption(ctx context.Context, option models.PricedOption, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	fields := option.Option()
+	changed := !fields.Price.Equal(price)
+	previous := fields.Price
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("price", "currency", "PriceHistory").Save(option).Error; err != nil {
+			return err
+		}
+		if !changed {
+			return nil
+		}
+		entry := option.NewPriceHistory(price, &previous, provenance)
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(option).Updates(map[string]interface{}{
+			"price":    price.Amount,
+			"currency": price.Currency,
+		}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	fields.Price = price
+	return changed, nil
+}
+
+func (r *GormMenuRepository) DeleteOption(ctx context.Context, option models.PricedOption) error {
+	return r.db.WithContext(ctx).Delete(option).Error
+}
+
+type GormPriceHistoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *GormPriceHistoryRepository) ListByMenuItem(ctx context.Context, menuItemID uint, filter PriceHistoryFilter) ([]models.PriceHistory, error) {
+	query := r.db.WithContext(ctx).Where("menu_item_id = ?", menuItemID)
+	switch {
+	case filter.VariantID != nil:
+		query = query.Where("variant_id = ?", *filter.VariantID)
+	case filter.ModifierID != nil:
+		query = query.Where("modifier_id = ?", *filter.ModifierID)
+	default:
+		query = query.Where("variant_id IS NULL AND modifier_id IS NULL")
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+
+	var history []models.PriceHistory
+	if err := query.Order("recorded_at DESC").
+		Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+type GormDuplicateRepository struct {
+	db *gorm.DB
+}
+
+func (r *GormDuplicateRepository) Queue(ctx context.Context, candidate *models.DuplicateCandidate) (bool, error) {
+	orderPair(candidate)
+	if candidate.Status == "" {
+		candidate.Status = models.DuplicatePending
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(candidate)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *GormDuplicateRepository) List(ctx context.Context, status string, limit int) ([]models.DuplicateCandidate, error) {
+	// Merged restaurants are deleted, but still worth showing.
+	unscoped := func(db *gorm.DB) *gorm.DB { return db.Unscoped() }
+
+	var candidates []models.DuplicateCandidate
+	err := r.db.WithContext(ctx).
+		Preload("Restaurant", unscoped).Preload("Duplicate", unscoped).
+		Where("status = ?", status).
+		Order("score DESC, id").
+		Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+func (r *GormDuplicateRepository) Get(ctx context.Context, id uint) (*models.DuplicateCandidate, error) {
+	var candidate models.DuplicateCandidate
+	if err := r.db.WithContext(ctx).First(&candidate, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &candidate, nil
+}
+
+func (r *GormDuplicateRepository) Dismiss(ctx context.Context, candidate *models.DuplicateCandidate) error {
+	now := time.Now()
+	err := r.db.WithContext(ctx).Model(candidate).
+		Updates(map[string]interface{}{"status": models.DuplicateDismissed, "resolved_at": now}).Error
+	if err != nil {
+		return err
+	}
+	candidate.Status, candidate.ResolvedAt = models.DuplicateDismissed, &now
+	return nil
+}
+
+type GormMenuMatchRepository struct {
+	db *gorm.DB
+}
+
+func (r *GormMenuMatchRepository) Queue(ctx context.Context, candidate *models.MenuMatchCandidate) (bool, error) {
+	if candidate.Status == "" {
+		candidate.Status = models.DuplicatePending
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(candidate)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *GormMenuMatchRepository) List(ctx context.Context, status string, limit int) ([]models.MenuMatchCandidate, error) {
+	// Merged items are deleted, but still worth showing.
+	unscoped := func(db *gorm.DB) *gorm.DB { return db.Unscoped() }
+
+	var candidates []models.MenuMatchCandidate
+	err := r.db.WithContext(ctx).
+		Preload("MenuItem", unscoped).Preload("NewItem", unscoped).
+		Where("status = ?", status).
+		Order("confidence DESC, id").
+		Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+func (r *GormMenuMatchRepository) Get(ctx context.Context, id uint) (*models.MenuMatchCandidate, error) {
+	var candidate models.MenuMatchCandidate
+	if err := r.db.WithContext(ctx).First(&candidate, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &candidate, nil
+}
+
+func (r *GormMenuMatchRepository) Dismiss(ctx context.Context, candidate *models.MenuMatchCandidate) error {
+	now := time.Now()
+	err := r.db.WithContext(ctx).Model(candidate).
+		Updates(map[string]interface{}{"status": models.DuplicateDismissed, "resolved_at": now}).Error
+	if err != nil {
+		return err
+	}
+	candidate.Status, candidate.ResolvedAt = models.DuplicateDismissed, &now
+	return nil
+}
+
+type GormScrapeRepository struct {
+	db *gorm.DB
+}
+
+func (r *GormScrapeRepository) Save(ctx context.Context, data *models.ScrapedData) error {
+	return r.db.WithContext(ctx).Create(data).Error
+}
+
+func (r *GormScrapeRepository) LastScrapedAt(ctx context.Context) (time.Time, error) {
+	var last sql.NullTime
+	if err := r.db.WithContext(ctx).Model(&models.ScrapedData{}).Select("max(scraped_at)").Scan(&last).Error; err != nil {
+		return time.Time{}, err
+	}
+	return last.Time, nil
+}
+
+type GormPromoRepository struct {
+	db *gorm.DB
+}
+
+func (r *GormPromoRepository) ListByRestaurant(ctx context.Context, restaurantID uint) ([]models.PromoPrice, error) {
+	var promos []models.PromoPrice
+	err := r.db.WithContext(ctx).
+		Preload("Restaurant").
+		Where("restaurant_id = ? AND is_active", restaurantID).
+		Order("id").
+		Find(&promos).Error
+	if err != nil {
+		return nil, err
+	}
+	return promos, nil
+}
+
+func (r *GormPromoRepository) Get(ctx context.Context, id uint) (*models.PromoPrice, error) {
+	var promo models.PromoPrice
+	if err := r.db.WithContext(ctx).First(&promo, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &promo, nil
+}
+
+func (r *GormPromoRepository) Create(ctx context.Context, promo *models.PromoPrice) error {
+	return r.db.WithContext(ctx).Omit("Restaurant").Create(promo).Error
+}
+
+func (r *GormPromoRepository) Update(ctx context.Context, promo *models.PromoPrice) error {
+	return r.db.WithContext(ctx).Omit("Restaurant").Save(promo).Error
+}
+
+func (r *GormPromoRepository) Delete(ctx context.Context, promo *models.PromoPrice) error {
+	return r.db.WithContext(ctx).Delete(promo).Error
+}
+
+type GormExchangeRateRepository struct {
+	db *gorm.DB
+}
+
+func (r *GormExchangeRateRepository) Save(ctx context.Context, rates []models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}, {Name: "rate_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source"}),
+	}).CreateInBatches(rates, 500).Error
+}
+
+func (r *GormExchangeRateRepository) List(ctx context.Context) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	if err := r.db.WithContext(ctx).Order("currency, rate_date").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
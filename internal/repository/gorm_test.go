@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+)
+
+// dryRunDB returns a Postgres connection that builds SQL without running it.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestNearbyClampsCosine(t *testing.T) {
+	db := dryRunDB(t)
+
+	// For a restaurant at the search point itself, the cosine rounds to
+	// just over 1 at latitudes like this one, and Postgres's acos rejects it.
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var restaurants []models.Restaurant
+		return tx.Model(&models.Restaurant{}).Where("? <= ?", distanceKm(23.193936, 19.452675), 0.05).Find(&restaurants)
+	})
+	sql = strings.Join(strings.Fields(sql), " ")
+	if !strings.Contains(sql, "acos(LEAST(1.0, GREATEST(-1.0, cos(radians(23.193936))") {
+		t.Errorf("distance is not clamped to acos's domain: %s", sql)
+	}
+}
+
+func TestWhereMaxPriceCountsVariants(t *testing.T) {
+	db := dryRunDB(t)
+
+	for _, max := range []money.Money{money.New(500, "USD"), money.New(500, baseCurrency)} {
+		sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			var items []models.MenuItem
+			return whereMaxPrice(tx.Model(&models.MenuItem{}), max).Find(&items)
+		})
+		sql = strings.Join(strings.Fields(sql), " ")
+		want := "LEAST(CASE WHEN menu_items.currency = '" + max.Currency + "' THEN menu_items.price END, " +
+			"(SELECT min(v.price) FROM menu_item_variants v WHERE v.menu_item_id = menu_items.id AND v.currency = '" + max.Currency + "'"
+		if !strings.Contains(sql, want) || !strings.Contains(sql, "deleted_at IS NULL)) <= '5.0000'") {
+			t.Errorf("%s limit doesn't compare the cheapest variant in its currency: %s", max.Currency, sql)
+		}
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	unique := &pgconn.PgError{Code: "23505", ConstraintName: "idx_restaurants_external_id"}
+	if err := duplicate(fmt.Errorf("saving: %w", unique)); err != ErrDuplicate {
+		t.Errorf("duplicate(unique violation) = %v, want ErrDuplicate", err)
+	}
+
+	for _, err := range []error{nil, &pgconn.PgError{Code: "23503"}, errors.New("connection refused")} {
+		if got := duplicate(err); got != err {
+			t.Errorf("duplicate(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
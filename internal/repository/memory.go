@@ -0,0 +1,1453 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// NewMemoryRepositories returns repositories that keep everything in memory,
+// for tests and local experiments. The repositories share one store, so
+// menu items created through Menus show up in Restaurants.Get.
+func NewMemoryRepositories() Repositories {
+	store := &memoryStore{
+		restaurants:        make(map[uint]*models.Restaurant),
+		openingHours:       make(map[uint]*models.OpeningHours),
+		menuItems:          make(map[uint]*models.MenuItem),
+		variants:           make(map[uint]*models.MenuItemVariant),
+		modifiers:          make(map[uint]*models.Modifier),
+		cuisines:           make(map[string]*models.Cuisine),
+		restaurantCuisines: make(map[uint][]string),
+		sourceLinks:        make(map[sourceKey]*models.RestaurantSourceLink),
+	}
+	return Repositories{
+		Restaurants:   &MemoryRestaurantRepository{store: store},
+		Menus:         &MemoryMenuRepository{store: store},
+		PriceHistory:  &MemoryPriceHistoryRepository{store: store},
+		Promos:        &MemoryPromoRepository{store: store},
+		Scrapes:       &MemoryScrapeRepository{store: store},
+		Duplicates:    &MemoryDuplicateRepository{store: store},
+		MenuMatches:   &MemoryMenuMatchRepository{store: store},
+		ExchangeRates: &MemoryExchangeRateRepository{store: store},
+	}
+}
+
+type memoryStore struct {
+	mu                 sync.RWMutex
+	nextID             uint
+	restaurants        map[uint]*models.Restaurant
+	openingHours       map[uint]*models.OpeningHours // by restaurant ID
+	cuisines           map[string]*models.Cuisine    // by slug
+	restaurantCuisines map[uint][]string             // cuisine slugs by restaurant ID
+	sourceLinks        map[sourceKey]*models.RestaurantSourceLink
+	duplicates         []models.DuplicateCandidate
+	menuMatches        []models.MenuMatchCandidate
+	menuItems          map[uint]*models.MenuItem
+	variants           map[uint]*models.MenuItemVariant
+	modifiers          map[uint]*models.Modifier
+	priceHistory       []models.PriceHistory
+	promos             []models.PromoPrice
+	scrapes            []models.ScrapedData
+	rates              []models.ExchangeRate
+}
+
+type sourceKey struct {
+	source, externalID string
+}
+
+func (s *memoryStore) newID() uint {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *memoryStore) recordPrice(menuItemID uint, price money.Money, previous *money.Money, provenance models.PriceProvenance) {
+	s.record(models.NewPriceHistory(menuItemID, price, previous, provenance))
+}
+
+func (s *memoryStore) record(entry models.PriceHistory) {
+	entry.ID = s.newID()
+	s.priceHistory = append(s.priceHistory, entry)
+}
+
+// withRelations returns a copy of restaurant with its opening hours and
+// cuisines.
+func (s *memoryStore) withRelations(restaurant *models.Restaurant) models.Restaurant {
+	found := *restaurant
+	if hours, ok := s.openingHours[restaurant.ID]; ok {
+		copied := *hours
+		found.OpeningHours = &copied
+	}
+	for _, slug := range s.restaurantCuisines[restaurant.ID] {
+		found.Cuisines = append(found.Cuisines, *s.cuisines[slug])
+	}
+	return found
+}
+
+// restaurantMatches applies all of filter but OpenAt, which is left to
+// filterOpen.
+func (s *memoryStore) restaurantMatches(restaurant *models.Restaurant, filter RestaurantFilter) bool {
+	if restaurant.DeletedAt.Valid ||
+		filter.City != "" && restaurant.City != filter.City ||
+		len(filter.Cuisines) > 0 && !s.hasCuisine(restaurant.ID, filter.Cuisines) ||
+		filter.PriceRange != "" && restaurant.PriceRange != filter.PriceRange {
+		return false
+	}
+	if filter.Diet.empty() {
+		return true
+	}
+	for _, item := range s.menuItems {
+		if item.RestaurantID == restaurant.ID && item.IsAvailable && !item.DeletedAt.Valid && filter.Diet.matches(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCuisine reports whether the restaurant has any of cuisines.
+func (s *memoryStore) hasCuisine(restaurantID uint, cuisines []string) bool {
+	for _, slug := range s.restaurantCuisines[restaurantID] {
+		for _, cuisine := range cuisines {
+			if slug == models.CuisineKey(cuisine) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// history returns the price history entries matching keep, oldest first.
+func (s *memoryStore) history(keep func(entry models.PriceHistory) bool) []models.PriceHistory {
+	history := []models.PriceHistory{}
+	for _, entry := range s.priceHistory {
+		if keep(entry) {
+			history = append(history, entry)
+		}
+	}
+	return history
+}
+
+// optionsOf returns the live options of a menu item in ID order.
+func optionsOf[T any, P interface {
+	*T
+	models.PricedOption
+}](options map[uint]P, menuItemID uint) []T {
+	found := []T{}
+	for _, option := range options {
+		if fields := option.Option(); fields.MenuItemID == menuItemID && !fields.DeletedAt.Valid {
+			found = append(found, *option)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return P(&found[i]).Option().ID < P(&found[j]).Option().ID
+	})
+	return found
+}
+
+// withOptions returns a copy of item with its variants and modifiers.
+func (s *memoryStore) withOptions(item *models.MenuItem) models.MenuItem {
+	found := *item
+	found.Variants = optionsOf(s.variants, item.ID)
+	found.Modifiers = optionsOf(s.modifiers, item.ID)
+	return found
+}
+
+// dealPrice returns what an item costs at the least, mirroring
+// dealEuroPrice, and the variant that costs that when it beats the item.
+func (s *memoryStore) dealPrice(item *models.MenuItem) (float64, *models.MenuItemVariant) {
+	euros := s.comparablePrice(item.Price)
+
+	var cheapest *models.MenuItemVariant
+	for _, variant := range optionsOf(s.variants, item.ID) {
+		variant := variant
+		if !variant.IsAvailable {
+			continue
+		}
+		if variantEuros := s.comparablePrice(variant.Price); variantEuros < euros {
+			euros, cheapest = variantEuros, &variant
+		}
+	}
+	return euros, cheapest
+}
+
+// comparablePrice is a price in euros, or its own amount without a rate for
+// its currency, mirroring euroPrice.
+func (s *memoryStore) comparablePrice(price money.Money) float64 {
+	if euros, ok := s.euroPrice(price); ok {
+		return euros
+	}
+	return price.Float64()
+}
+
+// euroPrice converts a price to euros at the latest stored rate, mirroring
+// the latest_exchange_rates view.
+func (s *memoryStore) euroPrice(price money.Money) (float64, bool) {
+	if price.Currency == baseCurrency {
+		return price.Float64(), true
+	}
+
+	var latest *models.ExchangeRate
+	for i := range s.rates {
+		rate := &s.rates[i]
+		if rate.Currency == price.Currency && (latest == nil || rate.Date.After(latest.Date)) {
+			latest = rate
+		}
+	}
+	if latest == nil {
+		return 0, false
+	}
+	return price.Float64() / latest.Rate, true
+}
+
+// withinDealPrice reports whether an item's deal price is at most max,
+// mirroring whereMaxPrice.
+func (s *memoryStore) withinDealPrice(item *models.MenuItem, max money.Money) bool {
+	if item.Price.Currency == max.Currency && item.Price.Amount <= max.Amount {
+		return true
+	}
+	for _, variant := range optionsOf(s.variants, item.ID) {
+		if variant.IsAvailable && variant.Price.Currency == max.Currency && variant.Price.Amount <= max.Amount {
+			return true
+		}
+	}
+	if _, ok := s.euroPrice(item.Price); !ok {
+		return false
+	}
+	euros, _ := s.dealPrice(item)
+	maxEuros, maxOK := s.euroPrice(max)
+	return maxOK && euros <= maxEuros
+}
+
+type MemoryRestaurantRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryRestaurantRepository) List(ctx context.Context, filter RestaurantFilter) ([]models.Restaurant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	restaurants := []models.Restaurant{}
+	for _, restaurant := range r.store.restaurants {
+		if !r.store.restaurantMatches(restaurant, filter) {
+			continue
+		}
+		restaurants = append(restaurants, r.store.withRelations(restaurant))
+	}
+	sort.Slice(restaurants, func(i, j int) bool {
+		return restaurants[i].ID < restaurants[j].ID
+	})
+	return filterOpen(restaurants, filter.OpenAt), nil
+}
+
+func (r *MemoryRestaurantRepository) Get(ctx context.Context, id uint) (*models.Restaurant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	restaurant, ok := r.store.restaurants[id]
+	if !ok || restaurant.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	found := r.store.withRelations(restaurant)
+	for _, link := range r.store.sourceLinks {
+		if link.RestaurantID == id {
+			found.SourceLinks = append(found.SourceLinks, *link)
+		}
+	}
+	sort.Slice(found.SourceLinks, func(i, j int) bool {
+		return found.SourceLinks[i].ID < found.SourceLinks[j].ID
+	})
+	found.MenuItems = []models.MenuItem{}
+	for _, item := range r.store.menuItems {
+		if item.RestaurantID == id && !item.DeletedAt.Valid {
+			found.MenuItems = append(found.MenuItems, *item)
+		}
+	}
+	sort.Slice(found.MenuItems, func(i, j int) bool {
+		return found.MenuItems[i].ID < found.MenuItems[j].ID
+	})
+	return &found, nil
+}
+
+func (r *MemoryRestaurantRepository) GetByExternalID(ctx context.Context, externalID string) (*models.Restaurant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, link := range r.store.sourceLinks {
+		if link.ExternalID == externalID {
+			if restaurant, ok := r.store.restaurants[link.RestaurantID]; ok {
+				found := *restaurant
+				return &found, nil
+			}
+		}
+	}
+	for _, restaurant := range r.store.restaurants {
+		if restaurant.ExternalID == externalID {
+			found := *restaurant
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryRestaurantRepository) Nearby(ctx context.Context, lat, lng float64, radius int, filter RestaurantFilter) ([]models.Restaurant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	type located struct {
+		restaurant models.Restaurant
+		distance   float64
+	}
+
+	var matches []located
+	for _, restaurant := range r.store.restaurants {
+		if !r.store.restaurantMatches(restaurant, filter) {
+			continue
+		}
+		distance := haversineKm(lat, lng, restaurant.Latitude, restaurant.Longitude)
+		if distance <= float64(radius)/1000.0 {
+			matches = append(matches, located{r.store.withRelations(restaurant), distance})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	restaurants := make([]models.Restaurant, 0, len(matches))
+	for _, match := range matches {
+		restaurants = append(restaurants, match.restaurant)
+	}
+	return filterOpen(restaurants, filter.OpenAt), nil
+}
+
+func (r *MemoryRestaurantRepository) Create(ctx context.Context, restaurant *models.Restaurant) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.externalIDTaken(restaurant.ExternalID, 0) {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	restaurant.ID = r.store.newID()
+	restaurant.CreatedAt, restaurant.UpdatedAt = now, now
+
+	stored := *restaurant
+	stored.OpeningHours, stored.Cuisines, stored.MenuItems, stored.SourceLinks = nil, nil, nil, nil
+	r.store.restaurants[stored.ID] = &stored
+	return nil
+}
+
+func (r *MemoryRestaurantRepository) Update(ctx context.Context, restaurant *models.Restaurant) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.restaurants[restaurant.ID]; !ok {
+		return ErrNotFound
+	}
+	if r.store.externalIDTaken(restaurant.ExternalID, restaurant.ID) {
+		return ErrDuplicate
+	}
+
+	restaurant.UpdatedAt = time.Now()
+	stored := *restaurant
+	stored.OpeningHours, stored.Cuisines, stored.MenuItems, stored.SourceLinks = nil, nil, nil, nil
+	r.store.restaurants[stored.ID] = &stored
+	return nil
+}
+
+// externalIDTaken reports whether a restaurant other than the one with ID id,
+// deleted or not, has externalID, as the unique index on the column does.
+func (s *memoryStore) externalIDTaken(externalID string, id uint) bool {
+	for _, restaurant := range s.restaurants {
+		if restaurant.ExternalID == externalID && restaurant.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRestaurantRepository) Delete(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	restaurant, ok := r.store.restaurants[id]
+	if !ok || restaurant.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	deleted := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	restaurant.DeletedAt = deleted
+	for _, item := range r.store.menuItems {
+		if item.RestaurantID == id && !item.DeletedAt.Valid {
+			item.DeletedAt = deleted
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRestaurantRepository) Restore(ctx context.Context, id uint) (*models.Restaurant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	restaurant, ok := r.store.restaurants[id]
+	if !ok || !restaurant.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	for _, item := range r.store.menuItems {
+		if item.RestaurantID == id && item.DeletedAt.Valid && item.DeletedAt.Time.Equal(restaurant.DeletedAt.Time) {
+			item.DeletedAt = gorm.DeletedAt{}
+		}
+	}
+	restaurant.DeletedAt = gorm.DeletedAt{}
+	found := *restaurant
+	return &found, nil
+}
+
+func (r *MemoryRestaurantRepository) SaveOpeningHours(ctx context.Context, hours *models.OpeningHours) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if existing, ok := r.store.openingHours[hours.RestaurantID]; ok {
+		hours.ID = existing.ID
+	} else {
+		hours.ID = r.store.newID()
+	}
+	hours.UpdatedAt = time.Now()
+	stored := *hours
+	r.store.openingHours[stored.RestaurantID] = &stored
+	return nil
+}
+
+func (r *MemoryRestaurantRepository) DeleteOpeningHours(ctx context.Context, restaurantID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.openingHours[restaurantID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.openingHours, restaurantID)
+	return nil
+}
+
+func (r *MemoryRestaurantRepository) SetCuisines(ctx context.Context, restaurant *models.Restaurant, cuisines []models.Cuisine) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.restaurants[restaurant.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	slugs := make([]string, len(cuisines))
+	for i := range cuisines {
+		cuisine := &cuisines[i]
+		if existing, ok := r.store.cuisines[cuisine.Slug]; ok {
+			cuisine.ID = existing.ID
+		} else {
+			cuisine.ID = r.store.newID()
+		}
+		copied := *cuisine
+		r.store.cuisines[cuisine.Slug] = &copied
+		slugs[i] = cuisine.Slug
+	}
+	r.store.restaurantCuisines[restaurant.ID] = slugs
+
+	restaurant.CuisineType = ""
+	if len(cuisines) > 0 {
+		restaurant.CuisineType = cuisines[0].Name
+	}
+	restaurant.Cuisines = cuisines
+	stored.CuisineType = restaurant.CuisineType
+	return nil
+}
+
+func (r *MemoryRestaurantRepository) LinkSource(ctx context.Context, link *models.RestaurantSourceLink) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := sourceKey{link.Source, link.ExternalID}
+	if existing, ok := r.store.sourceLinks[key]; ok {
+		link.ID, link.CreatedAt = existing.ID, existing.CreatedAt
+	} else {
+		link.ID, link.CreatedAt = r.store.newID(), time.Now()
+	}
+	stored := *link
+	r.store.sourceLinks[key] = &stored
+	return nil
+}
+
+func (r *MemoryRestaurantRepository) Merge(ctx context.Context, keepID, duplicateID uint, match MenuMatcher) (*RestaurantMerge, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	s := r.store
+
+	keep, ok := s.restaurants[keepID]
+	if !ok || keep.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	duplicate, ok := s.restaurants[duplicateID]
+	if !ok || duplicate.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	result := &RestaurantMerge{}
+	kept, items := s.liveItems(keep.ID), s.liveItems(duplicate.ID)
+	matches := match(items, kept)
+	for i := range items {
+		source := s.menuItems[items[i].ID]
+		if target := matches[i]; target != nil {
+			s.mergeMenuItem(s.menuItems[target.ID], source)
+			result.MergedItems++
+			continue
+		}
+		source.RestaurantID = keep.ID
+		result.MovedItems++
+	}
+
+	for i := range s.promos {
+		if s.promos[i].RestaurantID == duplicate.ID {
+			s.promos[i].RestaurantID = keep.ID
+		}
+	}
+	for i := range s.scrapes {
+		if id := s.scrapes[i].RestaurantID; id != nil && *id == duplicate.ID {
+			keepID := keep.ID
+			s.scrapes[i].RestaurantID = &keepID
+		}
+	}
+	for _, link := range s.sourceLinks {
+		if link.RestaurantID == duplicate.ID {
+			link.RestaurantID = keep.ID
+		}
+	}
+	key := sourceKey{models.ExternalIDSource(duplicate.ExternalID), duplicate.ExternalID}
+	if link, ok := s.sourceLinks[key]; ok {
+		link.RestaurantID = keep.ID
+	} else {
+		s.sourceLinks[key] = &models.RestaurantSourceLink{
+			ID:           s.newID(),
+			RestaurantID: keep.ID,
+			Source:       key.source,
+			ExternalID:   key.externalID,
+			CreatedAt:    time.Now(),
+		}
+	}
+
+	for _, slug := range s.restaurantCuisines[duplicate.ID] {
+		if !containsString(s.restaurantCuisines[keep.ID], slug) {
+			s.restaurantCuisines[keep.ID] = append(s.restaurantCuisines[keep.ID], slug)
+		}
+	}
+	if hours, ok := s.openingHours[duplicate.ID]; ok {
+		if _, kept := s.openingHours[keep.ID]; !kept {
+			hours.RestaurantID = keep.ID
+			s.openingHours[keep.ID] = hours
+		}
+		delete(s.openingHours, duplicate.ID)
+	}
+
+	now := time.Now()
+	fillRestaurant(keep, duplicate)
+	keep.UpdatedAt = now
+
+	pair := models.DuplicateCandidate{RestaurantID: keep.ID, DuplicateID: duplicate.ID}
+	orderPair(&pair)
+	candidates := s.duplicates[:0]
+	for _, candidate := range s.duplicates {
+		if candidate.RestaurantID == pair.RestaurantID && candidate.DuplicateID == pair.DuplicateID {
+			candidate.Status, candidate.ResolvedAt = models.DuplicateMerged, &now
+		} else if candidate.Status == models.DuplicatePending &&
+			(candidate.RestaurantID == duplicate.ID || candidate.DuplicateID == duplicate.ID) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	s.duplicates = candidates
+
+	duplicate.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	return result, nil
+}
+
+func (r *MemoryRestaurantRepository) ListCuisines(ctx context.Context) ([]models.Cuisine, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	cuisines := []models.Cuisine{}
+	for _, cuisine := range r.store.cuisines {
+		cuisines = append(cuisines, *cuisine)
+	}
+	sort.Slice(cuisines, func(i, j int) bool {
+		return cuisines[i].Name < cuisines[j].Name
+	})
+	return cuisines, nil
+}
+
+type MemoryMenuRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryMenuRepository) ListByRestaurant(ctx context.Context, restaurantID uint, filter MenuItemFilter) ([]models.MenuItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	items := []models.MenuItem{}
+	for _, item := range r.store.menuItems {
+		if item.RestaurantID != restaurantID || item.DeletedAt.Valid ||
+			filter.Category != "" && item.Category != filter.Category ||
+			!filter.Diet.matches(item) {
+			continue
+		}
+		items = append(items, r.store.withOptions(item))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+func (r *MemoryMenuRepository) Cheapest(ctx context.Context, filter DealFilter) ([]models.MenuItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	items := []models.MenuItem{}
+	prices := make(map[uint]float64)
+	for _, item := range r.store.menuItems {
+		restaurant, ok := r.store.restaurants[item.RestaurantID]
+		if !ok || restaurant.DeletedAt.Valid || item.DeletedAt.Valid || !item.IsAvailable ||
+			filter.City != "" && restaurant.City != filter.City ||
+			filter.Category != "" && item.Category != filter.Category ||
+			filter.MaxPrice != nil && !r.store.withinDealPrice(item, *filter.MaxPrice) ||
+			!filter.Diet.matches(item) {
+			continue
+		}
+		euros, variant := r.store.dealPrice(item)
+
+		found := *item
+		owner := *restaurant
+		found.Restaurant = &owner
+		found.DealVariant = variant
+		prices[found.ID] = euros
+		items = append(items, found)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := prices[items[i].ID], prices[items[j].ID]
+		if a != b {
+			return a < b
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	if filter.Limit > 0 && len(items) > filter.Limit {
+		items = items[:filter.Limit]
+	}
+	return items, nil
+}
+
+func (r *MemoryMenuRepository) Get(ctx context.Context, id uint) (*models.MenuItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	item, ok := r.store.menuItems[id]
+	if !ok || item.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	found := r.store.withOptions(item)
+	found.PriceHistory = r.store.history(func(entry models.PriceHistory) bool {
+		return entry.MenuItemID == id && entry.VariantID == nil && entry.ModifierID == nil
+	})
+	for i := range found.Variants {
+		variantID := found.Variants[i].ID
+		found.Variants[i].PriceHistory = r.store.history(func(entry models.PriceHistory) bool {
+			return entry.VariantID != nil && *entry.VariantID == variantID
+		})
+	}
+	for i := range found.Modifiers {
+		modifierID := found.Modifiers[i].ID
+		found.Modifiers[i].PriceHistory = r.store.history(func(entry models.PriceHistory) bool {
+			return entry.ModifierID != nil && *entry.ModifierID == modifierID
+		})
+	}
+	return &found, nil
+}
+
+func (r *MemoryMenuRepository) Rename(ctx context.Context, item *models.MenuItem, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.menuItems[item.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	now := time.Now()
+	stored.Name, stored.UpdatedAt = name, now
+	item.Name, item.UpdatedAt = name, now
+	return nil
+}
+
+func (r *MemoryMenuRepository) Create(ctx context.Context, item *models.MenuItem, provenance models.PriceProvenance) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.createMenuItem(item, provenance)
+	return nil
+}
+
+// createMenuItem implements MenuRepository.Create; the caller holds the
+// store's lock.
+func (s *memoryStore) createMenuItem(item *models.MenuItem, provenance models.PriceProvenance) {
+	now := time.Now()
+	item.ID = s.newID()
+	item.CreatedAt, item.UpdatedAt = now, now
+	s.storeMenuItem(item)
+	s.recordPrice(item.ID, item.Price, nil, provenance)
+}
+
+// storeMenuItem saves a copy of a menu item without its relations; the
+// caller holds the store's lock.
+func (s *memoryStore) storeMenuItem(item *models.MenuItem) {
+	stored := *item
+	stored.Restaurant, stored.DealVariant = nil, nil
+	stored.PriceHistory, stored.Variants, stored.Modifiers = nil, nil, nil
+	s.menuItems[stored.ID] = &stored
+}
+
+func (r *MemoryMenuRepository) UpdatePrice(ctx context.Context, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	if item.Price.Equal(price) {
+		return false, nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.menuItems[item.ID]
+	if !ok {
+		return false, ErrNotFound
+	}
+
+	r.store.updateMenuItemPrice(stored, price, provenance)
+	item.Price, item.UpdatedAt = stored.Price, stored.UpdatedAt
+	return true, nil
+}
+
+// updateMenuItemPrice implements MenuRepository.UpdatePrice on a stored
+// item; the caller holds the store's lock.
+func (s *memoryStore) updateMenuItemPrice(stored *models.MenuItem, price money.Money, provenance models.PriceProvenance) bool {
+	if stored.Price.Equal(price) {
+		return false
+	}
+	previous := stored.Price
+	s.recordPrice(stored.ID, price, &previous, provenance)
+	stored.Price = price
+	stored.UpdatedAt = time.Now()
+	return true
+}
+
+func (r *MemoryMenuRepository) Update(ctx context.Context, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.updateMenuItem(item, price, provenance)
+}
+
+// updateMenuItem implements MenuRepository.Update; the caller holds the
+// store's lock.
+func (s *memoryStore) updateMenuItem(item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	stored, ok := s.menuItems[item.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return false, ErrNotFound
+	}
+
+	item.Price, item.CreatedAt, item.UpdatedAt = stored.Price, stored.CreatedAt, time.Now()
+	s.storeMenuItem(item)
+	stored = s.menuItems[item.ID]
+	changed := s.updateMenuItemPrice(stored, price, provenance)
+	item.Price, item.UpdatedAt = stored.Price, stored.UpdatedAt
+	return changed, nil
+}
+
+func (r *MemoryMenuRepository) Delete(ctx context.Context, item *models.MenuItem) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.menuItems[item.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	item.DeletedAt = stored.DeletedAt
+	return nil
+}
+
+func (r *MemoryMenuRepository) Restore(ctx context.Context, id uint) (*models.MenuItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.menuItems[id]
+	if !ok || !stored.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	if restaurant, ok := r.store.restaurants[stored.RestaurantID]; !ok || restaurant.DeletedAt.Valid {
+		return nil, ErrRestaurantDeleted
+	}
+	stored.DeletedAt = gorm.DeletedAt{}
+	found := *stored
+	return &found, nil
+}
+
+func (r *MemoryMenuRepository) Import(ctx context.Context, restaurantID uint, plan MenuPlan, provenance models.PriceProvenance) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	create, update, err := plan(r.store.liveItems(restaurantID))
+	if err != nil {
+		return err
+	}
+	// Check before writing anything, so that a failed import changes
+	// nothing, as in a transaction.
+	for _, item := range update {
+		if stored, ok := r.store.menuItems[item.ID]; !ok || stored.DeletedAt.Valid || stored.RestaurantID != restaurantID {
+			return ErrNotFound
+		}
+	}
+	for i := range create {
+		create[i].RestaurantID = restaurantID
+		r.store.createMenuItem(&create[i], provenance)
+	}
+	for i := range update {
+		price := update[i].Price
+		if _, err := r.store.updateMenuItem(&update[i], price, provenance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryMenuRepository) SaveVariant(ctx context.Context, variant *models.MenuItemVariant, provenance models.PriceProvenance) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return saveMemoryOption(r.store, r.store.variants, variant, provenance), nil
+}
+
+func (r *MemoryMenuRepository) SaveModifier(ctx context.Context, modifier *models.Modifier, provenance models.PriceProvenance) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return saveMemoryOption(r.store, r.store.modifiers, modifier, provenance), nil
+}
+
+// saveMemoryOption implements SaveVariant and SaveModifier; the caller holds
+// the store's lock.
+func saveMemoryOption[T any, P interface {
+	*T
+	models.PricedOption
+}](s *memoryStore, options map[uint]P, option P, provenance models.PriceProvenance) bool {
+	fields := option.Option()
+	now := time.Now()
+
+	for _, stored := range options {
+		current := stored.Option()
+		if current.MenuItemID != fields.MenuItemID || current.DeletedAt.Valid || !strings.EqualFold(current.Name, fields.Name) {
+			continue
+		}
+
+		price := fields.Price
+		*fields = *current
+		if current.Price.Equal(price) {
+			return false
+		}
+		previous := current.Price
+		s.record(option.NewPriceHistory(price, &previous, provenance))
+		current.Price, current.UpdatedAt = price, now
+		fields.Price, fields.UpdatedAt = price, now
+		return true
+	}
+
+	fields.ID = s.newID()
+	fields.CreatedAt, fields.UpdatedAt = now, now
+	stored := *option
+	options[fields.ID] = &stored
+	s.record(option.NewPriceHistory(fields.Price, nil, provenance))
+	return false
+}
+
+func (r *MemoryMenuRepository) Merge(ctx context.Context, target, source *models.MenuItem) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.menuItems[target.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	merged, ok := r.store.menuItems[source.ID]
+	if !ok || merged.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	r.store.mergeMenuItem(stored, merged)
+
+	target.Price, target.IsAvailable = stored.Price, stored.IsAvailable
+	target.Description, target.Category = stored.Description, stored.Category
+	target.Diets, target.Allergens, target.SpiceLevel = stored.Diets, stored.Allergens, stored.SpiceLevel
+	target.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// liveItems returns copies of a restaurant's live menu items in ID order.
+func (s *memoryStore) liveItems(restaurantID uint) []models.MenuItem {
+	items := []models.MenuItem{}
+	for _, item := range s.menuItems {
+		if item.RestaurantID == restaurantID && !item.DeletedAt.Valid {
+			items = append(items, *item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+// mergeMenuItem implements MenuRepository.Merge on stored items; the caller
+// holds the store's lock.
+func (s *memoryStore) mergeMenuItem(target, source *models.MenuItem) {
+	if s.lastPriced(source.ID).After(s.lastPriced(target.ID)) {
+		target.Price = source.Price
+		target.IsAvailable = source.IsAvailable
+	}
+
+	mergeMemoryOptions(s, s.variants, func(entry *models.PriceHistory) **uint { return &entry.VariantID }, target.ID, source.ID)
+	mergeMemoryOptions(s, s.modifiers, func(entry *models.PriceHistory) **uint { return &entry.ModifierID }, target.ID, source.ID)
+
+	for i := range s.priceHistory {
+		if s.priceHistory[i].MenuItemID == source.ID {
+			s.priceHistory[i].MenuItemID = target.ID
+		}
+	}
+	for i := range s.promos {
+		promo := &s.promos[i]
+		if promo.MenuItemID != nil && *promo.MenuItemID == source.ID {
+			targetID := target.ID
+			promo.MenuItemID, promo.RestaurantID = &targetID, target.RestaurantID
+		}
+	}
+
+	now := time.Now()
+	candidates := s.menuMatches[:0]
+	for _, candidate := range s.menuMatches {
+		if (candidate.MenuItemID == target.ID && candidate.NewItemID == source.ID) ||
+			(candidate.MenuItemID == source.ID && candidate.NewItemID == target.ID) {
+			candidate.Status, candidate.ResolvedAt = models.DuplicateMerged, &now
+		} else if candidate.Status == models.DuplicatePending &&
+			(candidate.MenuItemID == source.ID || candidate.NewItemID == source.ID) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	s.menuMatches = candidates
+
+	fillMenuItem(target, source)
+	target.UpdatedAt = now
+	source.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+}
+
+// lastPriced returns when a menu item's own price was last recorded, or the
+// zero time if never.
+func (s *memoryStore) lastPriced(itemID uint) time.Time {
+	var last time.Time
+	for _, entry := range s.priceHistory {
+		if entry.MenuItemID == itemID && entry.VariantID == nil && entry.ModifierID == nil && !entry.RecordedAt.Before(last) {
+			last = entry.RecordedAt
+		}
+	}
+	return last
+}
+
+// mergeMemoryOptions moves the live options of one menu item to another, as
+// the Gorm mergeOptions does. historyID returns the field of a price history
+// entry that refers to this kind of option.
+func mergeMemoryOptions[T any, P interface {
+	*T
+	models.PricedOption
+}](s *memoryStore, options map[uint]P, historyID func(*models.PriceHistory) **uint, targetID, sourceID uint) {
+	byName := make(map[string]uint)
+	for _, option := range optionsOf(options, targetID) {
+		fields := P(&option).Option()
+		byName[nameKey(fields.Name)] = fields.ID
+	}
+
+	for _, option := range optionsOf(options, sourceID) {
+		stored := options[P(&option).Option().ID].Option()
+		keptID, matched := byName[nameKey(stored.Name)]
+
+		for i := range s.priceHistory {
+			entry := &s.priceHistory[i]
+			if id := historyID(entry); *id != nil && **id == stored.ID {
+				entry.MenuItemID = targetID
+				if matched {
+					optionID := keptID
+					*id = &optionID
+				}
+			}
+		}
+
+		if matched {
+			stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			continue
+		}
+		stored.MenuItemID = targetID
+		byName[nameKey(stored.Name)] = stored.ID
+	}
+}
+
+func (r *MemoryMenuRepository) GetOption(ctx context.Context, option models.PricedOption, menuItemID, id uint) error {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var stored models.PricedOption
+	switch option := option.(type) {
+	case *models.MenuItemVariant:
+		if variant, ok := r.store.variants[id]; ok {
+			*option = *variant
+			stored = variant
+		}
+	case *models.Modifier:
+		if modifier, ok := r.store.modifiers[id]; ok {
+			*option = *modifier
+			stored = modifier
+		}
+	}
+	if stored == nil || stored.Option().MenuItemID != menuItemID || stored.Option().DeletedAt.Valid {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MemoryMenuRepository) OptionNameTaken(ctx context.Context, option models.PricedOption) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	fields := option.Option()
+	taken := func(other *models.MenuItemOption) bool {
+		return other.ID != fields.ID && other.MenuItemID == fields.MenuItemID && !other.DeletedAt.Valid &&
+			strings.EqualFold(other.Name, fields.Name)
+	}
+	switch option.(type) {
+	case *models.MenuItemVariant:
+		for _, variant := range r.store.variants {
+			if taken(variant.Option()) {
+				return true, nil
+			}
+		}
+	case *models.Modifier:
+		for _, modifier := range r.store.modifiers {
+			if taken(modifier.Option()) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryMenuRepository) CreateOption(ctx context.Context, option models.PricedOption, provenance models.PriceProvenance) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	fields := option.Option()
+	now := time.Now()
+	fields.ID = r.store.newID()
+	fields.CreatedAt, fields.UpdatedAt = now, now
+	r.store.storeOption(option)
+	r.store.record(option.NewPriceHistory(fields.Price, nil, provenance))
+	return nil
+}
+
+func (r *MemoryMenuRepository) UpdateOption(ctx context.Context, option models.PricedOption, price money.Money, provenance models.PriceProvenance) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	fields := option.Option()
+	changed := !fields.Price.Equal(price)
+	if changed {
+		previous := fields.Price
+		r.store.record(option.NewPriceHistory(price, &previous, provenance))
+		fields.Price = price
+	}
+	fields.UpdatedAt = time.Now()
+	r.store.storeOption(option)
+	return changed, nil
+}
+
+func (r *MemoryMenuRepository) DeleteOption(ctx context.Context, option models.PricedOption) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	option.Option().DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.storeOption(option)
+	return nil
+}
+
+// storeOption saves a copy of a variant or modifier; the caller holds the
+// store's lock.
+func (s *memoryStore) storeOption(option models.PricedOption) {
+	switch option := option.(type) {
+	case *models.MenuItemVariant:
+		stored := *option
+		stored.PriceHistory = nil
+		s.variants[stored.ID] = &stored
+	case *models.Modifier:
+		stored := *option
+		stored.PriceHistory = nil
+		s.modifiers[stored.ID] = &stored
+	}
+}
+
+type MemoryPriceHistoryRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryPriceHistoryRepository) ListByMenuItem(ctx context.Context, menuItemID uint, filter PriceHistoryFilter) ([]models.PriceHistory, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	history := []models.PriceHistory{}
+	for i := len(r.store.priceHistory) - 1; i >= 0; i-- {
+		entry := r.store.priceHistory[i]
+		if entry.MenuItemID == menuItemID && (filter.Source == "" || entry.Source == filter.Source) &&
+			sameID(entry.VariantID, filter.VariantID) && sameID(entry.ModifierID, filter.ModifierID) {
+			history = append(history, entry)
+		}
+	}
+	return history, nil
+}
+
+type MemoryPromoRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryPromoRepository) ListByRestaurant(ctx context.Context, restaurantID uint) ([]models.PromoPrice, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	promos := []models.PromoPrice{}
+	for _, promo := range r.store.promos {
+		if promo.RestaurantID != restaurantID || !promo.IsActive || promo.DeletedAt.Valid {
+			continue
+		}
+		if restaurant, ok := r.store.restaurants[restaurantID]; ok {
+			owner := *restaurant
+			promo.Restaurant = &owner
+		}
+		promos = append(promos, promo)
+	}
+	return promos, nil
+}
+
+func (r *MemoryPromoRepository) Get(ctx context.Context, id uint) (*models.PromoPrice, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, promo := range r.store.promos {
+		if promo.ID == id && !promo.DeletedAt.Valid {
+			return &promo, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPromoRepository) Create(ctx context.Context, promo *models.PromoPrice) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	promo.ID = r.store.newID()
+	promo.CreatedAt, promo.UpdatedAt = now, now
+
+	stored := *promo
+	stored.Restaurant = nil
+	r.store.promos = append(r.store.promos, stored)
+	return nil
+}
+
+func (r *MemoryPromoRepository) Update(ctx context.Context, promo *models.PromoPrice) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.promos {
+		if r.store.promos[i].ID == promo.ID {
+			promo.UpdatedAt = time.Now()
+			stored := *promo
+			stored.Restaurant = nil
+			r.store.promos[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryPromoRepository) Delete(ctx context.Context, promo *models.PromoPrice) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.promos {
+		if r.store.promos[i].ID == promo.ID {
+			r.store.promos[i].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type MemoryDuplicateRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryDuplicateRepository) Queue(ctx context.Context, candidate *models.DuplicateCandidate) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	orderPair(candidate)
+	for _, queued := range r.store.duplicates {
+		if queued.RestaurantID == candidate.RestaurantID && queued.DuplicateID == candidate.DuplicateID {
+			return false, nil
+		}
+	}
+	if candidate.Status == "" {
+		candidate.Status = models.DuplicatePending
+	}
+	candidate.ID, candidate.CreatedAt = r.store.newID(), time.Now()
+	r.store.duplicates = append(r.store.duplicates, *candidate)
+	return true, nil
+}
+
+func (r *MemoryDuplicateRepository) List(ctx context.Context, status string, limit int) ([]models.DuplicateCandidate, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	candidates := []models.DuplicateCandidate{}
+	for _, candidate := range r.store.duplicates {
+		if candidate.Status != status {
+			continue
+		}
+		if restaurant, ok := r.store.restaurants[candidate.RestaurantID]; ok {
+			copied := *restaurant
+			candidate.Restaurant = &copied
+		}
+		if duplicate, ok := r.store.restaurants[candidate.DuplicateID]; ok {
+			copied := *duplicate
+			candidate.Duplicate = &copied
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+func (r *MemoryDuplicateRepository) Get(ctx context.Context, id uint) (*models.DuplicateCandidate, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, candidate := range r.store.duplicates {
+		if candidate.ID == id {
+			return &candidate, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryDuplicateRepository) Dismiss(ctx context.Context, candidate *models.DuplicateCandidate) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.duplicates {
+		stored := &r.store.duplicates[i]
+		if stored.ID == candidate.ID {
+			now := time.Now()
+			stored.Status, stored.ResolvedAt = models.DuplicateDismissed, &now
+			candidate.Status, candidate.ResolvedAt = stored.Status, stored.ResolvedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type MemoryMenuMatchRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryMenuMatchRepository) Queue(ctx context.Context, candidate *models.MenuMatchCandidate) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, queued := range r.store.menuMatches {
+		if queued.MenuItemID == candidate.MenuItemID && queued.NewItemID == candidate.NewItemID {
+			return false, nil
+		}
+	}
+	if candidate.Status == "" {
+		candidate.Status = models.DuplicatePending
+	}
+	candidate.ID, candidate.CreatedAt = r.store.newID(), time.Now()
+	r.store.menuMatches = append(r.store.menuMatches, *candidate)
+	return true, nil
+}
+
+func (r *MemoryMenuMatchRepository) List(ctx context.Context, status string, limit int) ([]models.MenuMatchCandidate, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	candidates := []models.MenuMatchCandidate{}
+	for _, candidate := range r.store.menuMatches {
+		if candidate.Status != status {
+			continue
+		}
+		if item, ok := r.store.menuItems[candidate.MenuItemID]; ok {
+			copied := *item
+			candidate.MenuItem = &copied
+		}
+		if item, ok := r.store.menuItems[candidate.NewItemID]; ok {
+			copied := *item
+			candidate.NewItem = &copied
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+func (r *MemoryMenuMatchRepository) Get(ctx context.Context, id uint) (*models.MenuMatchCandidate, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, candidate := range r.store.menuMatches {
+		if candidate.ID == id {
+			return &candidate, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryMenuMatchRepository) Dismiss(ctx context.Context, candidate *models.MenuMatchCandidate) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.menuMatches {
+		stored := &r.store.menuMatches[i]
+		if stored.ID == candidate.ID {
+			now := time.Now()
+			stored.Status, stored.ResolvedAt = models.DuplicateDismissed, &now
+			candidate.Status, candidate.ResolvedAt = stored.Status, stored.ResolvedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type MemoryScrapeRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryScrapeRepository) Save(ctx context.Context, data *models.ScrapedData) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	data.ID = r.store.newID()
+	r.store.scrapes = append(r.store.scrapes, *data)
+	return nil
+}
+
+func (r *MemoryScrapeRepository) LastScrapedAt(ctx context.Context) (time.Time, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var last time.Time
+	for _, data := range r.store.scrapes {
+		if data.ScrapedAt.After(last) {
+			last = data.ScrapedAt
+		}
+	}
+	return last, nil
+}
+
+type MemoryExchangeRateRepository struct {
+	store *memoryStore
+}
+
+func (r *MemoryExchangeRateRepository) Save(ctx context.Context, rates []models.ExchangeRate) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, rate := range rates {
+		replaced := false
+		for i := range r.store.rates {
+			stored := &r.store.rates[i]
+			if stored.Currency == rate.Currency && stored.Date.Equal(rate.Date) {
+				stored.Rate, stored.Source = rate.Rate, rate.Source
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			rate.ID = r.store.newID()
+			rate.CreatedAt = time.Now()
+			r.store.rates = append(r.store.rates, rate)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryExchangeRateRepository) List(ctx context.Context) ([]models.ExchangeRate, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	rates := append([]models.ExchangeRate(nil), r.store.rates...)
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Currency != rates[j].Currency {
+			return rates[i].Currency < rates[j].Currency
+		}
+		return rates[i].Date.Before(rates[j].Date)
+	})
+	return rates, nil
+}
+
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sameID(a, b *uint) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+)
+
+var testProvenance = models.PriceProvenance{Source: models.PriceSourceManual}
+
+func seedMenu(t *testing.T, repos Repositories, prices map[string]money.Money) *models.Restaurant {
+	t.Helper()
+	ctx := context.Background()
+	restaurant := &models.Restaurant{Name: "Luigi's", City: "Springfield", ExternalID: "manual:luigis"}
+	if err := repos.Restaurants.Create(ctx, restaurant); err != nil {
+		t.Fatal(err)
+	}
+	for name, price := range prices {
+		item := &models.MenuItem{RestaurantID: restaurant.ID, Name: name, Price: price, IsAvailable: true}
+		if err := repos.Menus.Create(ctx, item, testProvenance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return restaurant
+}
+
+func itemNames(items []models.MenuItem) []string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	return names
+}
+
+func equalNames(got []models.MenuItem, want ...string) bool {
+	names := itemNames(got)
+	if len(names) != len(want) {
+		return false
+	}
+	for i := range names {
+		if names[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCheapestWithoutExchangeRates(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	seedMenu(t, repos, map[string]money.Money{
+		"Pizza":    money.New(1200, "USD"),
+		"Salad":    money.New(800, "USD"),
+		"Tiramisu": money.New(600, "USD"),
+	})
+
+	deals, err := repos.Menus.Cheapest(ctx, DealFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(deals, "Tiramisu", "Salad", "Pizza") {
+		t.Errorf("Cheapest() = %v, want cheapest first", itemNames(deals))
+	}
+
+	max := money.New(1000, "USD")
+	deals, err = repos.Menus.Cheapest(ctx, DealFilter{MaxPrice: &max, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(deals, "Tiramisu", "Salad") {
+		t.Errorf("Cheapest() under 10 USD = %v", itemNames(deals))
+	}
+
+	// Without rates, a limit in another currency can't be compared.
+	max = money.New(5000, "GBP")
+	deals, err = repos.Menus.Cheapest(ctx, DealFilter{MaxPrice: &max, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deals) != 0 {
+		t.Errorf("Cheapest() under 50 GBP = %v, want none", itemNames(deals))
+	}
+}
+
+func TestCheapestMaxPriceCountsVariants(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	restaurant := seedMenu(t, repos, map[string]money.Money{
+		"Pizza": money.New(1200, "USD"),
+		"Salad": money.New(800, "USD"),
+	})
+	items, err := repos.Menus.ListByRestaurant(ctx, restaurant.ID, MenuItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pizza := items[0]
+	if pizza.Name != "Pizza" {
+		pizza = items[1]
+	}
+	slice := &models.MenuItemVariant{MenuItemOption: models.MenuItemOption{MenuItemID: pizza.ID, Name: "Slice", Price: money.New(350, "USD"), IsAvailable: true}}
+	if err := repos.Menus.CreateOption(ctx, slice, testProvenance); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the variant is under the limit, with no rates to fall back on.
+	max := money.New(500, "USD")
+	deals, err := repos.Menus.Cheapest(ctx, DealFilter{MaxPrice: &max, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(deals, "Pizza") || deals[0].DealVariant == nil || deals[0].DealVariant.ID != slice.ID {
+		t.Errorf("Cheapest() under 5 USD = %v, want the pizza by the slice", itemNames(deals))
+	}
+}
+
+func TestCheapestAcrossCurrencies(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	seedMenu(t, repos, map[string]money.Money{
+		"Pizza":    money.New(1200, "USD"),
+		"Pasta":    money.New(1000, "EUR"),
+		"Tiramisu": money.New(500, "GBP"),
+	})
+	err := repos.ExchangeRates.Save(ctx, []models.ExchangeRate{
+		{Currency: "USD", Date: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), Rate: 1.1},
+		{Currency: "GBP", Date: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), Rate: 0.85},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 12 USD is about 10.91 EUR and 5 GBP about 5.88 EUR. Euro prices
+	// compare without a stored rate.
+	deals, err := repos.Menus.Cheapest(ctx, DealFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(deals, "Tiramisu", "Pasta", "Pizza") {
+		t.Errorf("Cheapest() = %v", itemNames(deals))
+	}
+
+	max := money.New(1050, "EUR")
+	deals, err = repos.Menus.Cheapest(ctx, DealFilter{MaxPrice: &max, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalNames(deals, "Tiramisu", "Pasta") {
+		t.Errorf("Cheapest() under 10.50 EUR = %v", itemNames(deals))
+	}
+}
+
+func TestNearbyAtSearchPoint(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	restaurant := &models.Restaurant{Name: "Luigi's", ExternalID: "manual:luigis", Latitude: 23.193936, Longitude: 19.452675}
+	if err := repos.Restaurants.Create(ctx, restaurant); err != nil {
+		t.Fatal(err)
+	}
+
+	nearby, err := repos.Restaurants.Nearby(ctx, restaurant.Latitude, restaurant.Longitude, 50, RestaurantFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nearby) != 1 || nearby[0].ID != restaurant.ID {
+		t.Errorf("Nearby() at the restaurant's own location = %v", nearby)
+	}
+}
+
+func TestRestaurantExternalIDUnique(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	luigis := seedMenu(t, repos, nil)
+	marios := &models.Restaurant{Name: "Mario's", ExternalID: luigis.ExternalID}
+	if err := repos.Restaurants.Create(ctx, marios); err != ErrDuplicate {
+		t.Fatalf("Create() with a taken external ID = %v, want ErrDuplicate", err)
+	}
+
+	marios.ExternalID = "manual:marios"
+	if err := repos.Restaurants.Create(ctx, marios); err != nil {
+		t.Fatal(err)
+	}
+	// Deleted restaurants keep their external IDs.
+	if err := repos.Restaurants.Delete(ctx, luigis.ID); err != nil {
+		t.Fatal(err)
+	}
+	marios.ExternalID = luigis.ExternalID
+	if err := repos.Restaurants.Update(ctx, marios); err != ErrDuplicate {
+		t.Errorf("Update() to a taken external ID = %v, want ErrDuplicate", err)
+	}
+	marios.ExternalID = "manual:marios"
+	if err := repos.Restaurants.Update(ctx, marios); err != nil {
+		t.Errorf("Update() keeping its own external ID = %v", err)
+	}
+}
+
+func TestMergeResolvesMenuMatches(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	restaurant := seedMenu(t, repos, map[string]money.Money{
+		"Classic Burger":       money.New(1299, "USD"),
+		"Classic Cheeseburger": money.New(1399, "USD"),
+		"Cheeseburger":         money.New(1399, "USD"),
+	})
+	items, err := repos.Menus.ListByRestaurant(ctx, restaurant.ID, MenuItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]*models.MenuItem)
+	for i := range items {
+		byName[items[i].Name] = &items[i]
+	}
+	burger, cheeseburger, other := byName["Classic Burger"], byName["Classic Cheeseburger"], byName["Cheeseburger"]
+
+	queued := []*models.MenuMatchCandidate{
+		{MenuItemID: burger.ID, NewItemID: cheeseburger.ID, Confidence: 0.81},
+		{MenuItemID: other.ID, NewItemID: cheeseburger.ID, Confidence: 0.8},
+		{MenuItemID: burger.ID, NewItemID: other.ID, Confidence: 0.76},
+	}
+	for _, candidate := range queued {
+		if _, err := repos.MenuMatches.Queue(ctx, candidate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if added, err := repos.MenuMatches.Queue(ctx, &models.MenuMatchCandidate{MenuItemID: burger.ID, NewItemID: cheeseburger.ID}); err != nil || added {
+		t.Errorf("queueing a pair again = %v, %v, want it left alone", added, err)
+	}
+
+	if err := repos.Menus.Merge(ctx, burger, cheeseburger); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := repos.MenuMatches.List(ctx, models.DuplicateMerged, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 || merged[0].ID != queued[0].ID || merged[0].ResolvedAt == nil {
+		t.Errorf("merged candidates = %+v, want the merged pair", merged)
+	}
+	pending, err := repos.MenuMatches.List(ctx, models.DuplicatePending, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != queued[2].ID {
+		t.Errorf("pending candidates = %+v, want only the one without the merged item", pending)
+	}
+}
@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+)
+
+// ErrNotFound is returned when a lookup matches no record.
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicate is returned when a write would give a record a value that
+// must be unique, such as a restaurant's external ID, but another record
+// already has it.
+var ErrDuplicate = errors.New("duplicate record")
+
+// ErrRestaurantDeleted is returned when restoring a menu item of a restaurant
+// that is itself deleted.
+var ErrRestaurantDeleted = errors.New("restaurant is deleted")
+
+// RestaurantFilter narrows a restaurant listing. Empty fields don't filter.
+type RestaurantFilter struct {
+	City string
+	// Cuisines keeps restaurants with any of these cuisines, by slug. Values
+	// are normalized with models.CuisineKey, so "Middle Eastern" works too.
+	Cuisines   []string
+	PriceRange string
+	OpenAt     *OpenAt
+	// Diet keeps restaurants with at least one available item matching it.
+	Diet DietFilter
+}
+
+// DietFilter keeps menu items tagged as suitable for every one of Diets and
+// not tagged with any of ExcludeAllergens. Items whose allergens aren't
+// known are kept.
+type DietFilter struct {
+	Diets            []string
+	ExcludeAllergens []string
+}
+
+func (f DietFilter) empty() bool {
+	return len(f.Diets) == 0 && len(f.ExcludeAllergens) == 0
+}
+
+func (f DietFilter) matches(item *models.MenuItem) bool {
+	for _, diet := range f.Diets {
+		if !item.Diets.Contains(diet) {
+			return false
+		}
+	}
+	for _, allergen := range f.ExcludeAllergens {
+		if item.Allergens.Contains(allergen) {
+			return false
+		}
+	}
+	return true
+}
+
+// OpenAt keeps the restaurants that are open at Time. With Local set only
+// Time's date and wall clock count, read in each restaurant's own time
+// zone. Restaurants without opening hours never match.
+type OpenAt struct {
+	Time  time.Time
+	Local bool
+}
+
+func (o *OpenAt) matches(restaurant *models.Restaurant) bool {
+	if o == nil {
+		return true
+	}
+	return restaurant.IsOpenAt(restaurant.LocalTime(o.Time, o.Local))
+}
+
+func filterOpen(restaurants []models.Restaurant, open *OpenAt) []models.Restaurant {
+	if open == nil {
+		return restaurants
+	}
+	kept := restaurants[:0]
+	for _, restaurant := range restaurants {
+		if open.matches(&restaurant) {
+			kept = append(kept, restaurant)
+		}
+	}
+	return kept
+}
+
+type RestaurantRepository interface {
+	// List returns restaurants with their opening hours and cuisines.
+	List(ctx context.Context, filter RestaurantFilter) ([]models.Restaurant, error)
+	// Get returns a restaurant with its opening hours, cuisines, menu items
+	// and source links.
+	Get(ctx context.Context, id uint) (*models.Restaurant, error)
+	// GetByExternalID finds the restaurant an external ID is linked to, or
+	// else the one whose own ID it is. It also returns soft-deleted
+	// restaurants, so that callers can tell a deleted restaurant from a new
+	// one.
+	GetByExternalID(ctx context.Context, externalID string) (*models.Restaurant, error)
+	// Nearby returns restaurants within radius meters of a point that match
+	// filter, nearest first, with their opening hours and cuisines.
+	Nearby(ctx context.Context, lat, lng float64, radius int, filter RestaurantFilter) ([]models.Restaurant, error)
+	// Create returns ErrDuplicate when another restaurant, deleted or not,
+	// has the same external ID.
+	Create(ctx context.Context, restaurant *models.Restaurant) error
+	// Update saves a restaurant's own fields; its opening hours, cuisines
+	// and menu are left alone. Like Create, it returns ErrDuplicate when the
+	// external ID is taken.
+	Update(ctx context.Context, restaurant *models.Restaurant) error
+	// Delete soft-deletes a restaurant together with its live menu items,
+	// which get the same deletion time so that Restore can tell them from
+	// items deleted before. It returns ErrNotFound when there is no live
+	// restaurant with ID id.
+	Delete(ctx context.Context, id uint) error
+	// Restore undoes Delete, bringing back the menu items deleted with the
+	// restaurant, and returns the restaurant. It returns ErrNotFound when
+	// there is no deleted restaurant with ID id.
+	Restore(ctx context.Context, id uint) (*models.Restaurant, error)
+	// SaveOpeningHours replaces the opening hours of hours.RestaurantID.
+	SaveOpeningHours(ctx context.Context, hours *models.OpeningHours) error
+	// DeleteOpeningHours removes a restaurant's opening hours, returning
+	// ErrNotFound when it has none.
+	DeleteOpeningHours(ctx context.Context, restaurantID uint) error
+	// SetCuisines replaces a restaurant's cuisines, adding any new ones to
+	// the taxonomy, and sets its CuisineType to the first one's name.
+	SetCuisines(ctx context.Context, restaurant *models.Restaurant, cuisines []models.Cuisine) error
+	// ListCuisines returns the cuisine taxonomy ordered by name.
+	ListCuisines(ctx context.Context) ([]models.Cuisine, error)
+	// LinkSource links an external ID to link.RestaurantID, moving it if it
+	// was linked to another restaurant.
+	LinkSource(ctx context.Context, link *models.RestaurantSourceLink) error
+	// Merge merges the restaurant with ID duplicateID into the one with ID
+	// keepID, then deletes it. Its menu items are moved over, or merged as
+	// MenuRepository.Merge does into the kept restaurant's item that match
+	// picks. Its promos, scraped data, external IDs and cuisines go to the
+	// kept restaurant, and its opening hours and details such as the phone
+	// number fill in those the kept restaurant lacks. A queued candidate for
+	// the pair is marked merged, and other pending candidates involving the
+	// deleted restaurant are dropped. It returns ErrNotFound when either
+	// restaurant doesn't exist.
+	Merge(ctx context.Context, keepID, duplicateID uint, match MenuMatcher) (*RestaurantMerge, error)
+}
+
+// MenuPlan returns the changes to make to a restaurant's menu given its
+// current items, including unavailable ones: the items to create, and
+// existing items to save with their new fields and price.
+type MenuPlan func(existing []models.MenuItem) (create, update []models.MenuItem, err error)
+
+// MenuMatcher returns, for each of incoming, the one of existing it is taken
+// to be, or nil.
+type MenuMatcher func(incoming, existing []models.MenuItem) []*models.MenuItem
+
+// RestaurantMerge counts the menu items of a merged restaurant that were
+// moved over as they were and those merged into a matching item.
+type RestaurantMerge struct {
+	MovedItems  int
+	MergedItems int
+}
+
+// baseCurrency is the currency exchange rates are quoted against, as
+// exchange.Base. It has no stored rates; its rate is always 1.
+const baseCurrency = "EUR"
+
+// MenuItemFilter narrows a menu listing. Empty fields don't filter.
+type MenuItemFilter struct {
+	Category string
+	Diet     DietFilter
+}
+
+// DealFilter narrows the cheapest-items listing.
+type DealFilter struct {
+	City     string
+	Category string
+	// MaxPrice keeps items that cost at most this much once converted to its
+	// currency at the latest exchange rates. Items in a currency without a
+	// rate only match when they are in the same currency.
+	MaxPrice *money.Money
+	Diet     DietFilter
+	Limit    int
+}
+
+type MenuRepository interface {
+	// ListByRestaurant returns a restaurant's menu items with their variants
+	// and modifiers, in ID order.
+	ListByRestaurant(ctx context.Context, restaurantID uint, filter MenuItemFilter) ([]models.MenuItem, error)
+	// Get returns a menu item with its variants and modifiers, each with
+	// their price history.
+	Get(ctx context.Context, id uint) (*models.MenuItem, error)
+	// Create stores a new item and records its price in the price history.
+	Create(ctx context.Context, item *models.MenuItem, provenance models.PriceProvenance) error
+	// Cheapest returns available items of live restaurants, with their
+	// restaurant, cheapest first after converting to euros. An item costs
+	// the lower of its own price and that of its cheapest available variant,
+	// which is set as the item's DealVariant when it wins. Prices in a
+	// currency without an exchange rate compare by their own amount. Promos
+	// aren't taken into account.
+	Cheapest(ctx context.Context, filter DealFilter) ([]models.MenuItem, error)
+	// UpdatePrice stores a new price and records it in the price history.
+	// Nothing is written when the price is unchanged; the return value
+	// reports whether a change was recorded.
+	UpdatePrice(ctx context.Context, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error)
+	// Update saves an item's own fields and its new price, recording a
+	// price change in the history as UpdatePrice does. The return value
+	// reports whether the price changed.
+	Update(ctx context.Context, item *models.MenuItem, price money.Money, provenance models.PriceProvenance) (bool, error)
+	// Delete soft-deletes an item, keeping its price history.
+	Delete(ctx context.Context, item *models.MenuItem) error
+	// Restore undoes Delete and returns the item. It returns ErrNotFound
+	// when there is no deleted item with ID id, and ErrRestaurantDeleted
+	// when the item's restaurant is deleted.
+	Restore(ctx context.Context, id uint) (*models.MenuItem, error)
+	// Import applies plan to a restaurant's menu in one transaction,
+	// creating and updating items as Create and Update do. plan sees the
+	// menu as it is within the transaction.
+	Import(ctx context.Context, restaurantID uint, plan MenuPlan, provenance models.PriceProvenance) error
+	// Rename changes an item's name, keeping its price history.
+	Rename(ctx context.Context, item *models.MenuItem, name string) error
+	// SaveVariant stores a new variant, or updates the price of the item's
+	// variant of the same name (ignoring case), recording the price in the
+	// history when it is new or changed. The return value reports whether
+	// an existing variant's price changed.
+	SaveVariant(ctx context.Context, variant *models.MenuItemVariant, provenance models.PriceProvenance) (bool, error)
+	// SaveModifier is SaveVariant for modifiers.
+	SaveModifier(ctx context.Context, modifier *models.Modifier, provenance models.PriceProvenance) (bool, error)
+	// Merge merges source into target, which may belong to another
+	// restaurant, then deletes source. target keeps its name and gains
+	// source's price history and promos, and takes source's price and
+	// availability when source's price was recorded last. source's variants
+	// and modifiers are moved over, or, when target has one of the same name,
+	// have their price history moved to it. Empty details of target, such as
+	// its description and dietary tags, are filled in from source. A queued
+	// menu match between the two is marked merged, and pending ones of
+	// source are dropped.
+	Merge(ctx context.Context, target, source *models.MenuItem) error
+
+	// GetOption loads the live variant or modifier with ID id of the menu
+	// item into option, returning ErrNotFound when there is none.
+	GetOption(ctx context.Context, option models.PricedOption, menuItemID, id uint) error
+	// OptionNameTaken reports whether another live option of the same kind
+	// and item has the option's name, ignoring case.
+	OptionNameTaken(ctx context.Context, option models.PricedOption) (bool, error)
+	// CreateOption stores a new variant or modifier and records its price in
+	// the price history.
+	CreateOption(ctx context.Context, option models.PricedOption, provenance models.PriceProvenance) error
+	// UpdateOption saves an option's fields and its new price, recording a
+	// price change in the history. The return value reports whether the
+	// price changed.
+	UpdateOption(ctx context.Context, option models.PricedOption, price money.Money, provenance models.PriceProvenance) (bool, error)
+	// DeleteOption soft-deletes an option, keeping its price history.
+	DeleteOption(ctx context.Context, option models.PricedOption) error
+}
+
+// PriceHistoryFilter narrows a price history listing. Empty fields don't
+// filter.
+type PriceHistoryFilter struct {
+	Source string
+	// VariantID or ModifierID select the history of one of the item's
+	// variants or modifiers instead of the item's own.
+	VariantID  *uint
+	ModifierID *uint
+}
+
+type PriceHistoryRepository interface {
+	// ListByMenuItem returns the price history of an item, or of one of its
+	// variants or modifiers, newest first.
+	ListByMenuItem(ctx context.Context, menuItemID uint, filter PriceHistoryFilter) ([]models.PriceHistory, error)
+}
+
+type PromoRepository interface {
+	// ListByRestaurant returns the active promos of a restaurant and its
+	// items, with the restaurant, for working out their times.
+	ListByRestaurant(ctx context.Context, restaurantID uint) ([]models.PromoPrice, error)
+	// Get returns a promo, active or not, returning ErrNotFound when there
+	// is none.
+	Get(ctx context.Context, id uint) (*models.PromoPrice, error)
+	Create(ctx context.Context, promo *models.PromoPrice) error
+	Update(ctx context.Context, promo *models.PromoPrice) error
+	Delete(ctx context.Context, promo *models.PromoPrice) error
+}
+
+type ScrapeRepository interface {
+	Save(ctx context.Context, data *models.ScrapedData) error
+	// LastScrapedAt returns when data was last stored, or the zero time if
+	// never.
+	LastScrapedAt(ctx context.Context) (time.Time, error)
+}
+
+type DuplicateRepository interface {
+	// Queue adds a candidate pair to the review queue, ordering it so that
+	// RestaurantID is the older restaurant. Pairs already in the queue,
+	// including reviewed ones, are left alone; the return value reports
+	// whether the candidate was added.
+	Queue(ctx context.Context, candidate *models.DuplicateCandidate) (bool, error)
+	// List returns up to limit candidates with status, highest score first,
+	// with both restaurants, including merged ones that were deleted.
+	List(ctx context.Context, status string, limit int) ([]models.DuplicateCandidate, error)
+	Get(ctx context.Context, id uint) (*models.DuplicateCandidate, error)
+	// Dismiss marks a candidate as two different places.
+	Dismiss(ctx context.Context, candidate *models.DuplicateCandidate) error
+}
+
+type MenuMatchRepository interface {
+	// Queue adds a candidate to the review queue. Pairs already in the
+	// queue, including reviewed ones, are left alone; the return value
+	// reports whether the candidate was added.
+	Queue(ctx context.Context, candidate *models.MenuMatchCandidate) (bool, error)
+	// List returns up to limit candidates with status, highest confidence
+	// first, with both items, including merged ones that were deleted.
+	List(ctx context.Context, status string, limit int) ([]models.MenuMatchCandidate, error)
+	Get(ctx context.Context, id uint) (*models.MenuMatchCandidate, error)
+	// Dismiss marks a candidate as two different items.
+	Dismiss(ctx context.Context, candidate *models.MenuMatchCandidate) error
+}
+
+type ExchangeRateRepository interface {
+	// Save stores rates, replacing any already stored for the same currency
+	// and day.
+	Save(ctx context.Context, rates []models.ExchangeRate) error
+	List(ctx context.Context) ([]models.ExchangeRate, error)
+}
+
+// Repositories bundles the repositories a component may need.
+type Repositories struct {
+	Restaurants   RestaurantRepository
+	Menus         MenuRepository
+	PriceHistory  PriceHistoryRepository
+	Promos        PromoRepository
+	Scrapes       ScrapeRepository
+	Duplicates    DuplicateRepository
+	MenuMatches   MenuMatchRepository
+	ExchangeRates ExchangeRateRepository
+}
+
+// orderPair orders a candidate's restaurants so that the older, with the
+// lower ID, comes first.
+func orderPair(candidate *models.DuplicateCandidate) {
+	if candidate.DuplicateID < candidate.RestaurantID {
+		candidate.RestaurantID, candidate.DuplicateID = candidate.DuplicateID, candidate.RestaurantID
+	}
+}
+
+// nameKey folds a name for comparison, ignoring case and spacing.
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// fillRestaurant copies the details of duplicate that keep lacks onto keep.
+func fillRestaurant(keep, duplicate *models.Restaurant) {
+	for _, field := range []struct{ dst, src *string }{
+		{&keep.Address, &duplicate.Address},
+		{&keep.City, &duplicate.City},
+		{&keep.State, &duplicate.State},
+		{&keep.ZipCode, &duplicate.ZipCode},
+		{&keep.Country, &duplicate.Country},
+		{&keep.CuisineType, &duplicate.CuisineType},
+		{&keep.Phone, &duplicate.Phone},
+		{&keep.Website, &duplicate.Website},
+		{&keep.PriceRange, &duplicate.PriceRange},
+		{&keep.TimeZone, &duplicate.TimeZone},
+	} {
+		if *field.dst == "" {
+			*field.dst = *field.src
+		}
+	}
+	if keep.UTCOffsetMinutes == nil {
+		keep.UTCOffsetMinutes = duplicate.UTCOffsetMinutes
+	}
+}
+
+// fillMenuItem copies the details of source that target lacks onto target.
+func fillMenuItem(target, source *models.MenuItem) {
+	if target.Description == "" {
+		target.Description = source.Description
+	}
+	if target.Category == "" {
+		target.Category = source.Category
+	}
+	if len(target.Diets) == 0 {
+		target.Diets = source.Diets
+	}
+	if len(target.Allergens) == 0 {
+		target.Allergens = source.Allergens
+	}
+	if target.SpiceLevel == nil {
+		target.SpiceLevel = source.SpiceLevel
+	}
+}
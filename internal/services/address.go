@@ -0,0 +1,231 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"cheapeats-api/internal/models"
+)
+
+// PostalAddress is the structured part of a restaurant's address. Country is
+// the English country name as Places spells it, such as "United States".
+type PostalAddress struct {
+	City    string
+	State   string
+	ZipCode string
+	Country string
+}
+
+// Apply copies the address onto the restaurant, clearing fields it doesn't
+// have.
+func (a PostalAddress) Apply(restaurant *models.Restaurant) {
+	restaurant.City = a.City
+	restaurant.State = a.State
+	restaurant.ZipCode = a.ZipCode
+	restaurant.Country = a.Country
+}
+
+// placeAddress returns the structured address of a place, from its address
+// components when Places returned them and otherwise parsed from formatted.
+// It returns false when neither gave a city or postal code.
+func placeAddress(components []PlaceAddressComponent, formatted string) (PostalAddress, bool) {
+	if address, ok := AddressFromComponents(components); ok {
+		return address, true
+	}
+	return ParseAddress(formatted)
+}
+
+// cityComponentTypes are the component types that can name a city, best
+// first: postal towns stand in for localities in the UK, and sublocalities
+// such as Brooklyn where Places has no locality.
+var cityComponentTypes = []string{"locality", "postal_town", "sublocality_level_1", "sublocality", "administrative_area_level_3"}
+
+// AddressFromComponents maps Places address components to a PostalAddress.
+// It returns false when they have no city or postal code.
+func AddressFromComponents(components []PlaceAddressComponent) (PostalAddress, bool) {
+	find := func(typ string) *PlaceAddressComponent {
+		for i := range components {
+			for _, t := range components[i].Types {
+				if t == typ {
+					return &components[i]
+				}
+			}
+		}
+		return nil
+	}
+
+	var address PostalAddress
+	for _, typ := range cityComponentTypes {
+		if c := find(typ); c != nil {
+			address.City = c.LongName
+			break
+		}
+	}
+	if c := find("administrative_area_level_1"); c != nil {
+		address.State = c.ShortName
+	}
+	if c := find("postal_code"); c != nil {
+		address.ZipCode = c.LongName
+		if suffix := find("postal_code_suffix"); suffix != nil {
+			address.ZipCode += "-" + suffix.LongName
+		}
+	}
+	if c := find("country"); c != nil {
+		address.Country = c.LongName
+	}
+	return address, address.City != "" || address.ZipCode != ""
+}
+
+// addressFormat parses the locality part of an address, such as
+// "IL 62701" or "10117 Berlin". prev is the part before it, which is the
+// city in formats that don't include one.
+type addressFormat func(part, prev string) (PostalAddress, bool)
+
+var (
+	usStateZip      = regexp.MustCompile(`^([A-Z]{2}) (\d{5}(?:-\d{4})?)$`)
+	caProvincePost  = regexp.MustCompile(`^([A-Z]{2}) ([A-Z]\d[A-Z] ?\d[A-Z]\d)$`)
+	ukTownPostcode  = regexp.MustCompile(`^(?:(.+) )?([A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2})$`)
+	euPostcodeTown  = regexp.MustCompile(`^(?:[A-Z]{1,2}-)?(\d{4} ?[A-Z]{2}|\d{4}-\d{3}|\d{2}-\d{3}|\d{3} \d{2}|\d{4,5}) (.+?)(?: ([A-Z]{2}))?$`)
+	usStateOnly     = regexp.MustCompile(`^[A-Z]{2}$`)
+	addressFormatUS = func(part, prev string) (PostalAddress, bool) {
+		m := usStateZip.FindStringSubmatch(part)
+		if m == nil || prev == "" {
+			return PostalAddress{}, false
+		}
+		return PostalAddress{City: prev, State: m[1], ZipCode: m[2]}, true
+	}
+	addressFormatCA = func(part, prev string) (PostalAddress, bool) {
+		m := caProvincePost.FindStringSubmatch(part)
+		if m == nil || prev == "" {
+			return PostalAddress{}, false
+		}
+		return PostalAddress{City: prev, State: m[1], ZipCode: m[2]}, true
+	}
+	addressFormatUK = func(part, prev string) (PostalAddress, bool) {
+		m := ukTownPostcode.FindStringSubmatch(part)
+		if m == nil {
+			return PostalAddress{}, false
+		}
+		city := m[1]
+		if city == "" {
+			city = prev
+		}
+		return PostalAddress{City: city, ZipCode: m[2]}, city != ""
+	}
+	addressFormatEU = func(part, prev string) (PostalAddress, bool) {
+		m := euPostcodeTown.FindStringSubmatch(part)
+		if m == nil {
+			return PostalAddress{}, false
+		}
+		return PostalAddress{City: m[2], State: m[3], ZipCode: m[1]}, true
+	}
+)
+
+// country is a country the fallback parser knows the address format of.
+type country struct {
+	name    string
+	aliases []string
+	formats []addressFormat
+}
+
+var euFormats = []addressFormat{addressFormatEU}
+
+var countries = []country{
+	{"United States", []string{"USA", "US", "United States of America"}, []addressFormat{addressFormatUS}},
+	{"Canada", nil, []addressFormat{addressFormatCA}},
+	{"United Kingdom", []string{"UK", "GB", "Great Britain", "England", "Scotland", "Wales", "Northern Ireland"}, []addressFormat{addressFormatUK}},
+	{"Austria", []string{"Österreich"}, euFormats},
+	{"Belgium", []string{"België", "Belgique"}, euFormats},
+	{"Czechia", []string{"Czech Republic", "Česko"}, euFormats},
+	{"Denmark", []string{"Danmark"}, euFormats},
+	{"Finland", []string{"Suomi"}, euFormats},
+	{"France", nil, euFormats},
+	{"Germany", []string{"Deutschland"}, euFormats},
+	{"Greece", []string{"Ελλάδα"}, euFormats},
+	{"Italy", []string{"Italia"}, euFormats},
+	{"Luxembourg", nil, euFormats},
+	{"Netherlands", []string{"The Netherlands", "Nederland"}, euFormats},
+	{"Norway", []string{"Norge"}, euFormats},
+	{"Poland", []string{"Polska"}, euFormats},
+	{"Portugal", nil, euFormats},
+	{"Spain", []string{"España"}, euFormats},
+	{"Sweden", []string{"Sverige"}, euFormats},
+	{"Switzerland", []string{"Schweiz", "Suisse", "Svizzera"}, euFormats},
+}
+
+// canadaCode is Canada's country code, which some addresses end in. It is
+// also California's state code, so it is only taken as the country when
+// the rest of the address is in the Canadian format.
+const canadaCode = "CA"
+
+// unknownCountryFormats are tried, in order, when the address doesn't end
+// in a known country.
+var unknownCountryFormats = []addressFormat{addressFormatUS, addressFormatCA, addressFormatUK, addressFormatEU}
+
+func lookupCountry(name string) *country {
+	for i := range countries {
+		if strings.EqualFold(name, countries[i].name) {
+			return &countries[i]
+		}
+		for _, alias := range countries[i].aliases {
+			if strings.EqualFold(name, alias) {
+				return &countries[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ParseAddress parses the city, state, postal code and country out of a
+// formatted address in the US, Canadian, UK or continental European style,
+// working back from the end so that street lines and suite numbers are
+// skipped. It returns false, with just the country if it found one, when it
+// doesn't recognise the format.
+func ParseAddress(formatted string) (PostalAddress, bool) {
+	var parts []string
+	for _, part := range strings.Split(formatted, ",") {
+		if part = strings.Join(strings.Fields(part), " "); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return PostalAddress{}, false
+	}
+
+	formats := unknownCountryFormats
+	var name string
+	last := parts[len(parts)-1]
+	if c := lookupCountry(last); c != nil {
+		formats, name = c.formats, c.name
+		parts = parts[:len(parts)-1]
+	} else if strings.EqualFold(last, canadaCode) {
+		if address, ok := parseLocality(parts[:len(parts)-1], []addressFormat{addressFormatCA}); ok {
+			address.Country = "Canada"
+			return address, true
+		}
+	}
+
+	if address, ok := parseLocality(parts, formats); ok {
+		address.Country = name
+		return address, true
+	}
+	// A bare CA that isn't Canada is California.
+	if (name == "United States" || name == "" && strings.EqualFold(last, canadaCode)) && len(parts) >= 3 && usStateOnly.MatchString(parts[len(parts)-1]) {
+		return PostalAddress{City: parts[len(parts)-2], State: parts[len(parts)-1], Country: name}, true
+	}
+	return PostalAddress{Country: name}, false
+}
+
+// parseLocality finds the locality part of an address's comma-separated
+// parts in one of formats, working back from the end. The street line comes
+// first, so the first part is never the locality on its own.
+func parseLocality(parts []string, formats []addressFormat) (PostalAddress, bool) {
+	for i := len(parts) - 1; i >= 1; i-- {
+		for _, format := range formats {
+			if address, ok := format(parts[i], parts[i-1]); ok {
+				return address, true
+			}
+		}
+	}
+	return PostalAddress{}, false
+}
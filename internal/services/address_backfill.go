@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"cheapeats-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AddressChange describes what a backfill does to one restaurant's address.
+type AddressChange struct {
+	RestaurantID uint                   `json:"restaurant_id"`
+	Name         string                 `json:"name"`
+	Fields       map[string]FieldChange `json:"fields"`
+}
+
+// AddressBackfillResult summarises an address backfill, either planned (dry
+// run) or applied. Unparsed counts restaurants whose stored response had
+// neither address components nor an address in a known format; they are
+// left alone.
+type AddressBackfillResult struct {
+	DryRun    bool            `json:"dry_run"`
+	Scanned   int             `json:"scanned"`
+	Updated   int             `json:"updated"`
+	Unchanged int             `json:"unchanged"`
+	Unparsed  int             `json:"unparsed"`
+	Changes   []AddressChange `json:"changes"`
+}
+
+// BackfillAddresses re-derives the city, state, postal code and country of
+// every restaurant from its latest stored Places response, using the
+// address components when the response has them and the formatted address
+// otherwise. Unless dryRun is set, changed restaurants are updated.
+func BackfillAddresses(ctx context.Context, db *gorm.DB, dryRun bool) (*AddressBackfillResult, error) {
+	db = db.WithContext(ctx)
+	result := &AddressBackfillResult{DryRun: dryRun, Changes: []AddressChange{}}
+
+	latest := db.Model(&models.ScrapedData{}).
+		Select("DISTINCT ON (restaurant_id) id").
+		Where("source = ? AND restaurant_id IS NOT NULL", "google_places").
+		Order("restaurant_id, scraped_at DESC, id DESC")
+
+	var batch []models.ScrapedData
+	err := db.Preload("Restaurant").Where("id IN (?)", latest).
+		FindInBatches(&batch, 100, func(_ *gorm.DB, _ int) error {
+			for i := range batch {
+				restaurant := batch[i].Restaurant
+				if restaurant == nil {
+					// Deleted since it was scraped.
+					continue
+				}
+				result.Scanned++
+
+				address, ok := scrapedAddress(batch[i].RawData)
+				if !ok {
+					result.Unparsed++
+					continue
+				}
+
+				fields := diffAddress(restaurant, address)
+				if len(fields) == 0 {
+					result.Unchanged++
+					continue
+				}
+				result.Updated++
+				result.Changes = append(result.Changes, AddressChange{RestaurantID: restaurant.ID, Name: restaurant.Name, Fields: fields})
+
+				if dryRun {
+					continue
+				}
+				err := db.Model(restaurant).Updates(map[string]interface{}{
+					"city":     address.City,
+					"state":    address.State,
+					"zip_code": address.ZipCode,
+					"country":  address.Country,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scrapedPlace is the raw payload saved by FetchAndSaveRestaurants.
+type scrapedPlace struct {
+	SearchResult PlaceResult  `json:"search_result"`
+	Details      PlaceDetails `json:"details"`
+}
+
+func decodeScrapedPlace(raw models.JSONB) (scrapedPlace, bool) {
+	var payload scrapedPlace
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return payload, false
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return payload, false
+	}
+	return payload, true
+}
+
+// scrapedAddress reads the structured address out of a raw payload saved by
+// FetchAndSaveRestaurants.
+func scrapedAddress(raw models.JSONB) (PostalAddress, bool) {
+	payload, ok := decodeScrapedPlace(raw)
+	if !ok {
+		return PostalAddress{}, false
+	}
+
+	formatted := payload.Details.FormattedAddress
+	if formatted == "" {
+		formatted = payload.SearchResult.Address
+	}
+	return placeAddress(payload.Details.AddressComponents, formatted)
+}
+
+func diffAddress(restaurant *models.Restaurant, address PostalAddress) map[string]FieldChange {
+	fields := make(map[string]FieldChange)
+	for _, field := range []struct {
+		name     string
+		from, to string
+	}{
+		{"city", restaurant.City, address.City},
+		{"state", restaurant.State, address.State},
+		{"zip_code", restaurant.ZipCode, address.ZipCode},
+		{"country", restaurant.Country, address.Country},
+	} {
+		if field.from != field.to {
+			fields[field.name] = FieldChange{From: field.from, To: field.to}
+		}
+	}
+	return fields
+}
@@ -0,0 +1,35 @@
+package services
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		formatted string
+		want      PostalAddress
+		ok        bool
+	}{
+		{"123 Main St, Springfield, IL 62701, USA", PostalAddress{City: "Springfield", State: "IL", ZipCode: "62701", Country: "United States"}, true},
+		{"123 Main St, Suite 200, Springfield, IL 62701-1234, United States", PostalAddress{City: "Springfield", State: "IL", ZipCode: "62701-1234", Country: "United States"}, true},
+		{"123 Main St, Ste 4, San Francisco, CA 94103", PostalAddress{City: "San Francisco", State: "CA", ZipCode: "94103"}, true},
+		{"123 Main St, Springfield, IL, US", PostalAddress{City: "Springfield", State: "IL", Country: "United States"}, true},
+		{"123 Main St, Springfield, CA", PostalAddress{City: "Springfield", State: "CA"}, true},
+		{"123 Main St, Springfield, CA 90210, CA", PostalAddress{City: "Springfield", State: "CA", ZipCode: "90210"}, true},
+		{"290 Bremner Blvd, Toronto, ON M5V 3L9, Canada", PostalAddress{City: "Toronto", State: "ON", ZipCode: "M5V 3L9", Country: "Canada"}, true},
+		{"290 Bremner Blvd, Unit 5, Toronto, ON M5V 3L9, CA", PostalAddress{City: "Toronto", State: "ON", ZipCode: "M5V 3L9", Country: "Canada"}, true},
+		{"290 Bremner Blvd, Toronto, ON M5V3L9", PostalAddress{City: "Toronto", State: "ON", ZipCode: "M5V3L9"}, true},
+		{"10 Downing St, London SW1A 2AA, UK", PostalAddress{City: "London", ZipCode: "SW1A 2AA", Country: "United Kingdom"}, true},
+		{"Flat 3, 12 High St, Oxford, OX1 4AA, United Kingdom", PostalAddress{City: "Oxford", ZipCode: "OX1 4AA", Country: "United Kingdom"}, true},
+		{"Unter den Linden 77, 10117 Berlin, Germany", PostalAddress{City: "Berlin", ZipCode: "10117", Country: "Germany"}, true},
+		{"Damrak 1, 1012 LG Amsterdam, Nederland", PostalAddress{City: "Amsterdam", ZipCode: "1012 LG", Country: "Netherlands"}, true},
+		{"Rua Augusta 10, 1100-053 Lisboa, Portugal", PostalAddress{City: "Lisboa", ZipCode: "1100-053", Country: "Portugal"}, true},
+		{"Unter den Linden 77, Berlin, Germany", PostalAddress{Country: "Germany"}, false},
+		{"Somewhere, Nowhere", PostalAddress{}, false},
+		{"", PostalAddress{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseAddress(tt.formatted)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseAddress(%q) = %+v, %v, want %+v, %v", tt.formatted, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"cheapeats-api/internal/models"
+)
+
+// defaultCuisineRules are used when no rules file is configured. They double
+// as an example of the file format.
+//
+//go:embed cuisine_rules.json
+var defaultCuisineRules []byte
+
+// CuisineRule assigns a cuisine to restaurants with one of PlaceTypes, a
+// name containing one of NameKeywords, or enough menu items mentioning one
+// of MenuKeywords. Keywords match whole words, ignoring case.
+type CuisineRule struct {
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	PlaceTypes   []string `json:"place_types"`
+	NameKeywords []string `json:"name_keywords"`
+	MenuKeywords []string `json:"menu_keywords"`
+}
+
+// CuisineRules is the format of a cuisine rules file. MinMenuMatches is how
+// many menu items must mention a rule's menu keywords for the menu alone to
+// assign its cuisine; it defaults to 2.
+type CuisineRules struct {
+	MinMenuMatches int           `json:"min_menu_matches"`
+	Rules          []CuisineRule `json:"rules"`
+}
+
+// Evidence weights: a Places type is the strongest sign of a cuisine, the
+// menu the weakest. The main cuisine is the one with the most.
+const (
+	cuisineTypeWeight = 4
+	cuisineNameWeight = 2
+	cuisineMenuWeight = 1
+)
+
+// CuisineClassifier assigns cuisines to restaurants following a set of
+// rules.
+type CuisineClassifier struct {
+	rules          []CuisineRule
+	minMenuMatches int
+}
+
+// NewCuisineClassifier checks rules and returns a classifier using them.
+func NewCuisineClassifier(rules CuisineRules) (*CuisineClassifier, error) {
+	seen := make(map[string]bool, len(rules.Rules))
+	for i, rule := range rules.Rules {
+		switch {
+		case !models.ValidCuisineSlug(rule.Slug):
+			return nil, fmt.Errorf("rule %d: slug %q must be lowercase words joined by underscores", i+1, rule.Slug)
+		case seen[rule.Slug]:
+			return nil, fmt.Errorf("rule %d: duplicate slug %q", i+1, rule.Slug)
+		case strings.TrimSpace(rule.Name) == "":
+			return nil, fmt.Errorf("rule %d: name is required", i+1)
+		case len(rule.PlaceTypes)+len(rule.NameKeywords)+len(rule.MenuKeywords) == 0:
+			return nil, fmt.Errorf("rule %d: %s matches nothing", i+1, rule.Slug)
+		}
+		seen[rule.Slug] = true
+	}
+
+	minMenuMatches := rules.MinMenuMatches
+	if minMenuMatches <= 0 {
+		minMenuMatches = 2
+	}
+	return &CuisineClassifier{rules: rules.Rules, minMenuMatches: minMenuMatches}, nil
+}
+
+// LoadCuisineClassifier reads rules from a JSON file, or uses the built-in
+// rules when path is empty.
+func LoadCuisineClassifier(path string) (*CuisineClassifier, error) {
+	data := defaultCuisineRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var rules CuisineRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid cuisine rules: %w", err)
+	}
+	classifier, err := NewCuisineClassifier(rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cuisine rules: %w", err)
+	}
+	return classifier, nil
+}
+
+// Classify returns the cuisines of a restaurant with the given Places types,
+// name and menu, main cuisine first.
+func (c *CuisineClassifier) Classify(types []string, name string, menu []models.MenuItem) []models.Cuisine {
+	normalizedName := keywordText(name)
+	menuTexts := make([]string, len(menu))
+	for i, item := range menu {
+		menuTexts[i] = keywordText(item.Name + " " + item.Description)
+	}
+
+	type match struct {
+		rule  int
+		score int
+	}
+	var matches []match
+	for i, rule := range c.rules {
+		score := 0
+		if containsAny(types, rule.PlaceTypes) {
+			score += cuisineTypeWeight
+		}
+		if hasKeyword(normalizedName, rule.NameKeywords) {
+			score += cuisineNameWeight
+		}
+		if len(rule.MenuKeywords) > 0 {
+			count := 0
+			for _, text := range menuTexts {
+				if hasKeyword(text, rule.MenuKeywords) {
+					count++
+				}
+			}
+			if count >= c.minMenuMatches {
+				score += cuisineMenuWeight
+			}
+		}
+		if score > 0 {
+			matches = append(matches, match{rule: i, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	cuisines := make([]models.Cuisine, 0, len(matches))
+	for _, m := range matches {
+		rule := c.rules[m.rule]
+		cuisines = append(cuisines, models.Cuisine{Slug: rule.Slug, Name: rule.Name})
+	}
+	return cuisines
+}
+
+// keywordText lowercases s and turns everything but letters and digits into
+// single spaces, padded with a space at each end, so that whole-word
+// keywords can be found with strings.Contains.
+func keywordText(s string) string {
+	var b strings.Builder
+	b.WriteByte(' ')
+	space := true
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	if !space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func hasKeyword(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keywordText(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, value := range wanted {
+		if contains(values, value) {
+			return true
+		}
+	}
+	return false
+}
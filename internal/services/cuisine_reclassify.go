@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// CuisineChange describes what a reclassification does to one restaurant's
+// cuisines, given as slugs. To has the main cuisine first.
+type CuisineChange struct {
+	RestaurantID uint     `json:"restaurant_id"`
+	Name         string   `json:"name"`
+	From         []string `json:"from"`
+	To           []string `json:"to"`
+}
+
+// CuisineReclassifyResult summarises a reclassification, either planned (dry
+// run) or applied. Unmatched counts restaurants no rule matched; they keep
+// the cuisines they have.
+type CuisineReclassifyResult struct {
+	DryRun    bool            `json:"dry_run"`
+	Scanned   int             `json:"scanned"`
+	Updated   int             `json:"updated"`
+	Unchanged int             `json:"unchanged"`
+	Unmatched int             `json:"unmatched"`
+	Changes   []CuisineChange `json:"changes"`
+}
+
+// ReclassifyCuisines runs the classifier over every restaurant, using the
+// Places types of its latest stored Places response along with its name and
+// menu. Unless dryRun is set, restaurants whose cuisines change are updated.
+func ReclassifyCuisines(ctx context.Context, db *gorm.DB, classifier *CuisineClassifier, dryRun bool) (*CuisineReclassifyResult, error) {
+	db = db.WithContext(ctx)
+	restaurants := repository.NewGormRepositories(db).Restaurants
+	result := &CuisineReclassifyResult{DryRun: dryRun, Changes: []CuisineChange{}}
+
+	var batch []models.Restaurant
+	err := db.Preload("Cuisines").Preload("MenuItems").Order("id").
+		FindInBatches(&batch, 100, func(_ *gorm.DB, _ int) error {
+			types, err := latestPlaceTypes(db, batch)
+			if err != nil {
+				return err
+			}
+
+			for i := range batch {
+				restaurant := &batch[i]
+				result.Scanned++
+
+				cuisines := classifier.Classify(types[restaurant.ID], restaurant.Name, restaurant.MenuItems)
+				if len(cuisines) == 0 {
+					result.Unmatched++
+					continue
+				}
+
+				from := cuisineSlugs(restaurant.Cuisines)
+				to := cuisineSlugs(cuisines)
+				if restaurant.CuisineType == cuisines[0].Name && sameTags(sortedCopy(from), sortedCopy(to)) {
+					result.Unchanged++
+					continue
+				}
+				sort.Strings(from)
+				result.Updated++
+				result.Changes = append(result.Changes, CuisineChange{RestaurantID: restaurant.ID, Name: restaurant.Name, From: from, To: to})
+
+				if dryRun {
+					continue
+				}
+				if err := restaurants.SetCuisines(ctx, restaurant, cuisines); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// latestPlaceTypes returns the Places types of each restaurant, by ID, from
+// its latest stored Places response, preferring the details over the search
+// result.
+func latestPlaceTypes(db *gorm.DB, restaurants []models.Restaurant) (map[uint][]string, error) {
+	ids := make([]uint, len(restaurants))
+	for i, restaurant := range restaurants {
+		ids[i] = restaurant.ID
+	}
+
+	latest := db.Model(&models.ScrapedData{}).
+		Select("DISTINCT ON (restaurant_id) id").
+		Where("source = ? AND restaurant_id IN ?", "google_places", ids).
+		Order("restaurant_id, scraped_at DESC, id DESC")
+
+	var scrapes []models.ScrapedData
+	if err := db.Where("id IN (?)", latest).Find(&scrapes).Error; err != nil {
+		return nil, err
+	}
+
+	types := make(map[uint][]string, len(scrapes))
+	for _, scrape := range scrapes {
+		payload, ok := decodeScrapedPlace(scrape.RawData)
+		if !ok || scrape.RestaurantID == nil {
+			continue
+		}
+		if len(payload.Details.Types) > 0 {
+			types[*scrape.RestaurantID] = payload.Details.Types
+		} else {
+			types[*scrape.RestaurantID] = payload.SearchResult.Types
+		}
+	}
+	return types, nil
+}
+
+func cuisineSlugs(cuisines []models.Cuisine) []string {
+	slugs := make([]string, len(cuisines))
+	for i, cuisine := range cuisines {
+		slugs[i] = cuisine.Slug
+	}
+	return slugs
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Match thresholds. A restaurant new to ingest that scores AutoLinkScore or
+// more against one we have is taken to be the same place; pairs scoring
+// ReviewScore or more are queued for review.
+const (
+	AutoLinkScore = 0.9
+	ReviewScore   = 0.6
+)
+
+// Distances, in meters, over which the location score falls from 1 to 0.
+// Candidates are only looked for within matchRadius.
+const (
+	sameSpotDistance = 25
+	matchRadius      = 250
+)
+
+// Score weights. Without a phone number on both sides the phone's weight is
+// shared out between the others.
+const (
+	nameWeight     = 0.5
+	distanceWeight = 0.3
+	phoneWeight    = 0.2
+)
+
+// RestaurantMatch is a restaurant that may be the same place as another,
+// with how alike the two are.
+type RestaurantMatch struct {
+	Restaurant     models.Restaurant
+	Score          float64
+	NameSimilarity float64
+	DistanceMeters float64
+	// PhoneMatch is nil when either restaurant has no phone number.
+	PhoneMatch *bool
+}
+
+// Candidate returns the match as a review queue entry for the pair it forms
+// with the restaurant with ID restaurantID.
+func (m RestaurantMatch) Candidate(restaurantID uint) models.DuplicateCandidate {
+	return models.DuplicateCandidate{
+		RestaurantID:   restaurantID,
+		DuplicateID:    m.Restaurant.ID,
+		Score:          m.Score,
+		NameSimilarity: m.NameSimilarity,
+		DistanceMeters: m.DistanceMeters,
+		PhoneMatch:     m.PhoneMatch,
+	}
+}
+
+// RestaurantMatcher finds restaurants that may be the same place as another,
+// going by their normalized names, phone numbers and distance apart.
+type RestaurantMatcher struct {
+	restaurants repository.RestaurantRepository
+}
+
+func NewRestaurantMatcher(restaurants repository.RestaurantRepository) *RestaurantMatcher {
+	return &RestaurantMatcher{restaurants: restaurants}
+}
+
+// Match returns the live restaurants near restaurant that score ReviewScore
+// or more against it, best first. restaurant itself is left out when it has
+// an ID.
+func (m *RestaurantMatcher) Match(ctx context.Context, restaurant *models.Restaurant) ([]RestaurantMatch, error) {
+	nearby, err := m.restaurants.Nearby(ctx, restaurant.Latitude, restaurant.Longitude, matchRadius, repository.RestaurantFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []RestaurantMatch
+	for _, other := range nearby {
+		if restaurant.ID != 0 && other.ID == restaurant.ID {
+			continue
+		}
+		if match := ScoreMatch(restaurant, &other); match.Score >= ReviewScore {
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+// ScoreMatch scores how likely other is the same place as restaurant, from
+// 0 to 1.
+func ScoreMatch(restaurant, other *models.Restaurant) RestaurantMatch {
+	match := RestaurantMatch{
+		Restaurant:     *other,
+		NameSimilarity: nameSimilarity(NormalizeName(restaurant.Name), NormalizeName(other.Name)),
+		DistanceMeters: distanceMeters(restaurant.Latitude, restaurant.Longitude, other.Latitude, other.Longitude),
+	}
+
+	location := 0.0
+	switch {
+	case match.DistanceMeters <= sameSpotDistance:
+		location = 1
+	case match.DistanceMeters < matchRadius:
+		location = (matchRadius - match.DistanceMeters) / (matchRadius - sameSpotDistance)
+	}
+
+	phone, otherPhone := NormalizePhone(restaurant.Phone), NormalizePhone(other.Phone)
+	if phone == "" || otherPhone == "" {
+		scale := 1 / (nameWeight + distanceWeight)
+		match.Score = scale * (nameWeight*match.NameSimilarity + distanceWeight*location)
+	} else {
+		same := phone == otherPhone
+		match.PhoneMatch = &same
+		match.Score = nameWeight*match.NameSimilarity + distanceWeight*location
+		if same {
+			match.Score += phoneWeight
+		}
+	}
+	match.Score = math.Round(match.Score*1000) / 1000
+	return match
+}
+
+// nameStopWords are left out of normalized names, since they say little
+// about which restaurant it is.
+var nameStopWords = map[string]bool{"the": true, "and": true, "restaurant": true}
+
+// accents strips combining marks after decomposition, turning "é" into "e".
+var accents = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// NormalizeName returns name in lowercase without accents, punctuation or
+// stop words, with single spaces between words.
+func NormalizeName(name string) string {
+	if folded, _, err := transform.String(accents, name); err == nil {
+		name = folded
+	}
+	var words []string
+	for _, word := range strings.Fields(keywordText(name)) {
+		if !nameStopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// nameSimilarity is the Dice coefficient of the letter pairs of two
+// normalized names, ignoring spaces, so that "joes pizza" and "joe s pizza"
+// are equal and reordered words still score well.
+func nameSimilarity(a, b string) float64 {
+	a, b = strings.ReplaceAll(a, " ", ""), strings.ReplaceAll(b, " ", "")
+	if a == b {
+		if a == "" {
+			return 0
+		}
+		return 1
+	}
+
+	pairs := func(s string) map[string]int {
+		counts := make(map[string]int)
+		r := []rune(s)
+		for i := 0; i+1 < len(r); i++ {
+			counts[string(r[i:i+2])]++
+		}
+		return counts
+	}
+	pa, pb := pairs(a), pairs(b)
+
+	total, shared := 0, 0
+	for pair, n := range pa {
+		total += n
+		if m := pb[pair]; m < n {
+			shared += m
+		} else {
+			shared += n
+		}
+	}
+	for _, n := range pb {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// phoneDigits is how many trailing digits of a phone number are compared,
+// enough to tell numbers apart while ignoring country codes and trunk
+// prefixes.
+const phoneDigits = 9
+
+// NormalizePhone returns the last digits of a phone number, or "" when it
+// has too few to compare.
+func NormalizePhone(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < 7 {
+		return ""
+	}
+	if len(digits) > phoneDigits {
+		digits = digits[len(digits)-phoneDigits:]
+	}
+	return string(digits)
+}
+
+func distanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusMeters = 6371000.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// DuplicateScanResult summarises a scan for duplicate restaurants.
+type DuplicateScanResult struct {
+	Scanned int `json:"scanned"`
+	Queued  int `json:"queued"`
+}
+
+// FindDuplicates compares every live restaurant with those near it and
+// queues the pairs that score ReviewScore or more for review. Pairs already
+// queued are left as they are, so it can be run repeatedly.
+func FindDuplicates(ctx context.Context, repos repository.Repositories) (*DuplicateScanResult, error) {
+	restaurants, err := repos.Restaurants.List(ctx, repository.RestaurantFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := NewRestaurantMatcher(repos.Restaurants)
+	result := &DuplicateScanResult{}
+	for i := range restaurants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		restaurant := &restaurants[i]
+		result.Scanned++
+
+		matches, err := matcher.Match(ctx, restaurant)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			// Each pair is seen from both sides; queue it once.
+			if match.Restaurant.ID < restaurant.ID {
+				continue
+			}
+			candidate := match.Candidate(restaurant.ID)
+			queued, err := repos.Duplicates.Queue(ctx, &candidate)
+			if err != nil {
+				return nil, err
+			}
+			if queued {
+				result.Queued++
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"gorm.io/gorm"
+)
+
+// Supported export formats.
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatParquet = "parquet"
+)
+
+// parquetRowGroupSize bounds how many rows the Parquet writer buffers before
+// flushing a row group, so large exports don't accumulate in memory.
+const parquetRowGroupSize = 10000
+
+// ExportFilter narrows an export to a restaurant, a city or a date range.
+// Zero values mean no restriction.
+type ExportFilter struct {
+	RestaurantID uint
+	City         string
+	From         *time.Time
+	To           *time.Time
+}
+
+// MenuItemExportRow is the flattened menu item shape written by exports.
+type MenuItemExportRow struct {
+	ID             uint      `json:"id" parquet:"id"`
+	RestaurantID   uint      `json:"restaurant_id" parquet:"restaurant_id"`
+	RestaurantName string    `json:"restaurant_name" parquet:"restaurant_name"`
+	City           string    `json:"city" parquet:"city"`
+	Name           string    `json:"name" parquet:"name"`
+	Description    string    `json:"description" parquet:"description"`
+	Category       string    `json:"category" parquet:"category"`
+	Price          float64   `json:"price" parquet:"price"`
+	Currency       string    `json:"currency" parquet:"currency"`
+	IsAvailable    bool      `json:"is_available" parquet:"is_available"`
+	CreatedAt      time.Time `json:"created_at" parquet:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" parquet:"updated_at"`
+}
+
+// PriceHistoryExportRow is the flattened price history shape written by
+// exports. VariantName or ModifierName is set for entries of one of the
+// item's variants or modifiers, and PreviousPrice and PreviousCurrency are
+// empty for a first price.
+type PriceHistoryExportRow struct {
+	ID               uint      `json:"id" parquet:"id"`
+	MenuItemID       uint      `json:"menu_item_id" parquet:"menu_item_id"`
+	MenuItemName     string    `json:"menu_item_name" parquet:"menu_item_name"`
+	VariantName      string    `json:"variant_name" parquet:"variant_name"`
+	ModifierName     string    `json:"modifier_name" parquet:"modifier_name"`
+	Category         string    `json:"category" parquet:"category"`
+	RestaurantID     uint      `json:"restaurant_id" parquet:"restaurant_id"`
+	RestaurantName   string    `json:"restaurant_name" parquet:"restaurant_name"`
+	City             string    `json:"city" parquet:"city"`
+	Price            float64   `json:"price" parquet:"price"`
+	Currency         string    `json:"currency" parquet:"currency"`
+	PreviousPrice    *float64  `json:"previous_price" parquet:"previous_price,optional"`
+	PreviousCurrency string    `json:"previous_currency" parquet:"previous_currency"`
+	Source           string    `json:"source" parquet:"source"`
+	ScrapedDataID    *uint     `json:"scraped_data_id" parquet:"scraped_data_id,optional"`
+	RecordedAt       time.Time `json:"recorded_at" parquet:"recorded_at"`
+}
+
+func (MenuItemExportRow) csvHeader() []string {
+	return []string{"id", "restaurant_id", "restaurant_name", "city", "name", "description", "category", "price", "currency", "is_available", "created_at", "updated_at"}
+}
+
+func (row MenuItemExportRow) csvRecord() []string {
+	return []string{
+		formatUint(row.ID),
+		formatUint(row.RestaurantID),
+		row.RestaurantName,
+		row.City,
+		row.Name,
+		row.Description,
+		row.Category,
+		strconv.FormatFloat(row.Price, 'f', -1, 64),
+		row.Currency,
+		strconv.FormatBool(row.IsAvailable),
+		row.CreatedAt.UTC().Format(time.RFC3339),
+		row.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func (PriceHistoryExportRow) csvHeader() []string {
+	return []string{"id", "menu_item_id", "menu_item_name", "variant_name", "modifier_name", "category", "restaurant_id", "restaurant_name", "city", "price", "currency", "previous_price", "previous_currency", "source", "scraped_data_id", "recorded_at"}
+}
+
+func (row PriceHistoryExportRow) csvRecord() []string {
+	return []string{
+		formatUint(row.ID),
+		formatUint(row.MenuItemID),
+		row.MenuItemName,
+		row.VariantName,
+		row.ModifierName,
+		row.Category,
+		formatUint(row.RestaurantID),
+		row.RestaurantName,
+		row.City,
+		strconv.FormatFloat(row.Price, 'f', -1, 64),
+		row.Currency,
+		formatOptionalFloat(row.PreviousPrice),
+		row.PreviousCurrency,
+		row.Source,
+		formatOptionalUint(row.ScrapedDataID),
+		row.RecordedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+type exportRecord interface {
+	csvHeader() []string
+	csvRecord() []string
+}
+
+type Exporter struct{}
+
+func NewExporter() *Exporter {
+	return &Exporter{}
+}
+
+// ValidExportFormat reports whether format is one of the supported formats.
+func ValidExportFormat(format string) bool {
+	switch format {
+	case ExportFormatCSV, ExportFormatNDJSON, ExportFormatParquet:
+		return true
+	}
+	return false
+}
+
+// ExportMenuItems streams menu items matching filter to w. The date range
+// applies to when an item was last updated.
+func (e *Exporter) ExportMenuItems(ctx context.Context, db *gorm.DB, w io.Writer, format string, filter ExportFilter) error {
+	query := db.WithContext(ctx).
+		Table("menu_items AS mi").
+		Select(`mi.id, mi.restaurant_id, r.name AS restaurant_name, r.city,
+			mi.name, mi.description, mi.category, mi.price, mi.currency,
+			mi.is_available, mi.created_at, mi.updated_at`).
+		Joins("JOIN restaurants r ON r.id = mi.restaurant_id AND r.deleted_at IS NULL").
+		Where("mi.deleted_at IS NULL").
+		Order("mi.id")
+
+	query = applyExportFilter(query, filter, "mi.updated_at")
+
+	return streamExport[MenuItemExportRow](query, w, format)
+}
+
+// ExportPriceHistory streams price history records matching filter to w,
+// oldest first.
+func (e *Exporter) ExportPriceHistory(ctx context.Context, db *gorm.DB, w io.Writer, format string, filter ExportFilter) error {
+	query := db.WithContext(ctx).
+		Table("price_histories AS ph").
+		Select(`ph.id, ph.menu_item_id, mi.name AS menu_item_name,
+			COALESCE(v.name, '') AS variant_name, COALESCE(mo.name, '') AS modifier_name, mi.category,
+			mi.restaurant_id, r.name AS restaurant_name, r.city,
+			ph.price, ph.currency, ph.previous_price,
+			COALESCE(ph.previous_currency, '') AS previous_currency,
+			ph.source, ph.scraped_data_id, ph.recorded_at`).
+		Joins("JOIN menu_items mi ON mi.id = ph.menu_item_id").
+		Joins("JOIN restaurants r ON r.id = mi.restaurant_id").
+		Joins("LEFT JOIN menu_item_variants v ON v.id = ph.variant_id").
+		Joins("LEFT JOIN modifiers mo ON mo.id = ph.modifier_id").
+		Order("ph.recorded_at, ph.id")
+
+	query = applyExportFilter(query, filter, "ph.recorded_at")
+
+	return streamExport[PriceHistoryExportRow](query, w, format)
+}
+
+func applyExportFilter(query *gorm.DB, filter ExportFilter, timeColumn string) *gorm.DB {
+	if filter.RestaurantID != 0 {
+		query = query.Where("r.id = ?", filter.RestaurantID)
+	}
+	if filter.City != "" {
+		query = query.Where("r.city = ?", filter.City)
+	}
+	if filter.From != nil {
+		query = query.Where(timeColumn+" >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where(timeColumn+" < ?", *filter.To)
+	}
+	return query
+}
+
+// streamExport walks the query with a database cursor and writes each row as
+// soon as it is scanned, so memory use doesn't grow with the export size.
+func streamExport[T exportRecord](query *gorm.DB, w io.Writer, format string) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query export rows: %w", err)
+	}
+	defer rows.Close()
+
+	var write func(T) error
+	var finish func() error
+
+	switch format {
+	case ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		var zero T
+		if err := cw.Write(zero.csvHeader()); err != nil {
+			return err
+		}
+		write = func(row T) error { return cw.Write(row.csvRecord()) }
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case ExportFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(row T) error { return encoder.Encode(row) }
+		finish = func() error { return nil }
+	case ExportFormatParquet:
+		pw := parquet.NewGenericWriter[T](w)
+		buffered := 0
+		write = func(row T) error {
+			if _, err := pw.Write([]T{row}); err != nil {
+				return err
+			}
+			buffered++
+			if buffered >= parquetRowGroupSize {
+				buffered = 0
+				return pw.Flush()
+			}
+			return nil
+		}
+		finish = pw.Close
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+
+	for rows.Next() {
+		var row T
+		if err := query.ScanRows(rows, &row); err != nil {
+			return fmt.Errorf("failed to scan export row: %w", err)
+		}
+		if err := write(row); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read export rows: %w", err)
+	}
+
+	return finish()
+}
+
+func formatUint(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func formatOptionalUint(value *uint) string {
+	if value == nil {
+		return ""
+	}
+	return formatUint(*value)
+}
+
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cheapeats-api/internal/logging"
+	"cheapeats-api/internal/metrics"
+)
+
+var (
+	ErrQueueFull   = errors.New("ingest queue is full")
+	ErrQueueClosed = errors.New("ingest queue is shutting down")
+)
+
+// IngestJob asks for the restaurants around a location to be fetched from
+// the Places API and stored.
+type IngestJob struct {
+	ID         string    `json:"id"`
+	Lat        float64   `json:"lat"`
+	Lng        float64   `json:"lng"`
+	Radius     int       `json:"radius"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	// RequestID is the ID of the request that scheduled the job; the job's
+	// logs carry it too.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// IngestQueue runs ingest jobs on a fixed pool of background workers.
+type IngestQueue struct {
+	fetcher *PriceFetcher
+	workers int
+	jobs    chan IngestJob
+
+	// ctx is handed to running jobs and cancelled only when a shutdown runs
+	// out of time.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu          sync.RWMutex
+	closed      bool
+	lastSuccess time.Time
+}
+
+func NewIngestQueue(fetcher *PriceFetcher, workers, capacity int) *IngestQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &IngestQueue{
+		fetcher: fetcher,
+		workers: workers,
+		jobs:    make(chan IngestJob, capacity),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start launches the workers.
+func (q *IngestQueue) Start() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+}
+
+// Enqueue schedules a job without blocking. It fails when the queue is full
+// or shutting down.
+func (q *IngestQueue) Enqueue(ctx context.Context, lat, lng float64, radius int) (IngestJob, error) {
+	job := IngestJob{
+		ID:         newJobID(),
+		Lat:        lat,
+		Lng:        lng,
+		Radius:     radius,
+		EnqueuedAt: time.Now(),
+		RequestID:  logging.RequestID(ctx),
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return IngestJob{}, ErrQueueClosed
+	}
+
+	select {
+	case q.jobs <- job:
+		return job, nil
+	default:
+		return IngestJob{}, ErrQueueFull
+	}
+}
+
+// Depth returns the number of jobs waiting for a worker.
+func (q *IngestQueue) Depth() int {
+	return len(q.jobs)
+}
+
+// LastSuccess returns when a job last completed without error, or the zero
+// time if none has yet.
+func (q *IngestQueue) LastSuccess() time.Time {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.lastSuccess
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones to
+// finish. If ctx expires first, running jobs are cancelled, queued jobs are
+// dropped and ctx's error is returned.
+func (q *IngestQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *IngestQueue) work() {
+	defer q.wg.Done()
+
+	for job := range q.jobs {
+		ctx := logging.With(logging.WithRequestID(q.ctx, job.RequestID), "job_id", job.ID)
+
+		if q.ctx.Err() != nil {
+			slog.WarnContext(ctx, "dropping ingest job: shutting down")
+			metrics.IngestJobs.WithLabelValues("dropped").Inc()
+			continue
+		}
+
+		start := time.Now()
+		if err := q.fetcher.FetchAndSaveRestaurants(ctx, job.Lat, job.Lng, job.Radius); err != nil {
+			slog.ErrorContext(ctx, "ingest job failed", "error", err)
+			metrics.IngestJobs.WithLabelValues("failure").Inc()
+			continue
+		}
+
+		q.mu.Lock()
+		q.lastSuccess = time.Now()
+		q.mu.Unlock()
+		metrics.IngestJobs.WithLabelValues("success").Inc()
+
+		slog.InfoContext(ctx, "ingest job finished", "duration", time.Since(start))
+	}
+}
+
+func newJobID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
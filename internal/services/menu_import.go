@@ -0,0 +1,482 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"cheapeats-api/internal/metrics"
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
+)
+
+// MenuImportRow is one menu item as supplied by a partner spreadsheet or
+// JSON file. Diets, Allergens and SpiceLevel are left alone on existing items
+// when they are missing; an empty list clears them.
+type MenuImportRow struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Category    string      `json:"category"`
+	Price       json.Number `json:"price"`
+	Currency    string      `json:"currency"`
+	Available   *bool       `json:"available,omitempty"`
+	Diets       []string    `json:"diets,omitempty"`
+	Allergens   []string    `json:"allergens,omitempty"`
+	SpiceLevel  *int        `json:"spice_level,omitempty"`
+
+	// line is the row's line in a CSV file, 0 for other formats.
+	line int
+
+	// price is Price parsed in the row's currency, and diets and allergens
+	// the normalized tags, set by validateImportRows.
+	price     money.Money
+	diets     models.TagList
+	allergens models.TagList
+}
+
+// Import actions reported in a MenuImportChange.
+const (
+	ImportActionCreate     = "create"
+	ImportActionUpdate     = "update"
+	ImportActionDeactivate = "deactivate"
+	ImportActionUnchanged  = "unchanged"
+)
+
+// importProvenance is recorded with every price an import writes.
+var importProvenance = models.PriceProvenance{Source: models.PriceSourceImport}
+
+// FieldChange is the before and after value of a single menu item field.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// MenuImportChange describes what an import does to one menu item.
+// Confidence is how sure the match of a row to an existing item is; see
+// MenuItemMatch. A created item that may be an existing one renamed has the
+// existing item's ID in PossibleMatchID, for review.
+type MenuImportChange struct {
+	Action          string                 `json:"action"`
+	MenuItemID      *uint                  `json:"menu_item_id,omitempty"`
+	Name            string                 `json:"name"`
+	Confidence      float64                `json:"confidence,omitempty"`
+	PossibleMatchID *uint                  `json:"possible_match_id,omitempty"`
+	Fields          map[string]FieldChange `json:"fields,omitempty"`
+}
+
+// MenuImportResult summarises an import, either planned (dry run) or applied.
+type MenuImportResult struct {
+	RestaurantID uint               `json:"restaurant_id"`
+	DryRun       bool               `json:"dry_run"`
+	Created      int                `json:"created"`
+	Updated      int                `json:"updated"`
+	Deactivated  int                `json:"deactivated"`
+	Unchanged    int                `json:"unchanged"`
+	PriceChanges int                `json:"price_changes"`
+	Changes      []MenuImportChange `json:"changes"`
+}
+
+// MenuImportError reports a problem with the uploaded file itself, as
+// opposed to a database failure.
+type MenuImportError struct {
+	Row     int
+	Message string
+}
+
+func (e *MenuImportError) Error() string {
+	if e.Row > 0 {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+	}
+	return e.Message
+}
+
+type MenuImporter struct{}
+
+func NewMenuImporter() *MenuImporter {
+	return &MenuImporter{}
+}
+
+// ParseCSV reads menu rows from a CSV file with a header line. The name and
+// price columns are required; description, category, currency, available,
+// diets, allergens and spice_level are optional. Diets and allergens are
+// separated by ";" or "|".
+func (mi *MenuImporter) ParseCSV(r io.Reader) ([]MenuImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, &MenuImportError{Message: "missing CSV header"}
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, &MenuImportError{Message: fmt.Sprintf("missing required column %q", required)}
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []MenuImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, &MenuImportError{Row: parseErr.StartLine, Message: parseErr.Err.Error()}
+			}
+			return nil, &MenuImportError{Message: err.Error()}
+		}
+		// Quoted fields may span lines, so ask the reader where the record
+		// started rather than counting.
+		line, _ := reader.FieldPos(0)
+
+		price := field(record, "price")
+		if _, err := strconv.ParseFloat(price, 64); err != nil {
+			return nil, &MenuImportError{Row: line, Message: "invalid price"}
+		}
+
+		row := MenuImportRow{
+			Name:        field(record, "name"),
+			Description: field(record, "description"),
+			Category:    field(record, "category"),
+			Price:       json.Number(price),
+			Currency:    field(record, "currency"),
+			line:        line,
+		}
+
+		if available := field(record, "available"); available != "" {
+			value, err := parseImportBool(available)
+			if err != nil {
+				return nil, &MenuImportError{Row: line, Message: "invalid available value"}
+			}
+			row.Available = &value
+		}
+
+		if _, ok := columns["diets"]; ok {
+			row.Diets = splitImportTags(field(record, "diets"))
+		}
+		if _, ok := columns["allergens"]; ok {
+			row.Allergens = splitImportTags(field(record, "allergens"))
+		}
+		if level := field(record, "spice_level"); level != "" {
+			value, err := strconv.Atoi(level)
+			if err != nil {
+				return nil, &MenuImportError{Row: line, Message: "invalid spice_level value"}
+			}
+			row.SpiceLevel = &value
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// ParseJSON reads menu rows from either a bare JSON array or an object with
+// an "items" array.
+func (mi *MenuImporter) ParseJSON(r io.Reader) ([]MenuImportRow, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []MenuImportRow
+	if err := json.Unmarshal(body, &rows); err == nil {
+		return rows, nil
+	}
+
+	var wrapped struct {
+		Items []MenuImportRow `json:"items"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err != nil {
+		return nil, &MenuImportError{Message: "invalid JSON: " + err.Error()}
+	}
+	return wrapped.Items, nil
+}
+
+// Import compares rows against the restaurant's current menu. Rows are
+// matched to items by name, case-insensitively, or failing that with
+// MatchMenuItems, in which case the item is renamed so that its price
+// history carries on. Rows only matched for review are created, with the
+// match reported and, once applied, queued in MenuMatchRepository. Items
+// missing from the file are deactivated rather than deleted so their history
+// stays visible. Unless dryRun is set, all changes are applied with
+// MenuRepository.Import, in a single transaction.
+func (mi *MenuImporter) Import(ctx context.Context, repos repository.Repositories, restaurantID uint, rows []MenuImportRow, dryRun bool) (*MenuImportResult, error) {
+	if err := validateImportRows(rows); err != nil {
+		return nil, err
+	}
+
+	result := &MenuImportResult{RestaurantID: restaurantID, DryRun: dryRun}
+	// created are the items the plan creates, which Import gives IDs, and
+	// reviews their matches for review, nil for those without one.
+	var created []models.MenuItem
+	var reviews []*MenuItemMatch
+	plan := func(existing []models.MenuItem) (create, update []models.MenuItem, err error) {
+		incoming := make([]models.MenuItem, len(rows))
+		for i, row := range rows {
+			incoming[i] = models.MenuItem{Name: row.Name, Category: row.Category, Price: row.price}
+		}
+		matches := MatchMenuItems(incoming, existing)
+		seen := make(map[uint]bool, len(rows))
+
+		for i, row := range rows {
+			match := matches[i]
+			if match == nil || match.Review {
+				create = append(create, models.MenuItem{
+					RestaurantID: restaurantID,
+					Name:         row.Name,
+					Description:  row.Description,
+					Category:     row.Category,
+					Price:        row.price,
+					IsAvailable:  row.available(),
+					Diets:        row.diets,
+					Allergens:    row.allergens,
+					SpiceLevel:   row.SpiceLevel,
+				})
+				reviews = append(reviews, match)
+				change := MenuImportChange{Action: ImportActionCreate, Name: row.Name}
+				if match != nil {
+					change.Confidence, change.PossibleMatchID = match.Confidence, &match.Item.ID
+				}
+				result.add(change)
+				continue
+			}
+
+			item := match.Item
+			seen[item.ID] = true
+			fields := diffImportRow(item, row)
+			if match.Renamed {
+				fields["name"] = FieldChange{From: item.Name, To: row.Name}
+			}
+			change := MenuImportChange{Action: ImportActionUnchanged, MenuItemID: &item.ID, Name: item.Name, Confidence: match.Confidence}
+			if len(fields) > 0 {
+				change.Action = ImportActionUpdate
+				change.Fields = fields
+			}
+			result.add(change)
+
+			if len(fields) == 0 {
+				continue
+			}
+
+			updated := *item
+			updated.Description = row.Description
+			updated.Category = row.Category
+			updated.IsAvailable = row.available()
+			updated.Price = row.price
+			if row.Diets != nil {
+				updated.Diets = row.diets
+			}
+			if row.Allergens != nil {
+				updated.Allergens = row.allergens
+			}
+			if row.SpiceLevel != nil {
+				updated.SpiceLevel = row.SpiceLevel
+			}
+			if match.Renamed {
+				updated.Name = row.Name
+			}
+			update = append(update, updated)
+		}
+
+		for i := range existing {
+			item := existing[i]
+			if seen[item.ID] || !item.IsAvailable {
+				continue
+			}
+
+			result.add(MenuImportChange{
+				Action:     ImportActionDeactivate,
+				MenuItemID: &existing[i].ID,
+				Name:       item.Name,
+				Fields:     map[string]FieldChange{"is_available": {From: true, To: false}},
+			})
+			item.IsAvailable = false
+			update = append(update, item)
+		}
+		created = create
+		return create, update, nil
+	}
+
+	if dryRun {
+		existing, err := repos.Menus.ListByRestaurant(ctx, restaurantID, repository.MenuItemFilter{})
+		if err != nil {
+			return nil, err
+		}
+		plan(existing)
+		return result, nil
+	}
+
+	if err := repos.Menus.Import(ctx, restaurantID, plan, importProvenance); err != nil {
+		return nil, err
+	}
+	metrics.PriceChanges.Add(float64(result.PriceChanges))
+
+	// The import stands even if queueing fails; the matches are in the
+	// result either way.
+	for i, match := range reviews {
+		if match == nil {
+			continue
+		}
+		candidate := match.Candidate(created[i].ID)
+		if _, err := repos.MenuMatches.Queue(ctx, &candidate); err != nil {
+			slog.ErrorContext(ctx, "failed to queue menu match", "item", created[i].Name, "restaurant_id", restaurantID, "error", err)
+		}
+	}
+	return result, nil
+}
+
+func (r *MenuImportResult) add(change MenuImportChange) {
+	switch change.Action {
+	case ImportActionCreate:
+		r.Created++
+	case ImportActionUpdate:
+		r.Updated++
+		if _, ok := change.Fields["price"]; ok {
+			r.PriceChanges++
+		}
+	case ImportActionDeactivate:
+		r.Deactivated++
+	case ImportActionUnchanged:
+		r.Unchanged++
+	}
+	r.Changes = append(r.Changes, change)
+}
+
+func (row MenuImportRow) available() bool {
+	return row.Available == nil || *row.Available
+}
+
+func validateImportRows(rows []MenuImportRow) error {
+	if len(rows) == 0 {
+		return &MenuImportError{Message: "the import contains no menu items"}
+	}
+
+	names := make(map[string]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		line := row.line
+		if line == 0 {
+			line = i + 1
+		}
+		row.Name = strings.TrimSpace(row.Name)
+		row.Currency = money.NormalizeCurrency(row.Currency)
+
+		if row.Name == "" {
+			return &MenuImportError{Row: line, Message: "name is required"}
+		}
+		if !money.ValidCurrency(row.Currency) {
+			return &MenuImportError{Row: line, Message: "currency must be a three-letter ISO 4217 code"}
+		}
+		price, err := money.Parse(row.Price.String(), row.Currency)
+		if err != nil {
+			return &MenuImportError{Row: line, Message: "invalid price"}
+		}
+		if price.IsNegative() {
+			return &MenuImportError{Row: line, Message: "price must not be negative"}
+		}
+		row.price = price
+		if row.diets, err = models.ParseDiets(row.Diets); err != nil {
+			return &MenuImportError{Row: line, Message: err.Error()}
+		}
+		if row.allergens, err = models.ParseAllergens(row.Allergens); err != nil {
+			return &MenuImportError{Row: line, Message: err.Error()}
+		}
+		if row.SpiceLevel != nil && !models.ValidSpiceLevel(*row.SpiceLevel) {
+			return &MenuImportError{Row: line, Message: fmt.Sprintf("spice_level must be between 0 and %d", models.MaxSpiceLevel)}
+		}
+		if first, dup := names[importKey(row.Name)]; dup {
+			return &MenuImportError{Row: line, Message: fmt.Sprintf("duplicate item name %q (first seen in row %d)", row.Name, first)}
+		}
+		names[importKey(row.Name)] = line
+	}
+	return nil
+}
+
+func diffImportRow(item *models.MenuItem, row MenuImportRow) map[string]FieldChange {
+	fields := make(map[string]FieldChange)
+	if item.Description != row.Description {
+		fields["description"] = FieldChange{From: item.Description, To: row.Description}
+	}
+	if item.Category != row.Category {
+		fields["category"] = FieldChange{From: item.Category, To: row.Category}
+	}
+	if item.Price.String() != row.price.String() {
+		fields["price"] = FieldChange{From: item.Price, To: row.price}
+	}
+	if item.Price.Currency != row.price.Currency {
+		fields["currency"] = FieldChange{From: item.Price.Currency, To: row.price.Currency}
+	}
+	if item.IsAvailable != row.available() {
+		fields["is_available"] = FieldChange{From: item.IsAvailable, To: row.available()}
+	}
+	if row.Diets != nil && !sameTags(item.Diets, row.diets) {
+		fields["diets"] = FieldChange{From: item.Diets, To: row.diets}
+	}
+	if row.Allergens != nil && !sameTags(item.Allergens, row.allergens) {
+		fields["allergens"] = FieldChange{From: item.Allergens, To: row.allergens}
+	}
+	if row.SpiceLevel != nil && (item.SpiceLevel == nil || *item.SpiceLevel != *row.SpiceLevel) {
+		fields["spice_level"] = FieldChange{From: item.SpiceLevel, To: *row.SpiceLevel}
+	}
+	return fields
+}
+
+func sameTags(a, b models.TagList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitImportTags splits a CSV tag cell on ";" or "|". An empty cell gives
+// an empty, non-nil list, which clears the tags.
+func splitImportTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '|' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func importKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "y":
+		return true, nil
+	case "0", "false", "no", "n":
+		return false, nil
+	}
+	return false, errors.New("invalid boolean")
+}
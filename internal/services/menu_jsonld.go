@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"cheapeats-api/internal/models"
+)
+
+// jsonLDNode holds the schema.org properties the menu import reads from a
+// Restaurant, Menu, MenuSection, MenuItem or Offer node.
+type jsonLDNode struct {
+	Type            jsonLDStrings `json:"@type"`
+	Graph           jsonLDNodes   `json:"@graph"`
+	Name            string        `json:"name"`
+	Description     string        `json:"description"`
+	HasMenu         jsonLDNodes   `json:"hasMenu"`
+	HasMenuSection  jsonLDNodes   `json:"hasMenuSection"`
+	HasMenuItem     jsonLDNodes   `json:"hasMenuItem"`
+	Offers          jsonLDNodes   `json:"offers"`
+	Price           json.Number   `json:"price"`
+	PriceCurrency   string        `json:"priceCurrency"`
+	SuitableForDiet jsonLDStrings `json:"suitableForDiet"`
+}
+
+// jsonLDNodes is a property holding one node or an array of them. Plain
+// strings, which are references to nodes elsewhere, are skipped.
+type jsonLDNodes []jsonLDNode
+
+func (n *jsonLDNodes) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return nil
+	case data[0] == '{':
+		var node jsonLDNode
+		if err := json.Unmarshal(data, &node); err != nil {
+			return err
+		}
+		*n = jsonLDNodes{node}
+	case data[0] == '[':
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		for _, element := range raw {
+			var nodes jsonLDNodes
+			if err := nodes.UnmarshalJSON(element); err != nil {
+				return err
+			}
+			*n = append(*n, nodes...)
+		}
+	}
+	return nil
+}
+
+// jsonLDStrings is a property holding one string or an array of them; nodes
+// such as {"@id": "https://schema.org/VeganDiet"} count as their @id.
+type jsonLDStrings []string
+
+func (s *jsonLDStrings) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return nil
+	case data[0] == '"':
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = jsonLDStrings{value}
+	case data[0] == '{':
+		var ref struct {
+			ID string `json:"@id"`
+		}
+		if err := json.Unmarshal(data, &ref); err != nil {
+			return err
+		}
+		if ref.ID != "" {
+			*s = jsonLDStrings{ref.ID}
+		}
+	case data[0] == '[':
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		for _, element := range raw {
+			var values jsonLDStrings
+			if err := values.UnmarshalJSON(element); err != nil {
+				return err
+			}
+			*s = append(*s, values...)
+		}
+	}
+	return nil
+}
+
+func (n *jsonLDNode) is(typ string) bool {
+	for _, t := range n.Type {
+		if strings.TrimPrefix(strings.TrimPrefix(t, "https://schema.org/"), "http://schema.org/") == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseJSONLD reads menu rows from schema.org JSON-LD: a Menu, a Restaurant
+// with hasMenu, an array of them or a document with @graph. Items take their
+// category from the enclosing MenuSection and their price from the first
+// Offer with one. Diets come from suitableForDiet; restricted diets without
+// a matching tag, such as LowSaltDiet, are skipped.
+func (mi *MenuImporter) ParseJSONLD(r io.Reader) ([]MenuImportRow, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes jsonLDNodes
+	if err := json.Unmarshal(body, &nodes); err != nil {
+		return nil, &MenuImportError{Message: "invalid JSON-LD: " + err.Error()}
+	}
+
+	var rows []MenuImportRow
+	for i := range nodes {
+		if err := collectJSONLDItems(&nodes[i], "", &rows); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func collectJSONLDItems(node *jsonLDNode, category string, rows *[]MenuImportRow) error {
+	if node.is("MenuItem") {
+		row, err := jsonLDMenuItem(node, category)
+		if err != nil {
+			return &MenuImportError{Row: len(*rows) + 1, Message: err.Error()}
+		}
+		*rows = append(*rows, row)
+		return nil
+	}
+
+	if node.is("MenuSection") {
+		category = strings.TrimSpace(node.Name)
+	}
+	for _, children := range []jsonLDNodes{node.Graph, node.HasMenu, node.HasMenuSection, node.HasMenuItem} {
+		for i := range children {
+			if err := collectJSONLDItems(&children[i], category, rows); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonLDMenuItem(node *jsonLDNode, category string) (MenuImportRow, error) {
+	row := MenuImportRow{
+		Name:        strings.TrimSpace(node.Name),
+		Description: strings.TrimSpace(node.Description),
+		Category:    category,
+	}
+
+	for _, offer := range node.Offers {
+		if offer.Price != "" {
+			row.Price = offer.Price
+			row.Currency = offer.PriceCurrency
+			break
+		}
+	}
+	if row.Price == "" {
+		return row, fmt.Errorf("menu item %q has no offer with a price", row.Name)
+	}
+
+	if node.SuitableForDiet != nil {
+		row.Diets = []string{}
+		for _, diet := range node.SuitableForDiet {
+			if parsed, err := models.ParseDiet(diet); err == nil {
+				row.Diets = append(row.Diets, parsed)
+			}
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"cheapeats-api/internal/models"
+)
+
+// MenuMatchConfidence is the confidence from which an incoming menu item may
+// be an existing item under another name.
+const MenuMatchConfidence = 0.75
+
+// MenuRenameSimilarity is the name similarity from which such a match is
+// taken to be a rename. Below it, as for "Margherita Pizza" and "Marinara
+// Pizza" at the same price, the match is only suggested for review.
+const MenuRenameSimilarity = 0.8
+
+// Confidence weights of a menu item match.
+const (
+	itemNameWeight     = 0.6
+	itemCategoryWeight = 0.2
+	itemPriceWeight    = 0.2
+)
+
+// MenuItemMatch is the existing menu item an incoming one was matched to.
+// Confidence is 1 when the names are the same, ignoring case and spacing;
+// otherwise Confidence, from 0 to 1, weighs how alike the names are, whether
+// the categories agree and how close the prices are, and either Renamed is
+// set, or Review when the names are too far apart to be sure. An item under
+// review should be kept apart from the existing one and queued with
+// Candidate until an admin merges or dismisses the match.
+type MenuItemMatch struct {
+	Item       *models.MenuItem
+	Confidence float64
+	Renamed    bool
+	Review     bool
+}
+
+// Candidate returns the match as a review queue entry for the menu item with
+// ID newItemID, created for the incoming item.
+func (m MenuItemMatch) Candidate(newItemID uint) models.MenuMatchCandidate {
+	return models.MenuMatchCandidate{
+		MenuItemID: m.Item.ID,
+		NewItemID:  newItemID,
+		Confidence: m.Confidence,
+	}
+}
+
+// MatchMenuItems matches incoming menu items to the existing items of the
+// same restaurant, returning a match for each incoming item, or nil where
+// there is none. Each existing item is matched at most once: items with the
+// same name are paired first, then the remaining pairs scoring
+// MenuMatchConfidence or more, renames before those for review and most
+// confident first.
+func MatchMenuItems(incoming, existing []models.MenuItem) []*MenuItemMatch {
+	matches := make([]*MenuItemMatch, len(incoming))
+	taken := make([]bool, len(existing))
+
+	byName := make(map[string]int, len(existing))
+	for j := range existing {
+		if _, ok := byName[importKey(existing[j].Name)]; !ok {
+			byName[importKey(existing[j].Name)] = j
+		}
+	}
+	for i := range incoming {
+		if j, ok := byName[importKey(incoming[i].Name)]; ok && !taken[j] {
+			matches[i] = &MenuItemMatch{Item: &existing[j], Confidence: 1}
+			taken[j] = true
+		}
+	}
+
+	type pair struct {
+		incoming, existing int
+		confidence         float64
+		rename             bool
+	}
+	var pairs []pair
+	for i := range incoming {
+		if matches[i] != nil {
+			continue
+		}
+		for j := range existing {
+			if taken[j] {
+				continue
+			}
+			if confidence, name := menuItemConfidence(&incoming[i], &existing[j]); confidence >= MenuMatchConfidence {
+				pairs = append(pairs, pair{i, j, confidence, name >= MenuRenameSimilarity})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		if pairs[a].rename != pairs[b].rename {
+			return pairs[a].rename
+		}
+		return pairs[a].confidence > pairs[b].confidence
+	})
+
+	for _, p := range pairs {
+		if matches[p.incoming] != nil || taken[p.existing] {
+			continue
+		}
+		matches[p.incoming] = &MenuItemMatch{Item: &existing[p.existing], Confidence: p.confidence, Renamed: p.rename, Review: !p.rename}
+		taken[p.existing] = true
+	}
+	return matches
+}
+
+// menuItemConfidence scores how likely existing is incoming under another
+// name, from 0 to 1, and returns it with the similarity of their names.
+func menuItemConfidence(incoming, existing *models.MenuItem) (confidence, name float64) {
+	name = nameSimilarity(NormalizeName(incoming.Name), NormalizeName(existing.Name))
+
+	category := 0.0
+	switch {
+	case incoming.Category == "" || existing.Category == "":
+		category = 0.5
+	case importKey(incoming.Category) == importKey(existing.Category):
+		category = 1
+	}
+
+	// Prices in different currencies can't be compared; prices half as
+	// much again or more apart score nothing.
+	price := 0.5
+	if incoming.Price.Currency == existing.Price.Currency {
+		a, b := incoming.Price.Float64(), existing.Price.Float64()
+		if larger := math.Max(a, b); larger > 0 {
+			price = math.Max(0, 1-2*math.Abs(a-b)/larger)
+		} else {
+			price = 1
+		}
+	}
+
+	confidence = itemNameWeight*name + itemCategoryWeight*category + itemPriceWeight*price
+	return math.Round(confidence*1000) / 1000, name
+}
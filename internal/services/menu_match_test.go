@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+)
+
+func menuItem(name, category string, cents int64) models.MenuItem {
+	return models.MenuItem{Name: name, Category: category, Price: money.New(cents, "USD")}
+}
+
+func TestMenuItemConfidence(t *testing.T) {
+	tests := []struct {
+		incoming, existing models.MenuItem
+		confidence         float64
+		rename             bool
+	}{
+		{menuItem("Pizza Margherita", "Pizza", 1200), menuItem("Margherita Pizza", "Pizza", 1200), 0.957, true},
+		{menuItem("Chicken Wings", "Starters", 900), menuItem("Chicken Wing", "Starters", 850), 0.949, true},
+		{menuItem("Chicken Wings", "", 900), menuItem("Chicken Wing", "Starters", 900), 0.871, true},
+		{menuItem("Classic Cheeseburger", "Burgers", 1399), menuItem("Classic Burger", "Burgers", 1299), 0.811, false},
+		{menuItem("Marinara Pizza", "Pizza", 1200), menuItem("Margherita Pizza", "Pizza", 1200), 0.769, false},
+		{menuItem("Spaghetti Carbonara", "Pasta", 1400), menuItem("Spaghetti Bolognese", "Pasta", 1400), 0.718, false},
+		{menuItem("Greek Salad", "Salads", 900), menuItem("Caesar Salad", "Salads", 900), 0.653, false},
+		{menuItem("Pizza Margherita", "Pizza", 3600), menuItem("Margherita Pizza", "Pizza", 1200), 0.757, true},
+		{menuItem("Veggie Burger", "Burgers", 1299), menuItem("Classic Burger", "Burgers", 1299), 0.661, false},
+	}
+	for _, tt := range tests {
+		confidence, name := menuItemConfidence(&tt.incoming, &tt.existing)
+		if confidence != tt.confidence || (name >= MenuRenameSimilarity) != tt.rename {
+			t.Errorf("menuItemConfidence(%q, %q) = %v, %v, want %v with rename %v",
+				tt.incoming.Name, tt.existing.Name, confidence, name, tt.confidence, tt.rename)
+		}
+	}
+}
+
+func TestMatchMenuItems(t *testing.T) {
+	existing := []models.MenuItem{
+		menuItem("Classic Burger", "Burgers", 1299),
+		menuItem("Margherita Pizza", "Pizza", 1200),
+		menuItem("Chicken Wing", "Starters", 900),
+		menuItem("Caesar Salad", "Salads", 900),
+		menuItem("Soft Drink", "Drinks", 350),
+	}
+	for i := range existing {
+		existing[i].ID = uint(i + 1)
+	}
+
+	tests := []struct {
+		incoming models.MenuItem
+		// want is the ID of the matched item, or 0 for none.
+		want    uint
+		renamed bool
+		review  bool
+	}{
+		{menuItem("soft  drink", "Drinks", 399), 5, false, false},
+		{menuItem("Chicken Wings", "Starters", 900), 3, true, false},
+		{menuItem("Classic Cheeseburger", "Burgers", 1399), 1, false, true},
+		{menuItem("Marinara Pizza", "Pizza", 1200), 2, false, true},
+		{menuItem("Greek Salad", "Salads", 900), 0, false, false},
+		{menuItem("Veggie Burger", "Burgers", 1299), 0, false, false},
+	}
+	incoming := make([]models.MenuItem, len(tests))
+	for i, tt := range tests {
+		incoming[i] = tt.incoming
+	}
+
+	matches := MatchMenuItems(incoming, existing)
+	for i, tt := range tests {
+		match := matches[i]
+		switch {
+		case tt.want == 0:
+			if match != nil {
+				t.Errorf("%q matched %q, want no match", tt.incoming.Name, match.Item.Name)
+			}
+		case match == nil:
+			t.Errorf("%q matched nothing, want item %d", tt.incoming.Name, tt.want)
+		case match.Item.ID != tt.want || match.Renamed != tt.renamed || match.Review != tt.review:
+			t.Errorf("%q matched item %d with renamed %v and review %v, want item %d with %v and %v",
+				tt.incoming.Name, match.Item.ID, match.Renamed, match.Review, tt.want, tt.renamed, tt.review)
+		}
+	}
+}
+
+func TestMatchMenuItemsPrefersRenames(t *testing.T) {
+	existing := []models.MenuItem{menuItem("Margherita Pizza", "Pizza", 1200)}
+	existing[0].ID = 1
+
+	// The first item scores higher on category and price, but only the
+	// second is close enough in name to be the same item renamed.
+	incoming := []models.MenuItem{
+		menuItem("Marinara Pizza", "Pizza", 1200),
+		menuItem("Pizza Margherita", "", 1600),
+	}
+	matches := MatchMenuItems(incoming, existing)
+	if matches[0] != nil {
+		t.Errorf("%q matched %q, want no match", incoming[0].Name, matches[0].Item.Name)
+	}
+	if matches[1] == nil || !matches[1].Renamed {
+		t.Errorf("%q = %+v, want a rename of %q", incoming[1].Name, matches[1], existing[0].Name)
+	}
+}
@@ -2,22 +2,31 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
-	"strings"
 	"time"
 
-	"cheapeats-api/internal/database"
+	"cheapeats-api/internal/metrics"
 	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/money"
+	"cheapeats-api/internal/repository"
 )
 
 type PriceFetcher struct {
-	apiClient *RestaurantAPIClient
+	apiClient  *RestaurantAPIClient
+	repos      repository.Repositories
+	classifier *CuisineClassifier
+	matcher    *RestaurantMatcher
 }
 
-func NewPriceFetcher(apiClient *RestaurantAPIClient) *PriceFetcher {
+func NewPriceFetcher(apiClient *RestaurantAPIClient, repos repository.Repositories, classifier *CuisineClassifier) *PriceFetcher {
 	return &PriceFetcher{
-		apiClient: apiClient,
+		apiClient:  apiClient,
+		repos:      repos,
+		classifier: classifier,
+		matcher:    NewRestaurantMatcher(repos.Restaurants),
 	}
 }
 
@@ -27,9 +36,11 @@ func (pf *PriceFetcher) FetchAndSaveRestaurants(ctx context.Context, lat, lng fl
 		return fmt.Errorf("failed to search restaurants: %w", err)
 	}
 
-	db := database.GetDB()
-
 	for _, place := range searchResp.Results {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		restaurant := models.Restaurant{
 			ExternalID:  place.PlaceID,
 			Name:        place.Name,
@@ -38,40 +49,39 @@ func (pf *PriceFetcher) FetchAndSaveRestaurants(ctx context.Context, lat, lng fl
 			Longitude:   place.Geometry.Location.Lng,
 			Rating:      place.Rating,
 			PriceRange:  pf.convertPriceLevel(place.PriceLevel),
-			CuisineType: pf.extractCuisineType(place.Types),
 		}
 
-		addressParts := strings.Split(place.Address, ", ")
-		if len(addressParts) >= 3 {
-			restaurant.City = addressParts[len(addressParts)-3]
-			stateZip := addressParts[len(addressParts)-2]
-			stateParts := strings.Split(stateZip, " ")
-			if len(stateParts) >= 2 {
-				restaurant.State = stateParts[0]
-				restaurant.ZipCode = stateParts[1]
-			}
-			restaurant.Country = addressParts[len(addressParts)-1]
+		// Search results have no address components; the details below
+		// replace this with the structured address when they do.
+		if address, ok := ParseAddress(place.Address); ok {
+			address.Apply(&restaurant)
 		}
 
-		var existingRestaurant models.Restaurant
-		result := db.Where("external_id = ?", restaurant.ExternalID).First(&existingRestaurant)
-		
-		if result.Error != nil {
-			if err := db.Create(&restaurant).Error; err != nil {
-				fmt.Printf("Failed to create restaurant %s: %v\n", restaurant.Name, err)
+		existingRestaurant, err := pf.repos.Restaurants.GetByExternalID(ctx, restaurant.ExternalID)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			existingRestaurant, err = pf.createOrLink(ctx, &restaurant)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to create restaurant", "restaurant", restaurant.Name, "error", err)
 				continue
 			}
-			existingRestaurant = restaurant
-		} else {
-			if err := db.Model(&existingRestaurant).Updates(&restaurant).Error; err != nil {
-				fmt.Printf("Failed to update restaurant %s: %v\n", restaurant.Name, err)
+		case err != nil:
+			slog.ErrorContext(ctx, "failed to look up restaurant", "restaurant", restaurant.Name, "error", err)
+			continue
+		case existingRestaurant.DeletedAt.Valid:
+			// Deleted by an admin; don't bring it back.
+			continue
+		default:
+			mergeRestaurant(existingRestaurant, &restaurant)
+			if err := pf.repos.Restaurants.Update(ctx, existingRestaurant); err != nil {
+				slog.ErrorContext(ctx, "failed to update restaurant", "restaurant", restaurant.Name, "error", err)
 				continue
 			}
 		}
 
 		detailsResp, err := pf.apiClient.GetRestaurantDetails(ctx, place.PlaceID)
 		if err != nil {
-			fmt.Printf("Failed to get details for restaurant %s: %v\n", place.Name, err)
+			slog.WarnContext(ctx, "failed to get restaurant details", "restaurant", place.Name, "place_id", place.PlaceID, "error", err)
 			continue
 		}
 
@@ -81,11 +91,30 @@ func (pf *PriceFetcher) FetchAndSaveRestaurants(ctx context.Context, lat, lng fl
 		if detailsResp.Result.Website != "" {
 			existingRestaurant.Website = detailsResp.Result.Website
 		}
+		if detailsResp.Result.UTCOffsetMinutes != nil {
+			existingRestaurant.UTCOffsetMinutes = detailsResp.Result.UTCOffsetMinutes
+		}
+		if detailsResp.Result.FormattedAddress != "" {
+			existingRestaurant.Address = detailsResp.Result.FormattedAddress
+		}
+		if address, ok := placeAddress(detailsResp.Result.AddressComponents, existingRestaurant.Address); ok {
+			address.Apply(existingRestaurant)
+		}
 		
-		db.Save(&existingRestaurant)
-
-		pf.generateSampleMenuItems(existingRestaurant.ID, place.PriceLevel)
+		if err := pf.repos.Restaurants.Update(ctx, existingRestaurant); err != nil {
+			slog.ErrorContext(ctx, "failed to update restaurant", "restaurant", restaurant.Name, "error", err)
+			continue
+		}
+		
+		if hours := openingHoursFromPlace(&detailsResp.Result); hours != nil {
+			hours.RestaurantID = existingRestaurant.ID
+			if err := pf.repos.Restaurants.SaveOpeningHours(ctx, hours); err != nil {
+				slog.ErrorContext(ctx, "failed to save opening hours", "restaurant", restaurant.Name, "error", err)
+			}
+		}
+		metrics.RestaurantsIngested.Inc()
 
+		// Saved before the menu so that the recorded prices can point at it.
 		scrapedData := models.ScrapedData{
 			Source:       "google_places",
 			RestaurantID: &existingRestaurant.ID,
@@ -95,7 +124,19 @@ func (pf *PriceFetcher) FetchAndSaveRestaurants(ctx context.Context, lat, lng fl
 			},
 			ScrapedAt: time.Now(),
 		}
-		db.Create(&scrapedData)
+		provenance := models.PriceProvenance{Source: models.PriceSourceGooglePlaces}
+		if err := pf.repos.Scrapes.Save(ctx, &scrapedData); err != nil {
+			slog.ErrorContext(ctx, "failed to save scraped data", "restaurant", restaurant.Name, "error", err)
+		} else {
+			provenance.ScrapedDataID = &scrapedData.ID
+		}
+
+		generateSampleMenuItems(ctx, pf.repos, existingRestaurant.ID, place.PriceLevel, provenance)
+		types := detailsResp.Result.Types
+		if len(types) == 0 {
+			types = place.Types
+		}
+		pf.classifyCuisines(ctx, existingRestaurant, types)
 
 		time.Sleep(100 * time.Millisecond)
 	}
@@ -103,9 +144,127 @@ func (pf *PriceFetcher) FetchAndSaveRestaurants(ctx context.Context, lat, lng fl
 	return nil
 }
 
-func (pf *PriceFetcher) generateSampleMenuItems(restaurantID uint, priceLevel int) {
-	db := database.GetDB()
-	
+// createOrLink stores a restaurant we haven't seen before. When it is almost
+// certainly a place we already have under another external ID, the new ID
+// is linked to that restaurant instead, which is returned. Less certain
+// matches are created and queued for review.
+func (pf *PriceFetcher) createOrLink(ctx context.Context, restaurant *models.Restaurant) (*models.Restaurant, error) {
+	matches, err := pf.matcher.Match(ctx, restaurant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match restaurant: %w", err)
+	}
+
+	if len(matches) > 0 && matches[0].Score >= AutoLinkScore {
+		existing := matches[0].Restaurant
+		link := models.RestaurantSourceLink{
+			RestaurantID: existing.ID,
+			Source:       models.ExternalIDSource(restaurant.ExternalID),
+			ExternalID:   restaurant.ExternalID,
+		}
+		if err := pf.repos.Restaurants.LinkSource(ctx, &link); err != nil {
+			return nil, fmt.Errorf("failed to link restaurant: %w", err)
+		}
+		slog.InfoContext(ctx, "linked restaurant to existing one", "restaurant", restaurant.Name, "external_id", restaurant.ExternalID, "restaurant_id", existing.ID, "score", matches[0].Score)
+		return &existing, nil
+	}
+
+	if err := pf.repos.Restaurants.Create(ctx, restaurant); err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		candidate := match.Candidate(restaurant.ID)
+		if _, err := pf.repos.Duplicates.Queue(ctx, &candidate); err != nil {
+			slog.ErrorContext(ctx, "failed to queue duplicate candidate", "restaurant", restaurant.Name, "error", err)
+		}
+	}
+	return restaurant, nil
+}
+
+// mergeRestaurant copies the non-empty fields of fetched onto existing, so
+// that a sparse Places result doesn't wipe out data we already have.
+func mergeRestaurant(existing, fetched *models.Restaurant) {
+	for _, field := range []struct{ dst, src *string }{
+		{&existing.Name, &fetched.Name},
+		{&existing.Address, &fetched.Address},
+		{&existing.City, &fetched.City},
+		{&existing.State, &fetched.State},
+		{&existing.ZipCode, &fetched.ZipCode},
+		{&existing.Country, &fetched.Country},
+		{&existing.PriceRange, &fetched.PriceRange},
+	} {
+		if *field.src != "" {
+			*field.dst = *field.src
+		}
+	}
+	if fetched.Latitude != 0 {
+		existing.Latitude = fetched.Latitude
+	}
+	if fetched.Longitude != 0 {
+		existing.Longitude = fetched.Longitude
+	}
+	if fetched.Rating != 0 {
+		existing.Rating = fetched.Rating
+	}
+}
+
+// openingHoursFromPlace converts a place's regular hours, and the dates in
+// the coming week with exceptional hours, to OpeningHours. It returns nil
+// when Places has no hours for the place.
+func openingHoursFromPlace(details *PlaceDetails) *models.OpeningHours {
+	if details.OpeningHours == nil || len(details.OpeningHours.Periods) == 0 {
+		return nil
+	}
+
+	hours := &models.OpeningHours{
+		Periods:     models.OpeningPeriods{},
+		SpecialDays: models.SpecialDays{},
+	}
+	for _, period := range details.OpeningHours.Periods {
+		converted := models.OpeningPeriod{Open: placeWeekTime(period.Open)}
+		if period.Close != nil {
+			closeAt := placeWeekTime(*period.Close)
+			converted.Close = &closeAt
+		}
+		hours.Periods = append(hours.Periods, converted)
+	}
+
+	current := details.CurrentOpeningHours
+	if current == nil {
+		return hours
+	}
+	for _, day := range current.SpecialDays {
+		if !day.ExceptionalHours {
+			continue
+		}
+		special := models.SpecialDay{Date: day.Date, Hours: []models.TimeRange{}}
+		for _, period := range current.Periods {
+			if period.Open.Date != day.Date || period.Close == nil {
+				continue
+			}
+			special.Hours = append(special.Hours, models.TimeRange{
+				Open:  placeClock(period.Open.Time),
+				Close: placeClock(period.Close.Time),
+			})
+		}
+		hours.SpecialDays = append(hours.SpecialDays, special)
+	}
+	return hours
+}
+
+func placeWeekTime(at PlaceDayTime) models.WeekTime {
+	return models.WeekTime{Day: at.Day, Time: placeClock(at.Time)}
+}
+
+// placeClock turns Places' "HHMM" into "HH:MM".
+func placeClock(clock string) string {
+	if len(clock) != 4 {
+		return clock
+	}
+	return clock[:2] + ":" + clock[2:]
+}
+
+func generateSampleMenuItems(ctx context.Context, repos repository.Repositories, restaurantID uint, priceLevel int, provenance models.PriceProvenance) {
+	menus := repos.Menus
 	basePrice := 10.0
 	if priceLevel > 0 {
 		basePrice = float64(priceLevel) * 15.0
@@ -119,8 +278,7 @@ func (pf *PriceFetcher) generateSampleMenuItems(restaurantID uint, priceLevel in
 			Name:         "Signature Appetizer",
 			Description:  "Chef's special starter",
 			Category:     categories[0],
-			Price:        basePrice * 0.7 + rand.Float64()*5,
-			Currency:     "USD",
+			Price:        money.FromFloat(basePrice * 0.7 + rand.Float64()*5, "USD"),
 			IsAvailable:  true,
 		},
 		{
@@ -128,8 +286,7 @@ func (pf *PriceFetcher) generateSampleMenuItems(restaurantID uint, priceLevel in
 			Name:         "House Special Main",
 			Description:  "Most popular main dish",
 			Category:     categories[1],
-			Price:        basePrice + rand.Float64()*10,
-			Currency:     "USD",
+			Price:        money.FromFloat(basePrice + rand.Float64()*10, "USD"),
 			IsAvailable:  true,
 		},
 		{
@@ -137,8 +294,7 @@ func (pf *PriceFetcher) generateSampleMenuItems(restaurantID uint, priceLevel in
 			Name:         "Daily Special",
 			Description:  "Today's featured dish",
 			Category:     categories[1],
-			Price:        basePrice * 1.2 + rand.Float64()*8,
-			Currency:     "USD",
+			Price:        money.FromFloat(basePrice * 1.2 + rand.Float64()*8, "USD"),
 			IsAvailable:  true,
 		},
 		{
@@ -146,54 +302,124 @@ func (pf *PriceFetcher) generateSampleMenuItems(restaurantID uint, priceLevel in
 			Name:         "Classic Burger",
 			Description:  "Traditional burger with fries",
 			Category:     categories[1],
-			Price:        basePrice * 0.9 + rand.Float64()*5,
-			Currency:     "USD",
+			Price:        money.FromFloat(basePrice * 0.9 + rand.Float64()*5, "USD"),
 			IsAvailable:  true,
+			Allergens:    models.TagList{models.AllergenGluten, models.AllergenMilk, models.AllergenSesame},
 		},
 		{
 			RestaurantID: restaurantID,
 			Name:         "Dessert of the Day",
 			Description:  "Sweet treat to end your meal",
 			Category:     categories[2],
-			Price:        basePrice * 0.5 + rand.Float64()*3,
-			Currency:     "USD",
+			Price:        money.FromFloat(basePrice * 0.5 + rand.Float64()*3, "USD"),
 			IsAvailable:  true,
+			Diets:        models.TagList{models.DietPescatarian, models.DietVegetarian},
+			Allergens:    models.TagList{models.AllergenEgg, models.AllergenGluten, models.AllergenMilk},
 		},
 		{
 			RestaurantID: restaurantID,
 			Name:         "Soft Drink",
 			Description:  "Various sodas available",
 			Category:     categories[3],
-			Price:        3.50 + rand.Float64()*2,
-			Currency:     "USD",
+			Price:        money.FromFloat(3.50 + rand.Float64()*2, "USD"),
 			IsAvailable:  true,
+			Diets:        models.TagList{models.DietDairyFree, models.DietGlutenFree, models.DietPescatarian, models.DietVegan, models.DietVegetarian},
 		},
 	}
 
-	for _, item := range menuItems {
-		var existingItem models.MenuItem
-		result := db.Where("restaurant_id = ? AND name = ?", item.RestaurantID, item.Name).First(&existingItem)
+	existing, err := menus.ListByRestaurant(ctx, restaurantID, repository.MenuItemFilter{})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load menu items", "restaurant_id", restaurantID, "error", err)
+		return
+	}
+	matches := MatchMenuItems(menuItems, existing)
+
+	for i := range menuItems {
+		item := &menuItems[i]
+		match := matches[i]
 		
-		if result.Error != nil {
-			db.Create(&item)
-			
-			priceHistory := models.PriceHistory{
-				MenuItemID: item.ID,
-				Price:      item.Price,
-				RecordedAt: time.Now(),
+		var existingItem *models.MenuItem
+		if match == nil || match.Review {
+			if err := menus.Create(ctx, item, provenance); err != nil {
+				slog.ErrorContext(ctx, "failed to create menu item", "item", item.Name, "restaurant_id", restaurantID, "error", err)
+				continue
+			}
+			existingItem = item
+			if match != nil {
+				candidate := match.Candidate(item.ID)
+				if _, err := repos.MenuMatches.Queue(ctx, &candidate); err != nil {
+					slog.ErrorContext(ctx, "failed to queue menu match", "item", item.Name, "restaurant_id", restaurantID, "error", err)
+				}
 			}
-			db.Create(&priceHistory)
 		} else {
-			if existingItem.Price != item.Price {
-				priceHistory := models.PriceHistory{
-					MenuItemID: existingItem.ID,
-					Price:      item.Price,
-					RecordedAt: time.Now(),
+			existingItem = match.Item
+			if match.Renamed {
+				// Keep the history of an item whose name changed.
+				previousName := existingItem.Name
+				if err := menus.Rename(ctx, existingItem, item.Name); err != nil {
+					slog.ErrorContext(ctx, "failed to rename menu item", "item", previousName, "restaurant_id", restaurantID, "error", err)
+					continue
 				}
-				db.Create(&priceHistory)
-				
-				db.Model(&existingItem).Update("price", item.Price)
+				slog.InfoContext(ctx, "matched renamed menu item", "from", previousName, "to", item.Name, "restaurant_id", restaurantID, "confidence", match.Confidence)
+			}
+			changed, err := menus.UpdatePrice(ctx, existingItem, item.Price, provenance)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to update menu item price", "item", item.Name, "restaurant_id", restaurantID, "error", err)
+				continue
 			}
+			if changed {
+				metrics.PriceChanges.Inc()
+			}
+		}
+
+		generateSampleOptions(ctx, menus, existingItem, provenance)
+	}
+}
+
+// sampleOption is a variant or modifier priced relative to its item.
+type sampleOption struct {
+	name   string
+	markup float64
+}
+
+var (
+	sampleVariants = map[string][]sampleOption{
+		"Classic Burger": {{"Combo with Fries and Drink", 4.0}},
+		"Soft Drink":     {{"Small", -1.0}, {"Large", 1.5}},
+	}
+	sampleModifiers = map[string][]sampleOption{
+		"Classic Burger": {{"Extra Cheese", 1.0}, {"Bacon", 2.0}},
+	}
+)
+
+func generateSampleOptions(ctx context.Context, menus repository.MenuRepository, item *models.MenuItem, provenance models.PriceProvenance) {
+	option := func(o sampleOption) models.MenuItemOption {
+		return models.MenuItemOption{
+			MenuItemID:  item.ID,
+			Name:        o.name,
+			Price:       money.FromFloat(item.Price.Float64()+o.markup, item.Price.Currency),
+			IsAvailable: true,
+		}
+	}
+
+	for _, o := range sampleVariants[item.Name] {
+		changed, err := menus.SaveVariant(ctx, &models.MenuItemVariant{MenuItemOption: option(o)}, provenance)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to save menu item variant", "item", item.Name, "variant", o.name, "error", err)
+			continue
+		}
+		if changed {
+			metrics.PriceChanges.Inc()
+		}
+	}
+	for _, o := range sampleModifiers[item.Name] {
+		changed, err := menus.SaveModifier(ctx, &models.Modifier{MenuItemOption: option(o)}, provenance)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to save modifier", "item", item.Name, "modifier", o.name, "error", err)
+			continue
+		}
+		if changed {
+			metrics.PriceChanges.Inc()
 		}
 	}
 }
@@ -215,35 +441,22 @@ func (pf *PriceFetcher) convertPriceLevel(level int) string {
 	}
 }
 
-func (pf *PriceFetcher) extractCuisineType(types []string) string {
-	cuisineTypes := map[string]string{
-		"chinese_restaurant":  "Chinese",
-		"italian_restaurant":  "Italian",
-		"mexican_restaurant":  "Mexican",
-		"japanese_restaurant": "Japanese",
-		"indian_restaurant":   "Indian",
-		"thai_restaurant":     "Thai",
-		"french_restaurant":   "French",
-		"pizza":              "Pizza",
-		"burger":             "Burger",
-		"seafood":            "Seafood",
-		"vegetarian":         "Vegetarian",
-		"cafe":               "Cafe",
-		"bakery":             "Bakery",
-		"bar":                "Bar",
+// classifyCuisines assigns cuisines to a restaurant from its Places types,
+// name and menu. Restaurants nothing matches keep the cuisines they have.
+func (pf *PriceFetcher) classifyCuisines(ctx context.Context, restaurant *models.Restaurant, types []string) {
+	menu, err := pf.repos.Menus.ListByRestaurant(ctx, restaurant.ID, repository.MenuItemFilter{})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load menu for cuisine classification", "restaurant", restaurant.Name, "error", err)
+		return
 	}
 
-	for _, t := range types {
-		if cuisine, ok := cuisineTypes[t]; ok {
-			return cuisine
-		}
+	cuisines := pf.classifier.Classify(types, restaurant.Name, menu)
+	if len(cuisines) == 0 {
+		return
 	}
-
-	if contains(types, "restaurant") {
-		return "General"
+	if err := pf.repos.Restaurants.SetCuisines(ctx, restaurant, cuisines); err != nil {
+		slog.ErrorContext(ctx, "failed to set cuisines", "restaurant", restaurant.Name, "error", err)
 	}
-
-	return "Other"
 }
 
 func contains(slice []string, item string) bool {
@@ -8,6 +8,9 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"cheapeats-api/internal/metrics"
+	"cheapeats-api/internal/tracing"
 )
 
 type RestaurantAPIClient struct {
@@ -18,7 +21,8 @@ type RestaurantAPIClient struct {
 func NewRestaurantAPIClient(apiKey string) *RestaurantAPIClient {
 	return &RestaurantAPIClient{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: tracing.NewTransport(metrics.NewTransport(nil)),
 		},
 		apiKey: apiKey,
 	}
@@ -37,9 +41,7 @@ type PlaceResult struct {
 	Rating       float32  `json:"rating"`
 	PriceLevel   int      `json:"price_level"`
 	Types        []string `json:"types"`
-	OpeningHours *struct {
-		OpenNow bool `json:"open_now"`
-	} `json:"opening_hours,omitempty"`
+	OpeningHours *PlaceOpeningHours `json:"opening_hours,omitempty"`
 }
 
 type Geometry struct {
@@ -64,6 +66,48 @@ type PlaceDetails struct {
 	PriceLevel      int      `json:"price_level"`
 	Types           []string `json:"types"`
 	Geometry        Geometry `json:"geometry"`
+	OpeningHours        *PlaceOpeningHours `json:"opening_hours,omitempty"`
+	CurrentOpeningHours *PlaceOpeningHours `json:"current_opening_hours,omitempty"`
+	UTCOffsetMinutes    *int               `json:"utc_offset_minutes,omitempty"`
+	AddressComponents   []PlaceAddressComponent `json:"address_components,omitempty"`
+}
+
+// PlaceAddressComponent is one part of a place's address, such as its
+// locality or postal code, as named by Types.
+type PlaceAddressComponent struct {
+	LongName  string   `json:"long_name"`
+	ShortName string   `json:"short_name"`
+	Types     []string `json:"types"`
+}
+
+// PlaceOpeningHours is a place's regular weekly hours or, as
+// current_opening_hours, its hours for the coming seven days, where
+// SpecialDays lists the dates that differ from the regular week.
+type PlaceOpeningHours struct {
+	OpenNow     bool              `json:"open_now"`
+	Periods     []PlacePeriod     `json:"periods"`
+	WeekdayText []string          `json:"weekday_text,omitempty"`
+	SpecialDays []PlaceSpecialDay `json:"special_days,omitempty"`
+}
+
+// PlacePeriod is an opening span. Close is missing for places that are
+// always open.
+type PlacePeriod struct {
+	Open  PlaceDayTime  `json:"open"`
+	Close *PlaceDayTime `json:"close,omitempty"`
+}
+
+// PlaceDayTime is a day of the week (0 is Sunday) and a time as "HHMM".
+// Date is only set in current_opening_hours.
+type PlaceDayTime struct {
+	Day  int    `json:"day"`
+	Time string `json:"time"`
+	Date string `json:"date,omitempty"`
+}
+
+type PlaceSpecialDay struct {
+	Date             string `json:"date"`
+	ExceptionalHours bool   `json:"exceptional_hours"`
 }
 
 func (c *RestaurantAPIClient) SearchRestaurantsByLocation(ctx context.Context, lat, lng float64, radius int) (*PlaceSearchResponse, error) {
@@ -110,7 +154,7 @@ func (c *RestaurantAPIClient) GetRestaurantDetails(ctx context.Context, placeID
 	
 	params := url.Values{}
 	params.Add("place_id", placeID)
-	params.Add("fields", "place_id,name,formatted_address,formatted_phone_number,website,rating,price_level,types,geometry")
+	params.Add("fields", "place_id,name,formatted_address,formatted_phone_number,website,rating,price_level,types,geometry,opening_hours,current_opening_hours,utc_offset,address_components")
 	params.Add("key", c.apiKey)
 
 	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
@@ -141,4 +185,24 @@ func (c *RestaurantAPIClient) GetRestaurantDetails(ctx context.Context, placeID
 	}
 
 	return &result, nil
-}
\ No newline at end of file
+}
+
+// Ping checks that the Places API host can be reached. It doesn't call an
+// API method, so it costs no quota.
+func (c *RestaurantAPIClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", "https://maps.googleapis.com/maps/api/place/", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Places API: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("Places API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
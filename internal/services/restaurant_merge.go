@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+)
+
+// Errors returned by merges.
+var (
+	ErrMergeSelf            = errors.New("can't merge into itself")
+	ErrMergeOtherRestaurant = errors.New("can't merge menu items of different restaurants")
+)
+
+// RestaurantMergeResult summarises a merge. MovedItems counts the menu items
+// moved over as they were; MergedItems those merged into a matching item.
+type RestaurantMergeResult struct {
+	RestaurantID uint `json:"restaurant_id"`
+	MergedID     uint `json:"merged_id"`
+	MovedItems   int  `json:"moved_items"`
+	MergedItems  int  `json:"merged_items"`
+}
+
+// MergeRestaurants merges the restaurant with ID duplicateID into the one
+// with ID keepID, then deletes it, as RestaurantRepository.Merge does. Menu
+// items are matched with MatchMenuItems.
+//
+// It returns repository.ErrNotFound when either restaurant doesn't exist.
+func MergeRestaurants(ctx context.Context, restaurants repository.RestaurantRepository, keepID, duplicateID uint) (*RestaurantMergeResult, error) {
+	if keepID == duplicateID {
+		return nil, ErrMergeSelf
+	}
+
+	merge, err := restaurants.Merge(ctx, keepID, duplicateID, matchMenuItems)
+	if err != nil {
+		return nil, err
+	}
+	return &RestaurantMergeResult{
+		RestaurantID: keepID,
+		MergedID:     duplicateID,
+		MovedItems:   merge.MovedItems,
+		MergedItems:  merge.MergedItems,
+	}, nil
+}
+
+// matchMenuItems is MatchMenuItems as a repository.MenuMatcher. Items only
+// matched for review are moved rather than merged.
+func matchMenuItems(incoming, existing []models.MenuItem) []*models.MenuItem {
+	targets := make([]*models.MenuItem, len(incoming))
+	for i, match := range MatchMenuItems(incoming, existing) {
+		if match != nil && !match.Review {
+			targets[i] = match.Item
+		}
+	}
+	return targets
+}
+
+// MergeMenuItems merges the menu item with ID sourceID into the one with ID
+// targetID, of the same restaurant, as MenuRepository.Merge does, and
+// returns the merged item. It returns repository.ErrNotFound when either
+// item doesn't exist.
+func MergeMenuItems(ctx context.Context, menus repository.MenuRepository, targetID, sourceID uint) (*models.MenuItem, error) {
+	if targetID == sourceID {
+		return nil, ErrMergeSelf
+	}
+
+	target, err := menus.Get(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := menus.Get(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source.RestaurantID != target.RestaurantID {
+		return nil, ErrMergeOtherRestaurant
+	}
+
+	if err := menus.Merge(ctx, target, source); err != nil {
+		return nil, err
+	}
+	// Reload to pick up the variants, modifiers and history moved over.
+	return menus.Get(ctx, targetID)
+}
+
+// RenameMenuItem renames the menu item with ID id and returns it. When the
+// restaurant has another item by the new name, ignoring case, as an import
+// or ingest that didn't recognise the rename creates, that item is merged
+// into this one so that a single item carries the whole price history. It
+// returns repository.ErrNotFound when the item doesn't exist.
+func RenameMenuItem(ctx context.Context, menus repository.MenuRepository, id uint, name string) (*models.MenuItem, error) {
+	item, err := menus.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	others, err := menus.ListByRestaurant(ctx, item.RestaurantID, repository.MenuItemFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range others {
+		other := &others[i]
+		if other.ID == item.ID || !strings.EqualFold(other.Name, name) {
+			continue
+		}
+		if err := menus.Merge(ctx, item, other); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := menus.Rename(ctx, item, name); err != nil {
+		return nil, err
+	}
+	return menus.Get(ctx, id)
+}
+
+// MergeMenuMatch merges a queued match: the new item is merged into the
+// existing one, which takes its name, as RenameMenuItem does, and the merged
+// item is returned. It returns repository.ErrNotFound when either item no
+// longer exists.
+func MergeMenuMatch(ctx context.Context, menus repository.MenuRepository, candidate *models.MenuMatchCandidate) (*models.MenuItem, error) {
+	newItem, err := menus.Get(ctx, candidate.NewItemID)
+	if err != nil {
+		return nil, err
+	}
+	return RenameMenuItem(ctx, menus, candidate.MenuItemID, newItem.Name)
+}
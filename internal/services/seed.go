@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cheapeats-api/internal/models"
+	"cheapeats-api/internal/repository"
+)
+
+// seedRestaurants are demo restaurants for local development. Their external
+// IDs start with "seed:" so they are easy to find and never collide with
+// Places IDs.
+var seedRestaurants = []struct {
+	restaurant models.Restaurant
+	priceLevel int
+	cuisines   []models.Cuisine
+}{
+	{models.Restaurant{ExternalID: "seed:1", Name: "Golden Dragon", Address: "120 Grant Ave, San Francisco, CA 94108, USA", City: "San Francisco", State: "CA", ZipCode: "94108", Country: "United States", Latitude: 37.7903, Longitude: -122.4058, Rating: 4.3, PriceRange: "$", TimeZone: "America/Los_Angeles"}, 1, []models.Cuisine{{Slug: "chinese", Name: "Chinese"}}},
+	{models.Restaurant{ExternalID: "seed:2", Name: "Trattoria Bella", Address: "415 Columbus Ave, San Francisco, CA 94133, USA", City: "San Francisco", State: "CA", ZipCode: "94133", Country: "United States", Latitude: 37.7989, Longitude: -122.4078, Rating: 4.5, PriceRange: "$$", TimeZone: "America/Los_Angeles"}, 2, []models.Cuisine{{Slug: "italian", Name: "Italian"}, {Slug: "pizza", Name: "Pizza"}}},
+	{models.Restaurant{ExternalID: "seed:3", Name: "Taqueria El Sol", Address: "2889 Mission St, San Francisco, CA 94110, USA", City: "San Francisco", State: "CA", ZipCode: "94110", Country: "United States", Latitude: 37.7521, Longitude: -122.4183, Rating: 4.6, PriceRange: "$", TimeZone: "America/Los_Angeles"}, 1, []models.Cuisine{{Slug: "mexican", Name: "Mexican"}}},
+	{models.Restaurant{ExternalID: "seed:4", Name: "Sakura Sushi", Address: "210 W 44th St, New York, NY 10036, USA", City: "New York", State: "NY", ZipCode: "10036", Country: "United States", Latitude: 40.7577, Longitude: -73.9870, Rating: 4.4, PriceRange: "$$$", TimeZone: "America/New_York"}, 3, []models.Cuisine{{Slug: "japanese", Name: "Japanese"}, {Slug: "sushi", Name: "Sushi"}}},
+	{models.Restaurant{ExternalID: "seed:5", Name: "Brooklyn Burger Co", Address: "85 Atlantic Ave, Brooklyn, NY 11201, USA", City: "Brooklyn", State: "NY", ZipCode: "11201", Country: "United States", Latitude: 40.6908, Longitude: -73.9967, Rating: 4.1, PriceRange: "$$", TimeZone: "America/New_York"}, 2, []models.Cuisine{{Slug: "burger", Name: "Burger"}, {Slug: "american", Name: "American"}}},
+}
+
+// Seed inserts the demo restaurants with sample menus and price history.
+// Restaurants that already exist are left alone, so it can be run
+// repeatedly. It returns the number of restaurants created.
+func Seed(ctx context.Context, repos repository.Repositories) (int, error) {
+	created := 0
+	for _, seed := range seedRestaurants {
+		_, err := repos.Restaurants.GetByExternalID(ctx, seed.restaurant.ExternalID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return created, fmt.Errorf("failed to look up %s: %w", seed.restaurant.Name, err)
+		}
+
+		restaurant := seed.restaurant
+		if err := repos.Restaurants.Create(ctx, &restaurant); err != nil {
+			return created, fmt.Errorf("failed to create %s: %w", restaurant.Name, err)
+		}
+		if err := repos.Restaurants.SetCuisines(ctx, &restaurant, seed.cuisines); err != nil {
+			return created, fmt.Errorf("failed to set cuisines of %s: %w", restaurant.Name, err)
+		}
+		hours := seedOpeningHours(restaurant.ID)
+		if err := repos.Restaurants.SaveOpeningHours(ctx, &hours); err != nil {
+			return created, fmt.Errorf("failed to save opening hours of %s: %w", restaurant.Name, err)
+		}
+		generateSampleMenuItems(ctx, repos, restaurant.ID, seed.priceLevel, models.PriceProvenance{Source: models.PriceSourceManual})
+		promo := seedHappyHour(restaurant.ID)
+		if err := repos.Promos.Create(ctx, &promo); err != nil {
+			return created, fmt.Errorf("failed to create happy hour of %s: %w", restaurant.Name, err)
+		}
+		created++
+	}
+	return created, nil
+}
+
+// seedOpeningHours opens every day from 11:00, until 22:00 during the week
+// and past midnight on Friday and Saturday.
+func seedOpeningHours(restaurantID uint) models.OpeningHours {
+	hours := models.OpeningHours{RestaurantID: restaurantID, SpecialDays: models.SpecialDays{}}
+	for day := 0; day < 7; day++ {
+		closeAt := models.WeekTime{Day: day, Time: "22:00"}
+		if day == int(time.Friday) || day == int(time.Saturday) {
+			closeAt = models.WeekTime{Day: (day + 1) % 7, Time: "01:00"}
+		}
+		hours.Periods = append(hours.Periods, models.OpeningPeriod{
+			Open:  models.WeekTime{Day: day, Time: "11:00"},
+			Close: &closeAt,
+		})
+	}
+	return hours
+}
+
+// seedHappyHour takes a fifth off the whole menu on weekday afternoons.
+func seedHappyHour(restaurantID uint) models.PromoPrice {
+	discount := 20
+	return models.PromoPrice{
+		RestaurantID:    restaurantID,
+		Name:            "Happy hour",
+		DiscountPercent: &discount,
+		Days:            models.Weekdays{1, 2, 3, 4, 5},
+		StartTime:       "16:00",
+		EndTime:         "18:00",
+		IsActive:        true,
+	}
+}